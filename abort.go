@@ -0,0 +1,127 @@
+package atomicwrite
+
+import (
+	"errors"
+	"fmt"
+	"gocloud.dev/gcerrors"
+	"sync/atomic"
+)
+
+// ErrAborted is returned when an AtomicWriter instance is used after its `Abort` or `DiscardAsync` methods have been invoked.
+var ErrAborted = errors.New("Atomic writer has been aborted")
+
+// Abort discards any data written to the intermediate temporary file without copying it to the final path defined in the
+// `New` constructor and removes the temporary file. Abort is a no-op if the writer has already been closed or aborted so it
+// is safe to defer calling it immediately after the writer has been created.
+func (aw *AtomicWriter) Abort() error {
+
+	if !atomic.CompareAndSwapInt32(&aw.state, state_open, state_aborted) {
+		return nil
+	}
+
+	aw.stopFlush()
+
+	aw.emit(EventAborted, 0, nil)
+
+	return aw.discard()
+}
+
+// Cancel cancels the context used to write and commit data, which was derived from the context passed to the `New`
+// constructor, and then aborts the writer. Unlike the `Abort` method, which waits for in-flight operations to complete, Cancel
+// causes in-flight writes and commits to fail. If the `Close` method is committing data when Cancel is invoked then the commit
+// fails, the final path is left unchanged and the intermediate temporary file is removed by `Close`. Cancel is safe to invoke
+// from a different goroutine than the one writing data.
+func (aw *AtomicWriter) Cancel() error {
+	aw.cancel()
+	return aw.Abort()
+}
+
+// DiscardAsync marks the writer as aborted and then removes the intermediate temporary file in a background goroutine,
+// returning immediately. Use the `Wait` method to determine whether the temporary file was removed successfully. Like
+// the `Abort` method DiscardAsync is a no-op if the writer has already been closed or aborted.
+func (aw *AtomicWriter) DiscardAsync() {
+
+	aw.discard_mu.Lock()
+
+	if !atomic.CompareAndSwapInt32(&aw.state, state_open, state_aborted) {
+		aw.discard_mu.Unlock()
+		return
+	}
+
+	aw.discard_async = true
+	aw.discard_mu.Unlock()
+
+	aw.emit(EventAborted, 0, nil)
+
+	go func() {
+		defer close(aw.discard_done)
+		aw.stopFlush()
+		aw.discard_err = aw.discard()
+	}()
+}
+
+// Wait blocks until the background goroutine launched by the `DiscardAsync` method has completed and returns
+// any error it encountered. If the writer was not aborted by `DiscardAsync` Wait returns nil immediately. Wait is
+// safe to invoke from a different goroutine than the one that invoked `DiscardAsync`.
+func (aw *AtomicWriter) Wait() error {
+
+	aw.discard_mu.Lock()
+	discard_async := aw.discard_async
+	aw.discard_mu.Unlock()
+
+	if !discard_async {
+		return nil
+	}
+
+	<-aw.discard_done
+	return aw.discard_err
+}
+
+// discard cancels the underlying writer and removes the intermediate temporary file.
+func (aw *AtomicWriter) discard() error {
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	return aw.discardLocked()
+}
+
+// abortLocked marks the writer as aborted and removes the intermediate temporary file. Unlike the `Abort` method it
+// does not wait for the goroutine launched by the `WithBackgroundFlush` option to exit. The caller must hold aw.mu.
+func (aw *AtomicWriter) abortLocked() {
+
+	if !atomic.CompareAndSwapInt32(&aw.state, state_open, state_aborted) {
+		return
+	}
+
+	aw.emitLocked(EventAborted, 0, nil)
+	aw.discardLocked()
+}
+
+// discardLocked cancels the underlying writer and removes the intermediate temporary file. The caller must hold aw.mu.
+func (aw *AtomicWriter) discardLocked() error {
+
+	// Cancelling the writer's context and then closing it will cause the write to be
+	// discarded so the error returned by Close is expected and ignored. Depending on the
+	// underlying driver the temporary file may never have been created, hence NotFound.
+
+	aw.cancel()
+	aw.writer.Close()
+
+	if aw.tee != nil {
+		aw.tee.abort()
+	}
+
+	aw.deleteCheckpoint()
+
+	err := aw.bucket.Delete(aw.cleanup_ctx, aw.atomic_path)
+
+	if err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+		err = fmt.Errorf("Failed to delete %s, %w", aw.atomic_path, err)
+		aw.emitLocked(EventError, 0, err)
+		return err
+	}
+
+	aw.emitLocked(EventStagingDeleted, 0, nil)
+	return nil
+}
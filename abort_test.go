@@ -0,0 +1,297 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"gocloud.dev/blob"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicWriteAbort(t *testing.T) {
+
+	ctx := context.Background()
+
+	wr, err := New(ctx, "mem://atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	_, err = aw.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = aw.Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort writer, %v", err)
+	}
+
+	_, err = aw.Write([]byte(HELLO_WORLD))
+
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("Expected write after abort to fail with ErrAborted, got %v", err)
+	}
+
+	err = aw.Close()
+
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("Expected close after abort to fail with ErrAborted, got %v", err)
+	}
+
+	for _, path := range []string{aw.atomic_path, aw.final_path} {
+
+		exists, err := aw.bucket.Exists(ctx, path)
+
+		if err != nil {
+			t.Fatalf("Failed to determine whether %s exists, %v", path, err)
+		}
+
+		if exists {
+			t.Fatalf("Expected %s not to exist after abort", path)
+		}
+	}
+
+	err = aw.Abort()
+
+	if err != nil {
+		t.Fatalf("Expected second call to abort to be a no-op, %v", err)
+	}
+}
+
+func TestAtomicWriteDiscardAsync(t *testing.T) {
+
+	ctx := context.Background()
+
+	wr, err := New(ctx, "mem://atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	_, err = aw.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	aw.DiscardAsync()
+
+	err = aw.Wait()
+
+	if err != nil {
+		t.Fatalf("Failed to discard writer, %v", err)
+	}
+
+	exists, err := aw.bucket.Exists(ctx, aw.atomic_path)
+
+	if err != nil {
+		t.Fatalf("Failed to determine whether %s exists, %v", aw.atomic_path, err)
+	}
+
+	if exists {
+		t.Fatalf("Expected %s not to exist after discard", aw.atomic_path)
+	}
+}
+
+func TestAtomicWriteDiscardAsyncWaitConcurrent(t *testing.T) {
+
+	ctx := context.Background()
+
+	wr, err := New(ctx, "mem://atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	_, err = aw.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	// Run with -race; Wait and DiscardAsync are invoked from different goroutines
+
+	discarded := make(chan struct{})
+
+	go func() {
+		aw.DiscardAsync()
+		close(discarded)
+	}()
+
+	wait_errs := make(chan error, 1)
+
+	go func() {
+		wait_errs <- aw.Wait()
+	}()
+
+	<-discarded
+
+	err = <-wait_errs
+
+	if err != nil {
+		t.Fatalf("Failed to wait for discard, %v", err)
+	}
+
+	// Once DiscardAsync has returned Wait must block until the temporary file has been removed
+
+	err = aw.Wait()
+
+	if err != nil {
+		t.Fatalf("Failed to wait for discard, %v", err)
+	}
+
+	exists, err := aw.bucket.Exists(ctx, aw.atomic_path)
+
+	if err != nil {
+		t.Fatalf("Failed to determine whether %s exists, %v", aw.atomic_path, err)
+	}
+
+	if exists {
+		t.Fatalf("Expected %s not to exist after discard", aw.atomic_path)
+	}
+}
+
+func TestAtomicWriteAbortFile(t *testing.T) {
+
+	ctx := context.Background()
+
+	tmpdir := t.TempDir()
+	path := filepath.Join(tmpdir, "atomicwrite.txt")
+
+	wr, err := New(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	_, err = aw.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = aw.Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort writer, %v", err)
+	}
+
+	entries, err := os.ReadDir(tmpdir)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", tmpdir, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected %s to be empty after abort, found %d entries", tmpdir, len(entries))
+	}
+}
+
+func TestAtomicWriteCancel(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	wr, err := New(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	err = aw.Cancel()
+
+	if err != nil {
+		t.Fatalf("Failed to cancel writer, %v", err)
+	}
+
+	_, err = aw.Write([]byte(HELLO_WORLD))
+
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("Expected ErrAborted writing to cancelled writer, got %v", err)
+	}
+}
+
+func TestAtomicWriteCancelDuringClose(t *testing.T) {
+
+	ctx := context.Background()
+
+	bucket, mock := newMockBucket()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return bucket, nil
+	}
+
+	started := make(chan bool)
+	release := make(chan bool)
+
+	// Block committing data to the final path until the writer has been cancelled
+
+	fn := func(asFunc func(interface{}) bool) error {
+		started <- true
+		<-release
+		return nil
+	}
+
+	wr, err := New(ctx, "mock://atomicwrite.txt", WithBucketOpener(opener), WithFinalBeforeWrite(fn))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	_, err = aw.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	errs := make(chan error)
+
+	go func() {
+		errs <- aw.Close()
+	}()
+
+	<-started
+
+	err = aw.Cancel()
+
+	if err != nil {
+		t.Fatalf("Failed to cancel writer, %v", err)
+	}
+
+	close(release)
+
+	err = <-errs
+
+	if err == nil {
+		t.Fatalf("Expected close to fail after writer was cancelled")
+	}
+
+	if aw.Committed() {
+		t.Fatalf("Expected writer not to be committed")
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+
+	if len(mock.blobs) != 0 {
+		t.Fatalf("Expected bucket to be empty, found %d blobs", len(mock.blobs))
+	}
+}
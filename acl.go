@@ -0,0 +1,51 @@
+package atomicwrite
+
+import (
+	"fmt"
+)
+
+// Common access control lists (ACLs) that can be assigned to objects in both S3 and GCS buckets. The names
+// follow the S3 "canned ACL" conventions and can be translated to their S3 or GCS equivalents using the
+// `S3CannedACL` and `GCSPredefinedACL` methods respectively.
+const (
+	ACL_PRIVATE                   string = "private"
+	ACL_PUBLIC_READ               string = "public-read"
+	ACL_AUTHENTICATED_READ        string = "authenticated-read"
+	ACL_BUCKET_OWNER_READ         string = "bucket-owner-read"
+	ACL_BUCKET_OWNER_FULL_CONTROL string = "bucket-owner-full-control"
+)
+
+// acl_table maps common ACL names to their S3 canned ACL and GCS predefined ACL equivalents.
+var acl_table = map[string][2]string{
+	ACL_PRIVATE:                   {"private", "private"},
+	ACL_PUBLIC_READ:               {"public-read", "publicRead"},
+	ACL_AUTHENTICATED_READ:        {"authenticated-read", "authenticatedRead"},
+	ACL_BUCKET_OWNER_READ:         {"bucket-owner-read", "bucketOwnerRead"},
+	ACL_BUCKET_OWNER_FULL_CONTROL: {"bucket-owner-full-control", "bucketOwnerFullControl"},
+}
+
+// S3CannedACL returns the S3 canned ACL for 'acl', suitable for assigning to the `ACL` property of the
+// `s3manager.UploadInput` instance used by gocloud.dev/blob/s3blob writers.
+func S3CannedACL(acl string) (string, error) {
+
+	v, ok := acl_table[acl]
+
+	if !ok {
+		return "", fmt.Errorf("Unsupported ACL '%s'", acl)
+	}
+
+	return v[0], nil
+}
+
+// GCSPredefinedACL returns the GCS predefined ACL for 'acl', suitable for assigning to the `PredefinedACL` property
+// of the `storage.Writer` instance used by gocloud.dev/blob/gcsblob writers.
+func GCSPredefinedACL(acl string) (string, error) {
+
+	v, ok := acl_table[acl]
+
+	if !ok {
+		return "", fmt.Errorf("Unsupported ACL '%s'", acl)
+	}
+
+	return v[1], nil
+}
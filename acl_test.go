@@ -0,0 +1,42 @@
+package atomicwrite
+
+import (
+	"testing"
+)
+
+func TestACL(t *testing.T) {
+
+	tests := map[string][2]string{
+		ACL_PRIVATE:     {"private", "private"},
+		ACL_PUBLIC_READ: {"public-read", "publicRead"},
+	}
+
+	for acl, expected := range tests {
+
+		s3_acl, err := S3CannedACL(acl)
+
+		if err != nil {
+			t.Fatalf("Failed to derive S3 ACL for %s, %v", acl, err)
+		}
+
+		if s3_acl != expected[0] {
+			t.Fatalf("Unexpected S3 ACL for %s: %s", acl, s3_acl)
+		}
+
+		gcs_acl, err := GCSPredefinedACL(acl)
+
+		if err != nil {
+			t.Fatalf("Failed to derive GCS ACL for %s, %v", acl, err)
+		}
+
+		if gcs_acl != expected[1] {
+			t.Fatalf("Unexpected GCS ACL for %s: %s", acl, gcs_acl)
+		}
+	}
+
+	_, err := S3CannedACL("public-read-write-execute")
+
+	if err == nil {
+		t.Fatalf("Expected unsupported ACL to fail")
+	}
+}
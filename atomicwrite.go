@@ -3,136 +3,806 @@ package atomicwrite
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"gocloud.dev/blob"
 	_ "gocloud.dev/blob/fileblob"
 	_ "gocloud.dev/blob/memblob"
+	"gocloud.dev/gcerrors"
 	"io"
 	"log"
 	"math/rand"
 	"net/url"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 )
 
-// type AtomicWriter implements an atomic io.WriteCloser instance backed by the gocloud.dev/blob package.
+// type AtomicWriter implements an atomic io.WriteCloser instance backed by the gocloud.dev/blob package. AtomicWriter instances
+// are safe for concurrent use by multiple goroutines: calls to the `Write` method are serialized so the data passed to each call
+// is written contiguously, although the order of concurrent calls is not defined. Writes which have not started by the time the
+// `Close` (or `Abort`) method is invoked fail and are not committed.
 type AtomicWriter struct {
 	io.WriteCloser
+	// A random identifier for the writer, included in the lifecycle events it emits
+	id string
 	// The underlying blob.Bucket instance where data is written
-	bucket      *blob.Bucket
+	bucket *blob.Bucket
 	// The underlying io.WriteCloser instance for writing data
-	writer      io.WriteCloser
+	writer io.WriteCloser
 	// The final path (relative to bucket) that data will be written to
-	final_path  string
+	final_path string
 	// The temporary path (relative to bucket) that data will be written to before writes are commited to final_path
 	atomic_path string
+	// The context.Context instance, derived from the one passed to the `New` constructor, used to write and commit data
+	ctx context.Context
+	// The function used to cancel ctx
+	cancel context.CancelFunc
+	// A context.Context instance, derived from the one passed to the `New` constructor, which carries its values but is never
+	// cancelled, used to remove temporary files
+	cleanup_ctx context.Context
+	// The options used to create the `blob.Writer` instance for atomic_path
+	staging_opts *blob.WriterOptions
+	// The state of the writer (open, closed or aborted) which is read and updated using the sync/atomic package
+	state int32
+	// A channel that is closed when the goroutine launched by the `DiscardAsync` method completes
+	discard_done chan struct{}
+	// A boolean flag indicating whether the writer was aborted by the `DiscardAsync` method, guarded by discard_mu
+	discard_async bool
+	// A mutex guarding discard_async so that the `Wait` method observes the `DiscardAsync` method atomically
+	discard_mu sync.Mutex
+	// The error (if any) returned by the goroutine launched by the `DiscardAsync` method
+	discard_err error
+	// Ensures that data is only committed once, however many times the `Close` method is invoked
+	close_once sync.Once
+	// The error (if any) returned by the first call to the `Close` method, which is returned by all subsequent calls
+	close_err error
+	// Zero or more listeners to receive lifecycle events
+	listeners []WriterEventListener
+	// The options used to create the `blob.Writer` instance for final_path
+	final_opts *blob.WriterOptions
+	// The size of the buffer used to copy data from atomic_path to final_path
+	copy_buffer_size int
+	// The number of bytes that may be written before the quota defined by the `WithQuota` option is exceeded, or -1 if there is no quota
+	quota_remaining int64
+	// The number of bytes written to atomic_path
+	written int64
+	// Set to 1 once data has been successfully copied to final_path, read and updated using the sync/atomic package
+	committed int32
+	// The bucket URI derived from the URI passed to the `New` constructor
+	bucket_uri string
+	// The function used to open buckets for additional_uris
+	bucket_opener BucketOpenerFunc
+	// The function used to release buckets opened by bucket_opener; buckets returned by a custom bucket opener are left open
+	bucket_closer func(*blob.Bucket) error
+	// Zero or more additional URIs that data is copied to after it has been copied to final_path
+	additional_uris []string
+	// A mutex guarding writer, atomic_path, checkpoint_path and final_path which change when the `Snapshot`, `Flush` or `SetFinalPath` methods are invoked
+	mu sync.Mutex
+	// The temporary path (relative to bucket) of the data persisted by the most recent call to the `Flush` method
+	checkpoint_path string
+	// The value of written when the `Flush` method was last invoked
+	flushed int64
+	// A channel that is closed to stop the goroutine launched by the `WithBackgroundFlush` option
+	flush_done chan struct{}
+	// Used to ensure flush_done is only closed once
+	flush_once sync.Once
+	// Used to wait for the goroutine launched by the `WithBackgroundFlush` option to exit
+	flush_wg sync.WaitGroup
+	// Zero or more channels to notify as the running total of bytes written crosses a threshold
+	notifiers []*bytesWrittenNotifier
+	// The local filesystem path of the lock file acquired before data is copied to final_path, if the `WithFlock` option is enabled
+	lock_path string
+	// Zero or more functions used to transform the keys of additional_uris
+	key_normalizers []KeyNormalizerFunc
+	// The HTTP request that data is streamed to, if the `WithTeeToHTTP` option is enabled
+	tee *httpTee
+	// A boolean flag indicating whether data is encrypted by the `WithAESGCMEncryption` option
+	encrypted bool
+	// The configuration for splitting data in to shards, if the `WithShard` option is enabled
+	shard *shardConfig
+	// A boolean flag indicating whether to only commit data if final_path does not exist
+	if_not_exists bool
+	// The ETag final_path must have for data to be committed, if any
+	if_match string
+	// A boolean flag indicating whether to validate that data is a well-formed Parquet file before it is committed
+	parquet_validation bool
 }
 
+// type AtomicWriteCloser is the interface implemented by AtomicWriter instances. It is the primary interface for consumers of
+// this package, and for mocking AtomicWriter instances, since it does not depend on the concrete AtomicWriter type. The
+// io.WriteCloser instances returned by the `New` constructor can be asserted to this interface.
+type AtomicWriteCloser interface {
+	io.WriteCloser
+	// Abort discards any data written and removes the intermediate temporary file.
+	Abort() error
+	// AtomicPath returns the path (relative to the bucket) of the intermediate temporary file.
+	AtomicPath() string
+	// FinalPath returns the path (relative to the bucket) that data is committed to.
+	FinalPath() string
+}
+
+var _ AtomicWriteCloser = (*AtomicWriter)(nil)
+
+const (
+	// The writer is open and accepting writes
+	state_open int32 = iota
+	// The `Close` method has been invoked
+	state_closed
+	// The `Abort` or `DiscardAsync` methods have been invoked
+	state_aborted
+)
+
+// DEFAULT_LOCAL_COPY_BUFFER_SIZE is the default size, in bytes, of the buffer used to copy data from the
+// intermediate temporary file to the final path for local (`file://` and `mem://`) buckets.
+const DEFAULT_LOCAL_COPY_BUFFER_SIZE int = 32 * 1024
+
+// DEFAULT_REMOTE_COPY_BUFFER_SIZE is the default size, in bytes, of the buffer used to copy data from the
+// intermediate temporary file to the final path for remote buckets.
+const DEFAULT_REMOTE_COPY_BUFFER_SIZE int = 1024 * 1024
+
 // New returns a new AtomicWriter instance. 'uri' is expected to a valid gocloud.dev/blob URI however if 'uri' is passed
-// in as a schema-less Unix-style path it will be converted to a gocloud.dev/blob `file://` URI. Under the hood this method
+// in as a schema-less Unix-style path it will be converted to a gocloud.dev/blob `file://` URI. A leading "~/" (or
+// "~username/") in schema-less paths is expanded to the current (or named) user's home directory. Under the hood this method
 // will attempt to create a new temporary file for the "path" element of URI whose filename will be appended with a random
 // string. This temporary file is where data will be written to until the `Close` method is invoked at which point the data
-// in the temporary file will be copied to the final path (defined by 'uri') and the temporary file will be removed. This
-// method will create a new `blob.Writer` instance (which implements `io.WriteCloser`) with the default nil `blob.WriterOptions`.
-// If you need to specify custom writer options you should use the `NewWithOptions` method.
-func New(ctx context.Context, uri string) (io.WriteCloser, error) {
-	return NewWithOptions(ctx, uri, nil)
+// in the temporary file will be copied to the final path (defined by 'uri') and the temporary file will be removed. By
+// default this method will create a new `blob.Writer` instance (which implements `io.WriteCloser`) with the default nil
+// `blob.WriterOptions`. Custom behaviour may be specified using one or more 'opts' Option values.
+func New(ctx context.Context, uri string, opts ...Option) (io.WriteCloser, error) {
+
+	aw, err := newAtomicWriter(ctx, uri, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return aw, nil
 }
 
-// NewWithOptions returns a new AtomicWriter instance, specifying 'writer_opts' as the custom options used to create the
-// underlying `blob.Writer` instance.
-func NewWithOptions(ctx context.Context, uri string, writer_opts *blob.WriterOptions) (io.WriteCloser, error) {	
+// newAtomicWriter returns a new AtomicWriter instance as described in the documentation for the `New` method.
+func newAtomicWriter(ctx context.Context, uri string, opts ...Option) (*AtomicWriter, error) {
 
-	u, err := url.Parse(uri)
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	err := o.validateURIs(append([]string{uri}, o.additional_uris...)...)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+		return nil, err
 	}
 
-	var bucket_uri string
-	var final_path string
-	var atomic_path string
+	bucket_uri, final_path, err := parseURI(uri)
 
-	if u.Scheme == "" {
+	if err != nil {
+		return nil, err
+	}
+
+	final_path = normalizeKey(o.key_normalizers, final_path)
+
+	if final_path == "" {
+		return nil, fmt.Errorf("Failed to derive key from URI, normalized key is empty")
+	}
+
+	lock_path := ""
 
-		abs_path, err := filepath.Abs(uri)
+	if o.flock {
+
+		path, err := localPath(uri)
 
 		if err != nil {
-			return nil, fmt.Errorf("Failed to derive absolute path for URI, %w", err)
+			return nil, fmt.Errorf("Failed to derive lock path, %w", err)
 		}
 
-		root := filepath.Dir(abs_path)
-		fname := filepath.Base(abs_path)
+		lock_path = filepath.Join(filepath.Dir(path), filepath.FromSlash(final_path)) + LOCK_EXTENSION
+	}
+
+	if o.encryption_key != nil {
 
-		bucket_uri = fmt.Sprintf("file://%s", root)
-		final_path = fname
+		_, err := newAESGCM(o.encryption_key)
 
-	} else {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var shard *shardConfig
+
+	if o.shard_template != "" {
 
-		root := filepath.Dir(uri)
-		fname := filepath.Base(uri)
+		if len(o.additional_uris) > 0 {
+			return nil, fmt.Errorf("Sharding can not be combined with additional paths, %w", ErrNotSupported)
+		}
+
+		shard, err = newShardConfig(o.shard_max_size, o.shard_template)
 
-		bucket_uri = root
-		final_path = fname
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	bucket, err := blob.OpenBucket(ctx, bucket_uri)
+	encrypted := o.encryption_key != nil || o.encrypt_writer != nil
+
+	if encrypted && o.flush_interval > 0 {
+		return nil, fmt.Errorf("Background flushing is not supported for encrypted writers, %w", ErrNotSupported)
+	}
+
+	if encrypted && o.parquet_validation {
+		return nil, fmt.Errorf("Parquet validation is not supported for encrypted writers, %w", ErrNotSupported)
+	}
+
+	id, err := newWriteID()
+
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, err := o.wrappedBucketOpener()(ctx, bucket_uri)
 
 	if err != nil {
 		return nil, fmt.Errorf("Failed to open bucket %s, %w", bucket_uri, err)
 	}
 
-	ext := filepath.Ext(final_path)
+	quota_remaining, err := o.remainingQuota(ctx, bucket_uri, bucket)
+
+	if err != nil {
+		return nil, err
+	}
+
+	final_opts, err := o.finalWriterOptions(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	final_opts = o.withPartSize(final_opts, bucket_uri)
+
+	on_collision := func(attempt int, test_path string) {
+
+		if o.logger != nil {
+			o.logger.Printf("Temporary path %s for %s already exists (attempt %d), trying again", test_path, final_path, attempt)
+		}
+
+		e := WriterEvent{
+			Kind:       EventCollision,
+			FinalPath:  final_path,
+			AtomicPath: test_path,
+			Attempt:    attempt,
+		}
+
+		emitEvent(o.listeners, e)
+	}
+
+	atomic_path, err := deriveAtomicPath(ctx, bucket, final_path, on_collision)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The blob.Writer documentation says that the way to abort a write is to cancel
+	// the context used to create the writer so that's what we do in the Abort and
+	// Cancel methods. The same context is used to commit data in the Close method.
+
+	wr_ctx, cancel := context.WithCancel(ctx)
+
+	staging_opts := o.withPartSize(o.stagingWriterOptions(), bucket_uri)
+
+	var wr io.WriteCloser
+
+	wr, err = bucket.NewWriter(wr_ctx, atomic_path, staging_opts)
+
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("Failed to open %s, %w", atomic_path, err)
+	}
+
+	switch {
+	case o.encryption_key != nil:
+
+		ew, err := newAESGCMWriter(wr, o.encryption_key)
 
-	max_tries := 15
+		if err != nil {
+			cancel()
+			wr.Close()
+			return nil, fmt.Errorf("Failed to create encrypted writer, %w", err)
+		}
 
-	for i := 0; i < max_tries; i++ {
+		wr = ew
+
+	case o.encrypt_writer != nil:
+
+		ew, err := newEncryptWriter(wr, o.encrypt_writer)
+
+		if err != nil {
+			cancel()
+			wr.Close()
+			return nil, fmt.Errorf("Failed to create encrypted writer, %w", err)
+		}
+
+		wr = ew
+	}
+
+	copy_buffer_size := o.copy_buffer_size
+
+	if copy_buffer_size <= 0 {
+
+		copy_buffer_size = DEFAULT_REMOTE_COPY_BUFFER_SIZE
+
+		if isLocalBucketURI(bucket_uri) {
+			copy_buffer_size = DEFAULT_LOCAL_COPY_BUFFER_SIZE
+		}
+	}
+
+	aw := &AtomicWriter{
+		id:                 id,
+		bucket:             bucket,
+		writer:             wr,
+		atomic_path:        atomic_path,
+		final_path:         final_path,
+		ctx:                wr_ctx,
+		cancel:             cancel,
+		cleanup_ctx:        detachContext(ctx),
+		discard_done:       make(chan struct{}),
+		staging_opts:       staging_opts,
+		bucket_uri:         bucket_uri,
+		bucket_opener:      o.wrappedBucketOpener(),
+		bucket_closer:      o.closeBucket,
+		additional_uris:    o.additional_uris,
+		notifiers:          o.notifiers,
+		lock_path:          lock_path,
+		key_normalizers:    o.key_normalizers,
+		encrypted:          encrypted,
+		shard:              shard,
+		if_not_exists:      o.if_not_exists,
+		if_match:           o.if_match,
+		parquet_validation: o.parquet_validation,
+		listeners:          o.listeners,
+		final_opts:         final_opts,
+		copy_buffer_size:   copy_buffer_size,
+		quota_remaining:    quota_remaining,
+	}
+
+	if o.tee_http != nil {
+		aw.tee = newHTTPTee(o.tee_http)
+	}
+
+	aw.emit(EventOpened, 0, nil)
+
+	if o.flush_interval > 0 {
+		aw.startFlush(o.flush_interval)
+	}
+
+	return aw, nil
+}
+
+// NewWithOptions returns a new AtomicWriter instance, specifying 'writer_opts' as the custom options used to create the
+// underlying `blob.Writer` instance. This is the equivalent of calling `New(ctx, uri, WithWriterOptions(writer_opts))`.
+func NewWithOptions(ctx context.Context, uri string, writer_opts *blob.WriterOptions) (io.WriteCloser, error) {
+	return New(ctx, uri, WithWriterOptions(writer_opts))
+}
+
+// NewWithTimeout returns a new AtomicWriter instance whose entire lifetime, from creating the intermediate temporary file
+// through to copying data to the final path in the `Close` method, must complete within 'timeout'. If the deadline is exceeded
+// subsequent writes and the `Close` method will fail and the final path will be left unchanged. Resources associated with the
+// deadline are released when the writer is closed or aborted.
+func NewWithTimeout(ctx context.Context, uri string, timeout time.Duration, opts ...Option) (io.WriteCloser, error) {
+
+	timeout_ctx, timeout_cancel := context.WithTimeout(ctx, timeout)
+
+	aw, err := newAtomicWriter(timeout_ctx, uri, opts...)
+
+	if err != nil {
+		timeout_cancel()
+		return nil, err
+	}
+
+	cancel := aw.cancel
+
+	aw.cancel = func() {
+		cancel()
+		timeout_cancel()
+	}
+
+	return aw, nil
+}
+
+// max_atomic_path_tries is the maximum number of candidate paths for an intermediate temporary file tried by `deriveAtomicPath`.
+const max_atomic_path_tries int = 15
+
+// deriveAtomicPath returns a path (relative to 'bucket') for an intermediate temporary file associated with 'key'. The
+// new path is derived by appending a random string to the filename of 'key' (before its extension) and is always in the
+// same "directory" as 'key'. Random strings are generated until a path that does not already exist in 'bucket' is found or
+// until the maximum number of tries is exceeded (in which case an error is returned). If 'on_collision' is not nil it is
+// invoked with the attempt number and the path for each candidate path that already exists.
+func deriveAtomicPath(ctx context.Context, bucket *blob.Bucket, key string, on_collision func(int, string)) (string, error) {
+
+	dir, fname := path.Split(key)
+	ext := path.Ext(fname)
+	stem := strings.TrimSuffix(fname, ext)
+
+	for i := 0; i < max_atomic_path_tries; i++ {
 
 		r := rand.Int()
 
-		new_ext := fmt.Sprintf("-%d%s", r, ext)
-		test_path := strings.Replace(final_path, ext, new_ext, 1)
+		test_path := fmt.Sprintf("%s%s-%d%s", dir, stem, r, ext)
 
 		exists, err := bucket.Exists(ctx, test_path)
 
 		if err != nil {
-			return nil, fmt.Errorf("Failed to determine whether %s exists, %w", test_path, err)
+			return "", fmt.Errorf("Failed to determine whether %s exists, %w", test_path, err)
 		}
 
 		if !exists {
-			atomic_path = test_path
-			break
+			return test_path, nil
+		}
+
+		if on_collision != nil {
+			on_collision(i+1, test_path)
 		}
 	}
 
-	wr, err := bucket.NewWriter(ctx, atomic_path, writer_opts)
+	return "", fmt.Errorf("Failed to derive temporary path for %s after %d tries", key, max_atomic_path_tries)
+}
+
+// parseURI derives a gocloud.dev/blob bucket URI and a key (relative to that bucket) from 'uri'. Schema-less
+// Unix-style paths are converted to `file://` bucket URIs, after expanding a leading "~/" or "~username/" to the
+// relevant home directory (see `expandHome`). For `file://` URIs the bucket is the parent directory
+// of the path and the key is the filename. For all other schemes the bucket is defined by the scheme and host
+// (and any query parameters) and the key is the path. If there is no path then the host is assumed to be the key,
+// for example `mem://example.txt`. Keys which are not valid UTF-8 are percent-encoded (see `encodeKey`).
+func parseURI(uri string) (string, string, error) {
+
+	u, err := url.Parse(uri)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to open %s, %w", atomic_path, err)
+		return "", "", fmt.Errorf("Failed to parse URI, %w", err)
 	}
 
-	aw := &AtomicWriter{
-		bucket:      bucket,
-		writer:      wr,
-		atomic_path: atomic_path,
-		final_path:  final_path,
+	if u.Scheme == "" {
+
+		path, err := expandHome(uri)
+
+		if err != nil {
+			return "", "", err
+		}
+
+		abs_path, err := filepath.Abs(path)
+
+		if err != nil {
+			return "", "", fmt.Errorf("Failed to derive absolute path for URI, %w", err)
+		}
+
+		root := filepath.Dir(abs_path)
+		fname := filepath.Base(abs_path)
+
+		bucket_uri := fmt.Sprintf("file://%s", root)
+		return bucket_uri, encodeKey(fname), nil
 	}
 
-	return aw, nil
+	key := strings.TrimLeft(u.Path, "/")
+
+	switch {
+	case u.Scheme == "file":
+		key = filepath.Base(u.Path)
+		u.Path = filepath.Dir(u.Path)
+	case key == "":
+		key = u.Host
+		u.Host = ""
+	default:
+		u.Path = ""
+	}
+
+	if key == "" || key == "." || key == "/" {
+		return "", "", fmt.Errorf("Failed to derive key from URI")
+	}
+
+	bucket_uri := u.String()
+
+	if u.Host == "" && u.Path == "" {
+
+		bucket_uri = fmt.Sprintf("%s://", u.Scheme)
+
+		if u.RawQuery != "" {
+			bucket_uri = fmt.Sprintf("%s?%s", bucket_uri, u.RawQuery)
+		}
+	}
+
+	return bucket_uri, encodeKey(key), nil
+}
+
+// encodeKey returns 'key' with any bytes which are not part of a valid UTF-8 sequence percent-encoded, for example
+// "\xff" becomes "%FF". The gocloud.dev/blob package rejects keys which are not valid UTF-8 even though some storage
+// systems (and most local filesystems) allow arbitrary byte sequences. Valid UTF-8 sequences, including multi-byte
+// sequences, are left unchanged.
+func encodeKey(key string) string {
+
+	if utf8.ValidString(key) {
+		return key
+	}
+
+	var sb strings.Builder
+
+	for i := 0; i < len(key); {
+
+		r, sz := utf8.DecodeRuneInString(key[i:])
+
+		if r == utf8.RuneError && sz == 1 {
+			sb.WriteString(fmt.Sprintf("%%%02X", key[i]))
+		} else {
+			sb.WriteString(key[i : i+sz])
+		}
+
+		i += sz
+	}
+
+	return sb.String()
+}
+
+// localPath returns the absolute local filesystem path for 'uri', which may be a schema-less path or a `file://` URI.
+// ErrNotSupported is returned for all other URIs.
+func localPath(uri string) (string, error) {
+
+	bucket_uri, key, err := parseURI(uri)
+
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(bucket_uri)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse bucket URI, %w", err)
+	}
+
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("%s URIs are not local filesystem paths, %w", u.Scheme, ErrNotSupported)
+	}
+
+	return filepath.Join(u.Path, key), nil
 }
 
 // Write writes 'b' to the underlying writer instance.
 func (aw *AtomicWriter) Write(b []byte) (int, error) {
-	return aw.writer.Write(b)
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	switch atomic.LoadInt32(&aw.state) {
+	case state_aborted:
+		return 0, ErrAborted
+	case state_closed:
+		return 0, fmt.Errorf("Atomic writer has been closed")
+	}
+
+	if aw.quota_remaining >= 0 && atomic.LoadInt64(&aw.written)+int64(len(b)) > aw.quota_remaining {
+		return 0, ErrQuotaExceeded
+	}
+
+	n, err := aw.writer.Write(b)
+
+	total := atomic.AddInt64(&aw.written, int64(n))
+
+	if n > 0 {
+		aw.notifyBytesWritten(total-int64(n), total)
+	}
+
+	if err == nil && aw.tee != nil {
+		err = aw.tee.write(aw.ctx, b[:n])
+	}
+
+	if err == nil {
+		aw.emitLocked(EventWritten, n, nil)
+	}
+
+	return n, err
 }
 
 // Close will copy data written to the intermediate temporary file to the final path defined in the
-// `New` constructor. Upon successfully completing this operation the temporary file will be removed.
+// `New` constructor. Upon successfully completing this operation the temporary file will be removed. Subsequent
+// calls to Close wait for the first call to complete and return the same result, unless the writer has been aborted in which
+// case ErrAborted is returned. Data is always copied, using the underlying bucket, rather than renamed so there are no
+// "invalid cross-device link" errors if the final path is on a different filesystem partition.
 func (aw *AtomicWriter) Close() error {
 
-	ctx := context.Background()
+	if !atomic.CompareAndSwapInt32(&aw.state, state_open, state_closed) {
+
+		if atomic.LoadInt32(&aw.state) == state_aborted {
+			return ErrAborted
+		}
+	}
+
+	aw.close_once.Do(func() {
+		aw.close_err = aw.close()
+	})
+
+	return aw.close_err
+}
+
+// close copies data written to the intermediate temporary file to the final path. It is invoked exactly once, by the first call
+// to the `Close` method.
+func (aw *AtomicWriter) close() error {
+
+	aw.stopFlush()
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	defer aw.cancel()
+
+	if aw.tee != nil {
+
+		err := aw.tee.finish()
+
+		if err != nil {
+			aw.emitLocked(EventError, 0, err)
+			aw.discardLocked()
+			return err
+		}
+	}
+
+	if aw.lock_path != "" {
+
+		unlock, err := lockFile(aw.lock_path)
+
+		if err != nil {
+			err = fmt.Errorf("Failed to acquire lock %s, %w", aw.lock_path, err)
+			aw.emitLocked(EventError, 0, err)
+			aw.discardLocked()
+			return err
+		}
+
+		defer unlock()
+	}
+
+	err := aw.commit(aw.ctx)
+
+	aw.deleteCheckpoint()
+
+	if err != nil {
+		aw.emitLocked(EventError, 0, err)
+		return err
+	}
+
+	return nil
+}
+
+// AtomicPath returns the path (relative to the bucket) of the intermediate temporary file. This changes each time the `Snapshot`
+// or `Flush` methods are invoked.
+func (aw *AtomicWriter) AtomicPath() string {
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	return aw.atomic_path
+}
+
+// FinalPath returns the path (relative to the bucket) that data is committed to when the `Close` method is invoked.
+func (aw *AtomicWriter) FinalPath() string {
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	return aw.final_path
+}
+
+// SetFinalPath changes the path (relative to the bucket) that data is committed to when the `Close` method is invoked to 'key'.
+// This is useful when the final path depends on the data written, for example when the file extension is derived from the content
+// type of the data. 'key' is transformed by any functions defined by the `WithKeyNormalizer` option. An error is returned if the
+// writer has already been closed or aborted.
+func (aw *AtomicWriter) SetFinalPath(key string) error {
+
+	key = normalizeKey(aw.key_normalizers, encodeKey(key))
+
+	if key == "" {
+		return fmt.Errorf("Final key is empty")
+	}
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	switch atomic.LoadInt32(&aw.state) {
+	case state_aborted:
+		return ErrAborted
+	case state_closed:
+		return fmt.Errorf("Atomic writer has been closed")
+	}
+
+	if aw.lock_path != "" {
+		root := strings.TrimSuffix(aw.lock_path, filepath.FromSlash(aw.final_path)+LOCK_EXTENSION)
+		aw.lock_path = filepath.Join(root, filepath.FromSlash(key)) + LOCK_EXTENSION
+	}
+
+	aw.final_path = key
+
+	return nil
+}
+
+// Committed returns true if data has been successfully copied to the final path by the `Close` method. It returns false
+// if the writer is still open, has been aborted or if the `Close` method failed to copy data to the final path. Failures
+// copying data to the paths defined by the `WithAdditionalPaths` option do not affect the value returned by Committed.
+func (aw *AtomicWriter) Committed() bool {
+	return atomic.LoadInt32(&aw.committed) == 1
+}
+
+// BlobSize returns the size, in bytes, of the intermediate temporary file as reported by the underlying bucket. This is the
+// amount of data that has actually been persisted which, for backends that buffer data locally before uploading it, may be
+// less than the number of bytes passed to the `Write` method. Most backends do not create the temporary file until the
+// writer is closed in which case BlobSize returns 0.
+func (aw *AtomicWriter) BlobSize(ctx context.Context) (int64, error) {
+
+	aw.mu.Lock()
+	atomic_path := aw.atomic_path
+	aw.mu.Unlock()
+
+	attrs, err := aw.bucket.Attributes(ctx, atomic_path)
+
+	if err != nil {
+
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("Failed to derive attributes for %s, %w", atomic_path, err)
+	}
+
+	return attrs.Size, nil
+}
+
+// StagingBucketAttributes returns the attributes (size, ETag, modification time and so on) of the intermediate temporary file as
+// reported by the underlying bucket. This is useful for monitoring the progress of large writes. As with the `BlobSize` method
+// most backends do not create the temporary file until the writer is closed in which case the error returned has the
+// `gcerrors.NotFound` code.
+func (aw *AtomicWriter) StagingBucketAttributes(ctx context.Context) (*blob.Attributes, error) {
+
+	aw.mu.Lock()
+	atomic_path := aw.atomic_path
+	aw.mu.Unlock()
+
+	attrs, err := aw.bucket.Attributes(ctx, atomic_path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive attributes for %s, %w", atomic_path, err)
+	}
+
+	return attrs, nil
+}
+
+// commit copies data written to the intermediate temporary file to the final path and removes the temporary file. If the
+// data is not committed, and removing the temporary file also fails, the error returned wraps both errors (see cleanupError).
+// The caller must hold aw.mu.
+func (aw *AtomicWriter) commit(ctx context.Context) (err error) {
+
+	// The temporary file is removed however the commit ends, including when the writer
+	// itself fails to close and the temporary file may only be partially written
+
+	defer func() {
+
+		cleanup_err := aw.deleteStaging()
+
+		if cleanup_err == nil {
+			return
+		}
+
+		aw.emitLocked(EventError, 0, cleanup_err)
+
+		if err != nil {
+			err = &cleanupError{err: err, cleanup: cleanup_err}
+			return
+		}
+
+		// The data has been committed so removing the temporary file is not reported as a failure
+
+		log.Println(cleanup_err)
+	}()
 
-	err := aw.writer.Close()
+	err = aw.writer.Close()
 
 	if err != nil {
 		return fmt.Errorf("Failed to close atomic writer, %w", err)
@@ -144,24 +814,49 @@ func (aw *AtomicWriter) Close() error {
 		return fmt.Errorf("Failed to open atomic reader, %w", err)
 	}
 
-	defer func() {
+	defer r.Close()
+
+	err = aw.checkPreconditions(ctx)
+
+	if err != nil {
+		return err
+	}
 
-		r.Close()
+	if aw.parquet_validation {
 
-		err := aw.bucket.Delete(ctx, aw.atomic_path)
+		err := aw.validateParquet(ctx, r.Size())
 
 		if err != nil {
-			log.Printf("Failed to delete %s, %v", aw.atomic_path, err)
+			return err
 		}
-	}()
+	}
+
+	if aw.shard != nil && r.Size() > aw.shard.max_size {
+
+		err := aw.commitShards(ctx, r.Size())
 
-	wr, err := aw.bucket.NewWriter(ctx, aw.final_path, nil)
+		if err != nil {
+			return err
+		}
+
+		atomic.StoreInt32(&aw.committed, 1)
+		aw.emitLocked(EventCommitted, 0, nil)
+
+		return nil
+	}
+
+	wr, err := aw.bucket.NewWriter(ctx, aw.final_path, aw.final_opts)
 
 	if err != nil {
 		return fmt.Errorf("Failed to open %s for writing, %w", aw.final_path, err)
 	}
 
-	_, err = io.Copy(wr, r)
+	// blob.Reader and blob.Writer implement io.WriterTo and io.ReaderFrom using a fixed
+	// 1KB buffer so hide those methods to ensure our own buffer is used
+
+	buf := make([]byte, aw.copy_buffer_size)
+
+	_, err = io.CopyBuffer(struct{ io.Writer }{wr}, struct{ io.Reader }{r}, buf)
 
 	if err != nil {
 		return fmt.Errorf("Failed to copy atomic file %s, %w", aw.final_path, err)
@@ -173,5 +868,62 @@ func (aw *AtomicWriter) Close() error {
 		return fmt.Errorf("Failed to close %s, %w", aw.final_path, err)
 	}
 
+	atomic.StoreInt32(&aw.committed, 1)
+	aw.emitLocked(EventCommitted, 0, nil)
+
+	return aw.commitAdditional(ctx)
+}
+
+// deleteStaging removes the intermediate temporary file. It is not an error if the temporary file does not exist.
+func (aw *AtomicWriter) deleteStaging() error {
+
+	// Use a context which is never cancelled so the temporary file is removed even if the context used to commit data has been cancelled
+
+	err := aw.bucket.Delete(aw.cleanup_ctx, aw.atomic_path)
+
+	if err != nil {
+
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil
+		}
+
+		return fmt.Errorf("Failed to delete %s, %w", aw.atomic_path, err)
+	}
+
+	aw.emitLocked(EventStagingDeleted, 0, nil)
 	return nil
 }
+
+// type cleanupError is returned by the `Close` method when data could not be committed and the intermediate temporary file
+// could not be removed either. `errors.Is` matches either error while `errors.As` inspects the error which caused the commit
+// to fail. Both errors are included in its message.
+type cleanupError struct {
+	// The error which caused the commit to fail
+	err error
+	// The error removing the intermediate temporary file
+	cleanup error
+}
+
+// Error returns the message of the error which caused the commit to fail followed by the message of the error removing the
+// intermediate temporary file.
+func (e *cleanupError) Error() string {
+	return fmt.Sprintf("%v (additionally: %v)", e.err, e.cleanup)
+}
+
+// Unwrap returns the error which caused the commit to fail.
+func (e *cleanupError) Unwrap() error {
+	return e.err
+}
+
+// Is returns true if the error removing the intermediate temporary file matches 'target'. The error which caused the commit
+// to fail is matched by `errors.Is` using the `Unwrap` method.
+func (e *cleanupError) Is(target error) bool {
+	return errors.Is(e.cleanup, target)
+}
+
+// SignedURL returns a pre-signed URL for the final path defined in the `New` constructor. It is meant to be
+// called after the `Close` method has successfully committed data to the final path. Not all gocloud.dev/blob
+// drivers support signed URLs; see https://pkg.go.dev/gocloud.dev/blob#Bucket.SignedURL for details.
+func (aw *AtomicWriter) SignedURL(ctx context.Context, opts *blob.SignedURLOptions) (string, error) {
+	return aw.bucket.SignedURL(ctx, aw.FinalPath(), opts)
+}
@@ -3,11 +3,21 @@ package atomicwrite
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"gocloud.dev/gcerrors"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 const HELLO_WORLD string = "Hello world"
@@ -66,7 +76,7 @@ func TestAtomicWriteFile(t *testing.T) {
 	}()
 
 	uri := fmt.Sprintf("file://%s", path)
-	
+
 	err := testAtomicWrite(uri)
 
 	if err != nil {
@@ -94,7 +104,7 @@ func TestAtomicWriteMem(t *testing.T) {
 
 	fname := "atomicwrite.txt"
 	uri := fmt.Sprintf("mem://%s", fname)
-	
+
 	err := testAtomicWrite(uri)
 
 	if err != nil {
@@ -102,11 +112,158 @@ func TestAtomicWriteMem(t *testing.T) {
 	}
 }
 
-func testAtomicWrite(uri string) error {
+func TestParseURI(t *testing.T) {
+
+	cwd, err := os.Getwd()
+
+	if err != nil {
+		t.Fatalf("Failed to derive current working directory, %v", err)
+	}
+
+	tests := map[string][2]string{
+		"mem://atomicwrite.txt":                          {"mem://", "atomicwrite.txt"},
+		"mem://bucket/a/atomicwrite.txt":                 {"mem://bucket", "a/atomicwrite.txt"},
+		"file:///tmp/atomicwrite.txt":                    {"file:///tmp", "atomicwrite.txt"},
+		"file:///tmp/atomicwrite.txt?metadata=skip":      {"file:///tmp?metadata=skip", "atomicwrite.txt"},
+		"s3://bucket/a/atomicwrite.txt?region=us-east-1": {"s3://bucket?region=us-east-1", "a/atomicwrite.txt"},
+		"/tmp/atomicwrite.txt":                           {"file:///tmp", "atomicwrite.txt"},
+		"atomicwrite.txt":                                {fmt.Sprintf("file://%s", cwd), "atomicwrite.txt"},
+	}
+
+	for uri, expected := range tests {
+
+		bucket_uri, key, err := parseURI(uri)
+
+		if err != nil {
+			t.Fatalf("Failed to parse %s, %v", uri, err)
+		}
+
+		if bucket_uri != expected[0] {
+			t.Fatalf("Unexpected bucket URI for %s: %s", uri, bucket_uri)
+		}
+
+		if key != expected[1] {
+			t.Fatalf("Unexpected key for %s: %s", uri, key)
+		}
+	}
+
+	_, _, err = parseURI("file:///")
+
+	if err == nil {
+		t.Fatalf("Expected file:/// to fail")
+	}
+}
+
+func TestAtomicWriteNonUTF8Key(t *testing.T) {
 
 	ctx := context.Background()
 
-	wr, err := New(ctx, uri)
+	root := t.TempDir()
+
+	tests := map[string]string{
+		"caf\u00e9.txt":     "caf\u00e9.txt",
+		"\u65e5\u672c.txt":  "\u65e5\u672c.txt",
+		"bad\xff.txt":       "bad%FF.txt",
+		"bad\xc3\x28.txt":   "bad%C3(.txt",
+		"caf\u00e9\xfe.txt": "caf\u00e9%FE.txt",
+	}
+
+	for fname, expected := range tests {
+
+		path := filepath.Join(root, fname)
+
+		for _, uri := range []string{path, "file://" + path} {
+
+			_, key, err := parseURI(uri)
+
+			if err != nil {
+				t.Fatalf("Failed to parse %q, %v", uri, err)
+			}
+
+			if key != expected {
+				t.Fatalf("Unexpected key for %q: %q", uri, key)
+			}
+		}
+
+		wr, err := New(ctx, path)
+
+		if err != nil {
+			t.Fatalf("Failed to create writer for %q, %v", path, err)
+		}
+
+		_, err = wr.Write([]byte(HELLO_WORLD))
+
+		if err != nil {
+			t.Fatalf("Failed to write %q, %v", path, err)
+		}
+
+		err = wr.Close()
+
+		if err != nil {
+			t.Fatalf("Failed to close writer for %q, %v", path, err)
+		}
+
+		body, err := os.ReadFile(filepath.Join(root, expected))
+
+		if err != nil {
+			t.Fatalf("Failed to read %q, %v", expected, err)
+		}
+
+		if string(body) != HELLO_WORLD {
+			t.Fatalf("Unexpected body for %q: %s", expected, string(body))
+		}
+	}
+}
+
+func TestAtomicWriteWithBucketOpener(t *testing.T) {
+
+	ctx := context.Background()
+
+	var opened string
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		opened = bucket_uri
+		return memblob.OpenBucket(nil), nil
+	}
+
+	wr, err := New(ctx, "custom://bucket/atomicwrite.txt", WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	if opened != "custom://bucket" {
+		t.Fatalf("Unexpected bucket URI passed to opener: %s", opened)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	body, err := wr.(*AtomicWriter).bucket.ReadAll(ctx, "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to read atomicwrite.txt, %v", err)
+	}
+
+	if !bytes.Equal(body, []byte(HELLO_WORLD)) {
+		t.Fatalf("Invalid data (%s) written to atomicwrite.txt", string(body))
+	}
+}
+
+func testAtomicWrite(uri string, opts ...Option) error {
+
+	ctx := context.Background()
+
+	wr, err := New(ctx, uri, opts...)
 
 	if err != nil {
 		return fmt.Errorf("Failed to create writer, %v", err)
@@ -126,3 +283,893 @@ func testAtomicWrite(uri string) error {
 
 	return nil
 }
+
+func TestAtomicWriteSignedURL(t *testing.T) {
+
+	ctx := context.Background()
+
+	uri := "mem://atomicwrite.txt"
+
+	wr, err := New(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	// memblob does not support signed URLs so this is really just testing
+	// that the error is passed through from the underlying bucket
+
+	_, err = wr.(*AtomicWriter).SignedURL(ctx, nil)
+
+	if gcerrors.Code(err) != gcerrors.Unimplemented {
+		t.Fatalf("Expected unimplemented error, got %v", err)
+	}
+}
+
+func TestAtomicWriteSignedURLSetFinalPath(t *testing.T) {
+
+	ctx := context.Background()
+
+	wr, err := New(ctx, "mem://atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	defer aw.Abort()
+
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+
+	go func() {
+
+		defer wg.Done()
+
+		for i := 0; i < 50; i++ {
+			aw.SetFinalPath(fmt.Sprintf("atomicwrite-%d.txt", i))
+			runtime.Gosched()
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		aw.SignedURL(ctx, nil)
+		runtime.Gosched()
+	}
+
+	wg.Wait()
+}
+
+func TestDeriveAtomicPath(t *testing.T) {
+
+	ctx := context.Background()
+
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+
+	// The temporary file should always be a sibling of the final path
+
+	tests := map[string]string{
+		"atomicwrite.txt":        `^atomicwrite-\d+\.txt$`,
+		"a.txt/atomicwrite.txt":  `^a\.txt/atomicwrite-\d+\.txt$`,
+		"a/atomicwrite":          `^a/atomicwrite-\d+$`,
+		"a/b/atomicwrite.tar.gz": `^a/b/atomicwrite\.tar-\d+\.gz$`,
+	}
+
+	for key, pattern := range tests {
+
+		atomic_path, err := deriveAtomicPath(ctx, bucket, key, nil)
+
+		if err != nil {
+			t.Fatalf("Failed to derive atomic path for %s, %v", key, err)
+		}
+
+		if !regexp.MustCompile(pattern).MatchString(atomic_path) {
+			t.Fatalf("Unexpected atomic path for %s: %s", key, atomic_path)
+		}
+	}
+}
+
+func TestWithCopyBufferSize(t *testing.T) {
+
+	ctx := context.Background()
+
+	tests := map[string]int{
+		"mem://atomicwrite.txt":        DEFAULT_LOCAL_COPY_BUFFER_SIZE,
+		"mem://bucket/atomicwrite.txt": DEFAULT_LOCAL_COPY_BUFFER_SIZE,
+	}
+
+	for uri, expected := range tests {
+
+		wr, err := New(ctx, uri)
+
+		if err != nil {
+			t.Fatalf("Failed to create writer for %s, %v", uri, err)
+		}
+
+		if wr.(*AtomicWriter).copy_buffer_size != expected {
+			t.Fatalf("Unexpected default copy buffer size for %s: %d", uri, wr.(*AtomicWriter).copy_buffer_size)
+		}
+
+		wr.(*AtomicWriter).Abort()
+	}
+
+	bucket, _ := newMockBucket()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return bucket, nil
+	}
+
+	wr, err := New(ctx, "mock://bucket/atomicwrite.txt", WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	if wr.(*AtomicWriter).copy_buffer_size != DEFAULT_REMOTE_COPY_BUFFER_SIZE {
+		t.Fatalf("Unexpected default copy buffer size for remote bucket: %d", wr.(*AtomicWriter).copy_buffer_size)
+	}
+
+	wr.(*AtomicWriter).Abort()
+
+	err = testAtomicWrite("mem://atomicwrite.txt", WithCopyBufferSize(3))
+
+	if err != nil {
+		t.Fatalf("Failed to write with custom copy buffer size, %v", err)
+	}
+}
+
+func BenchmarkCopyBufferSize(b *testing.B) {
+
+	ctx := context.Background()
+
+	body := bytes.Repeat([]byte(HELLO_WORLD), 1024*1024)
+
+	for _, size := range []int{1024, DEFAULT_LOCAL_COPY_BUFFER_SIZE, DEFAULT_REMOTE_COPY_BUFFER_SIZE} {
+
+		b.Run(fmt.Sprintf("%d", size), func(b *testing.B) {
+
+			path := filepath.Join(b.TempDir(), "atomicwrite.txt")
+
+			b.SetBytes(int64(len(body)))
+
+			for i := 0; i < b.N; i++ {
+
+				wr, err := New(ctx, path, WithCopyBufferSize(size))
+
+				if err != nil {
+					b.Fatalf("Failed to create writer, %v", err)
+				}
+
+				_, err = wr.Write(body)
+
+				if err != nil {
+					b.Fatalf("Failed to write bytes, %v", err)
+				}
+
+				err = wr.Close()
+
+				if err != nil {
+					b.Fatalf("Failed to close writer, %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestAtomicWriteCloseIdempotent(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	wr, err := New(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	errs := make(chan error)
+
+	for i := 0; i < 5; i++ {
+		go func() {
+			errs <- wr.Close()
+		}()
+	}
+
+	for i := 0; i < 5; i++ {
+
+		err := <-errs
+
+		if err != nil {
+			t.Fatalf("Failed to close writer, %v", err)
+		}
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Expected subsequent close to be a no-op, %v", err)
+	}
+
+	err = wr.(*AtomicWriter).Abort()
+
+	if err != nil {
+		t.Fatalf("Expected abort after close to be a no-op, %v", err)
+	}
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if !bytes.Equal(body, []byte(HELLO_WORLD)) {
+		t.Fatalf("Invalid data (%s) written to %s", string(body), path)
+	}
+}
+
+func TestAtomicWriteCommitted(t *testing.T) {
+
+	tests := []string{"close", "abort", "cancel"}
+
+	for _, label := range tests {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		wr, err := New(ctx, "mem://atomicwrite.txt")
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		aw := wr.(*AtomicWriter)
+
+		_, err = aw.Write([]byte(HELLO_WORLD))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		if aw.Committed() {
+			t.Fatalf("Expected open writer not to be committed")
+		}
+
+		switch label {
+		case "abort":
+			aw.Abort()
+		case "cancel":
+
+			// Cancelling the context the writer was created with causes Close to fail
+
+			cancel()
+
+			err := aw.Close()
+
+			if err == nil {
+				t.Fatalf("Expected close to fail after context was cancelled")
+			}
+
+		default:
+
+			err := aw.Close()
+
+			if err != nil {
+				t.Fatalf("Failed to close writer, %v", err)
+			}
+		}
+
+		expected := label == "close"
+
+		if aw.Committed() != expected {
+			t.Fatalf("Unexpected committed value for %s, expected %t", label, expected)
+		}
+	}
+}
+
+func TestAtomicWriteCloseFailed(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wr, err := New(ctx, "mem://atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	// Cancelling the context the writer was created with causes Close to fail
+
+	cancel()
+
+	first_err := wr.Close()
+
+	if first_err == nil {
+		t.Fatalf("Expected close to fail after context was cancelled")
+	}
+
+	for i := 0; i < 3; i++ {
+
+		err := wr.Close()
+
+		if err != first_err {
+			t.Fatalf("Expected subsequent close to return %v, got %v", first_err, err)
+		}
+	}
+}
+
+func TestAtomicWriteBlobSize(t *testing.T) {
+
+	ctx := context.Background()
+
+	wr, err := New(ctx, "mem://atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	defer aw.Abort()
+
+	_, err = aw.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	// memblob does not create the temporary file until the writer is closed
+
+	size, err := aw.BlobSize(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to derive blob size, %v", err)
+	}
+
+	if size != 0 {
+		t.Fatalf("Expected blob size to be 0, got %d", size)
+	}
+
+	// Simulate a backend which persists data as it is written
+
+	err = aw.bucket.WriteAll(ctx, aw.atomic_path, []byte(HELLO_WORLD), nil)
+
+	if err != nil {
+		t.Fatalf("Failed to write temporary file, %v", err)
+	}
+
+	size, err = aw.BlobSize(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to derive blob size, %v", err)
+	}
+
+	if size != int64(len(HELLO_WORLD)) {
+		t.Fatalf("Expected blob size to be %d, got %d", len(HELLO_WORLD), size)
+	}
+}
+
+func TestAtomicWriteBlobSizeFlush(t *testing.T) {
+
+	ctx := context.Background()
+
+	wr, err := New(ctx, "mem://atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	defer aw.Abort()
+
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+
+	go func() {
+
+		defer wg.Done()
+
+		for i := 0; i < 50; i++ {
+
+			aw.Write([]byte(HELLO_WORLD))
+			aw.Flush()
+			runtime.Gosched()
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+
+		_, err := aw.BlobSize(ctx)
+
+		if err != nil {
+			t.Fatalf("Failed to derive blob size, %v", err)
+		}
+
+		runtime.Gosched()
+	}
+
+	wg.Wait()
+}
+
+func TestNewWithTimeout(t *testing.T) {
+
+	ctx := context.Background()
+
+	tests := map[time.Duration]bool{
+		time.Minute:           true,
+		time.Millisecond * 10: false,
+	}
+
+	for timeout, ok := range tests {
+
+		path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+		wr, err := NewWithTimeout(ctx, path, timeout)
+
+		if err != nil {
+			t.Fatalf("Failed to create writer with %v timeout, %v", timeout, err)
+		}
+
+		_, err = wr.Write([]byte(HELLO_WORLD))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes with %v timeout, %v", timeout, err)
+		}
+
+		if !ok {
+			time.Sleep(timeout * 5)
+		}
+
+		err = wr.Close()
+
+		switch {
+		case ok && err != nil:
+			t.Fatalf("Failed to close writer with %v timeout, %v", timeout, err)
+		case !ok && err == nil:
+			t.Fatalf("Expected close to fail after %v timeout", timeout)
+		}
+
+		_, err = os.Stat(path)
+
+		if ok != (err == nil) {
+			t.Fatalf("Unexpected state for final path with %v timeout, %v", timeout, err)
+		}
+	}
+}
+
+func TestAtomicWriteConcurrent(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	wr, err := New(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	goroutines := 10
+	writes := 100
+
+	expected := make([]string, 0)
+	errs := make(chan error, goroutines)
+
+	wg := new(sync.WaitGroup)
+
+	for g := 0; g < goroutines; g++ {
+
+		for i := 0; i < writes; i++ {
+			expected = append(expected, fmt.Sprintf("%d-%d", g, i))
+		}
+
+		wg.Add(1)
+
+		go func(g int) {
+
+			defer wg.Done()
+
+			for i := 0; i < writes; i++ {
+
+				_, err := wr.Write([]byte(fmt.Sprintf("%d-%d\n", g, i)))
+
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err == nil {
+		t.Fatalf("Expected write after close to fail")
+	}
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+
+	sort.Strings(lines)
+	sort.Strings(expected)
+
+	if strings.Join(lines, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Unexpected content, got %d lines but expected %d", len(lines), len(expected))
+	}
+}
+
+func TestAtomicWriteCloser(t *testing.T) {
+
+	ctx := context.Background()
+
+	wr, err := New(ctx, "mem://bucket/a/atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw, ok := wr.(AtomicWriteCloser)
+
+	if !ok {
+		t.Fatalf("Expected writer to implement AtomicWriteCloser")
+	}
+
+	if aw.FinalPath() != "a/atomicwrite.txt" {
+		t.Fatalf("Unexpected final path: %s", aw.FinalPath())
+	}
+
+	re, err := regexp.Compile(`^a/atomicwrite-\d+\.txt$`)
+
+	if err != nil {
+		t.Fatalf("Failed to compile regular expression, %v", err)
+	}
+
+	if !re.MatchString(aw.AtomicPath()) {
+		t.Fatalf("Unexpected atomic path: %s", aw.AtomicPath())
+	}
+
+	err = aw.Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort writer, %v", err)
+	}
+}
+
+func TestAtomicWriteSetFinalPath(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.bin")
+
+	wr, err := New(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to create new writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	_, err = wr.Write([]byte("hello world"))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	err = aw.SetFinalPath("")
+
+	if err == nil {
+		t.Fatalf("Expected empty key to fail")
+	}
+
+	err = aw.SetFinalPath("text/atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to set final path, %v", err)
+	}
+
+	if aw.FinalPath() != "text/atomicwrite.txt" {
+		t.Fatalf("Unexpected final path: %s", aw.FinalPath())
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	v, err := os.ReadFile(filepath.Join(root, "text", "atomicwrite.txt"))
+
+	if err != nil {
+		t.Fatalf("Failed to read final path, %v", err)
+	}
+
+	if string(v) != "hello world" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+
+	_, err = os.Stat(path)
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected %s not to exist, %v", path, err)
+	}
+
+	err = aw.SetFinalPath("other.txt")
+
+	if err == nil {
+		t.Fatalf("Expected SetFinalPath to fail after Close")
+	}
+
+	wr, err = New(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to create new writer, %v", err)
+	}
+
+	aw = wr.(*AtomicWriter)
+
+	err = aw.Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort writer, %v", err)
+	}
+
+	err = aw.SetFinalPath("other.txt")
+
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("Expected ErrAborted, got %v", err)
+	}
+}
+
+func TestAtomicWriteStagingBucketAttributes(t *testing.T) {
+
+	ctx := context.Background()
+
+	wr, err := New(ctx, "mem://atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	defer aw.Abort()
+
+	// memblob does not create the temporary file until the writer is closed
+
+	_, err = aw.StagingBucketAttributes(ctx)
+
+	if gcerrors.Code(err) != gcerrors.NotFound {
+		t.Fatalf("Expected NotFound error, got %v", err)
+	}
+
+	// Simulate a backend which persists data as it is written
+
+	err = aw.bucket.WriteAll(ctx, aw.atomic_path, []byte(HELLO_WORLD), nil)
+
+	if err != nil {
+		t.Fatalf("Failed to write temporary file, %v", err)
+	}
+
+	attrs, err := aw.StagingBucketAttributes(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to derive staging attributes, %v", err)
+	}
+
+	if attrs.Size != int64(len(HELLO_WORLD)) {
+		t.Fatalf("Expected size to be %d, got %d", len(HELLO_WORLD), attrs.Size)
+	}
+
+	if attrs.ETag == "" {
+		t.Fatalf("Expected ETag to be defined")
+	}
+}
+
+// failingDeleteBucket is a mockBucket whose `Delete` method always fails.
+type failingDeleteBucket struct {
+	*mockBucket
+}
+
+var errMockDelete = errors.New("delete failed")
+
+func (b *failingDeleteBucket) Delete(ctx context.Context, key string) error {
+	return errMockDelete
+}
+
+func TestAtomicWriteCloseCleanupError(t *testing.T) {
+
+	ctx := context.Background()
+
+	_, mock := newMockBucket()
+
+	mock.blobs["atomicwrite.txt"] = []byte("existing")
+
+	bucket := blob.NewBucket(&failingDeleteBucket{mock})
+	defer bucket.Close()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return bucket, nil
+	}
+
+	wr, err := New(ctx, "mock://bucket/atomicwrite.txt", WithBucketOpener(opener), WithIfNotExists())
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	err = wr.Close()
+
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Expected commit error to be returned, got %v", err)
+	}
+
+	if !errors.Is(err, errMockDelete) {
+		t.Fatalf("Expected cleanup error to be wrapped, got %v", err)
+	}
+
+	// Removing the temporary file after a successful commit is not reported as a failure
+
+	wr, err = New(ctx, "mock://bucket/other.txt", WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Expected successful commit, got %v", err)
+	}
+}
+
+func TestAtomicGuarantee(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.bin")
+
+	size := 4 * 1024 * 1024
+
+	if testing.Short() {
+		size = 512 * 1024
+	}
+
+	old_body := bytes.Repeat([]byte("a"), size)
+	new_body := bytes.Repeat([]byte("b"), size)
+
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+
+	reads := 0
+
+	go func() {
+
+		defer close(errs)
+
+		for {
+
+			select {
+			case <-done:
+				return
+			default:
+				// pass
+			}
+
+			v, err := os.ReadFile(path)
+
+			switch {
+			case os.IsNotExist(err):
+				// pass
+			case err != nil:
+				errs <- fmt.Errorf("Failed to read %s, %w", path, err)
+				return
+			case bytes.Equal(v, old_body), bytes.Equal(v, new_body):
+				// pass
+			default:
+				errs <- fmt.Errorf("Read partial write of %d bytes", len(v))
+				return
+			}
+
+			reads += 1
+		}
+	}()
+
+	for _, body := range [][]byte{old_body, new_body, old_body, new_body} {
+
+		wr, err := New(ctx, path)
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		// Write in small chunks so the reader has plenty of opportunities to see partial data
+
+		for i := 0; i < len(body); i += 64 * 1024 {
+
+			_, err := wr.Write(body[i : i+64*1024])
+
+			if err != nil {
+				t.Fatalf("Failed to write data, %v", err)
+			}
+		}
+
+		err = wr.Close()
+
+		if err != nil {
+			t.Fatalf("Failed to close writer, %v", err)
+		}
+	}
+
+	close(done)
+
+	err, ok := <-errs
+
+	if ok {
+		t.Fatalf("Atomicity violated, %v", err)
+	}
+
+	if reads == 0 {
+		t.Fatalf("Expected at least one read")
+	}
+}
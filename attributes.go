@@ -0,0 +1,42 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+)
+
+// Attributes returns the attributes of 'key' in the bucket defined by 'bucket_uri', opening the bucket the same way AtomicWriter
+// instances do. This is useful for reading the ETag to pass to the `WithIfMatch` option. If 'key' does not exist the returned error
+// has the gocloud.dev/gcerrors code NotFound. Only the `WithBucketOpener`, `WithBucketWrapper` and `WithKeyNormalizer` options are
+// used.
+func Attributes(ctx context.Context, bucket_uri string, key string, opts ...Option) (*blob.Attributes, error) {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	key = normalizeKey(o.key_normalizers, key)
+
+	if key == "" {
+		return nil, fmt.Errorf("Key is empty")
+	}
+
+	bucket, err := o.openBucket(ctx, bucket_uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer o.closeBucket(bucket)
+
+	attrs, err := bucket.Attributes(ctx, key)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve attributes for %s, %w", key, err)
+	}
+
+	return attrs, nil
+}
@@ -0,0 +1,45 @@
+package atomicwrite
+
+import (
+	"context"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"gocloud.dev/gcerrors"
+	"testing"
+)
+
+func TestAttributes(t *testing.T) {
+
+	ctx := context.Background()
+
+	mem := memblob.OpenBucket(nil)
+	defer mem.Close()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return mem, nil
+	}
+
+	err := testAtomicWrite("mem://atomicwrite.txt", WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to write atomicwrite.txt, %v", err)
+	}
+
+	attrs, err := Attributes(ctx, "mem://", "atomicwrite.txt", WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to retrieve attributes, %v", err)
+	}
+
+	if attrs.Size != int64(len(HELLO_WORLD)) || attrs.ETag == "" {
+		t.Fatalf("Unexpected attributes: %v", attrs)
+	}
+
+	// The shared bucket must still be open
+
+	_, err = Attributes(ctx, "mem://", "missing.txt", WithBucketOpener(opener))
+
+	if gcerrors.Code(err) != gcerrors.NotFound {
+		t.Fatalf("Expected NotFound error, got %v", err)
+	}
+}
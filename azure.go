@@ -0,0 +1,53 @@
+package atomicwrite
+
+import (
+	"fmt"
+	"gocloud.dev/blob"
+	"net/http"
+)
+
+// WithAzureHeaders returns an Option which assigns 'headers' to the final path (but not the intermediate temporary file).
+// Azure Blob Storage stores a set of standard HTTP headers with each blob; the following are supported: Cache-Control,
+// Content-Disposition, Content-Encoding, Content-Language and Content-Type. Header names are case-insensitive. These headers
+// are assigned using the corresponding `blob.WriterOptions` properties so they also apply to other drivers which support them.
+// Azure-specific settings, like the blob access tier, require the types exposed by the azureblob driver and should be assigned
+// using the `WithFinalBeforeWrite` option. Any other header causes the `New` constructor to return an error. This option may be
+// specified multiple times.
+func WithAzureHeaders(headers map[string]string) Option {
+
+	return func(o *options) {
+
+		if o.final_headers == nil {
+			o.final_headers = make(map[string]string)
+		}
+
+		for k, v := range headers {
+			o.final_headers[k] = v
+		}
+	}
+}
+
+// applyHeaders assigns 'headers' to the corresponding properties of 'writer_opts', returning an error for
+// any header which does not have a corresponding property.
+func applyHeaders(writer_opts *blob.WriterOptions, headers map[string]string) error {
+
+	for k, v := range headers {
+
+		switch http.CanonicalHeaderKey(k) {
+		case "Cache-Control":
+			writer_opts.CacheControl = v
+		case "Content-Disposition":
+			writer_opts.ContentDisposition = v
+		case "Content-Encoding":
+			writer_opts.ContentEncoding = v
+		case "Content-Language":
+			writer_opts.ContentLanguage = v
+		case "Content-Type":
+			writer_opts.ContentType = v
+		default:
+			return fmt.Errorf("Unsupported header %s", k)
+		}
+	}
+
+	return nil
+}
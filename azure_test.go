@@ -0,0 +1,69 @@
+package atomicwrite
+
+import (
+	"context"
+	"gocloud.dev/blob"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithAzureHeaders(t *testing.T) {
+
+	ctx := context.Background()
+
+	headers := map[string]string{
+		"cache-control":       "max-age=3600",
+		"Content-Disposition": "attachment",
+		"Content-Type":        "text/plain",
+	}
+
+	o := defaultOptions()
+	WithAzureHeaders(headers)(o)
+
+	writer_opts, err := o.finalWriterOptions(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to derive final writer options, %v", err)
+	}
+
+	if writer_opts.CacheControl != "max-age=3600" || writer_opts.ContentDisposition != "attachment" || writer_opts.ContentType != "text/plain" {
+		t.Fatalf("Unexpected writer options: %v", writer_opts)
+	}
+
+	if o.stagingWriterOptions() != nil {
+		t.Fatalf("Expected headers not to be assigned to the temporary file")
+	}
+
+	root := t.TempDir()
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	err = testAtomicWrite(path, WithAzureHeaders(headers))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	bucket, err := blob.OpenBucket(ctx, "file://"+root)
+
+	if err != nil {
+		t.Fatalf("Failed to open bucket, %v", err)
+	}
+
+	defer bucket.Close()
+
+	attrs, err := bucket.Attributes(ctx, "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to derive attributes, %v", err)
+	}
+
+	if attrs.CacheControl != "max-age=3600" {
+		t.Fatalf("Unexpected cache control: %s", attrs.CacheControl)
+	}
+
+	_, err = New(ctx, path, WithAzureHeaders(map[string]string{"x-ms-access-tier": "Cool"}))
+
+	if err == nil {
+		t.Fatalf("Expected unsupported header to fail")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build go1.18
+// +build go1.18
+
+package atomicwrite
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+)
+
+// WriteBinary marshals 'v' using its `MarshalBinary` method and atomically writes the result to 'uri'. This works with any
+// type implementing the `encoding.BinaryMarshaler` interface, for example `time.Time` and `url.URL`. WriteBinary requires
+// Go 1.18 or higher.
+func WriteBinary[T encoding.BinaryMarshaler](ctx context.Context, uri string, v T, opts ...Option) error {
+
+	body, err := v.MarshalBinary()
+
+	if err != nil {
+		return fmt.Errorf("Failed to marshal value, %w", err)
+	}
+
+	return writeBytes(ctx, uri, body, opts...)
+}
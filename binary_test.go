@@ -0,0 +1,44 @@
+//go:build go1.18
+// +build go1.18
+
+package atomicwrite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteBinary(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.bin")
+
+	now := time.Now()
+
+	err := WriteBinary(ctx, path, now)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	body, err := readBytes(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	var v time.Time
+
+	err = v.UnmarshalBinary(body)
+
+	if err != nil {
+		t.Fatalf("Failed to unmarshal %s, %v", path, err)
+	}
+
+	if !v.Equal(now) {
+		t.Fatalf("Unexpected value: %v", v)
+	}
+}
@@ -0,0 +1,121 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The extension of the "sidecar" files used by the gocloud.dev/blob/fileblob package to store attributes
+const fileblob_attrs_ext string = ".attrs"
+
+// openBucket opens the bucket defined by 'bucket_uri' using the function defined by the `WithBucketOpener` option.
+func (o *options) openBucket(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+
+	bucket, err := o.wrappedBucketOpener()(ctx, bucket_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open bucket %s, %w", bucket_uri, err)
+	}
+
+	return bucket, nil
+}
+
+// closeBucket closes 'bucket' if it was opened by the default bucket opener. Buckets returned by the function defined by the
+// `WithBucketOpener` option are owned by the caller, who may share them between writers, and are left open.
+func (o *options) closeBucket(bucket *blob.Bucket) error {
+
+	if o.custom_bucket_opener {
+		return nil
+	}
+
+	return bucket.Close()
+}
+
+// lockKey acquires the same lock acquired by AtomicWriter instances created with the `WithFlock` option for 'key' in the bucket
+// defined by 'bucket_uri', if the `WithFlock` option is defined, and returns a function to release it. Otherwise the returned
+// function is a no-op.
+func (o *options) lockKey(bucket_uri string, key string) (func() error, error) {
+
+	if !o.flock {
+		return func() error { return nil }, nil
+	}
+
+	root, err := localBucketPath(bucket_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive lock path, %w", err)
+	}
+
+	lock_path := filepath.Join(root, filepath.FromSlash(key)) + LOCK_EXTENSION
+
+	unlock, err := lockFile(lock_path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to acquire lock %s, %w", lock_path, err)
+	}
+
+	return unlock, nil
+}
+
+// localBucketPath returns the absolute local filesystem path for the bucket defined by 'bucket_uri', which may be a schema-less
+// path or a `file://` URI. All other URIs return an error wrapping ErrNotSupported.
+func localBucketPath(bucket_uri string) (string, error) {
+
+	u, err := url.Parse(bucket_uri)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse bucket URI, %w", err)
+	}
+
+	switch u.Scheme {
+	case "":
+
+		path, err := expandHome(bucket_uri)
+
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Abs(path)
+
+	case "file":
+		return filepath.FromSlash(u.Path), nil
+	default:
+		return "", fmt.Errorf("%s URIs are not local filesystem paths, %w", u.Scheme, ErrNotSupported)
+	}
+}
+
+// localKeyPath returns the local filesystem path for 'key' in the `file://` bucket whose root directory is 'root'. It returns false
+// if 'key' contains characters or sequences which the gocloud.dev/blob/fileblob package escapes, or "." and ".." segments, since
+// the path derived here would not match the path used by that package.
+func localKeyPath(root string, key string) (string, bool) {
+
+	if strings.Contains(key, "__0x") || strings.Contains(key, "//") || strings.HasSuffix(key, "/") || strings.HasSuffix(key, fileblob_attrs_ext) {
+		return "", false
+	}
+
+	if os.PathSeparator != '/' && strings.ContainsRune(key, os.PathSeparator) {
+		return "", false
+	}
+
+	for _, r := range key {
+
+		if r < 32 || (os.PathSeparator == '\\' && strings.ContainsRune("<>:\"|?*", r)) {
+			return "", false
+		}
+	}
+
+	for _, part := range strings.Split(key, "/") {
+
+		if part == "." || part == ".." {
+			return "", false
+		}
+	}
+
+	return filepath.Join(root, filepath.FromSlash(key)), true
+}
@@ -0,0 +1,53 @@
+package atomicwrite
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalKeyPath(t *testing.T) {
+
+	root := t.TempDir()
+
+	tests := map[string]bool{
+		"atomicwrite.txt":       true,
+		"a/b/atomicwrite.txt":   true,
+		"a/../atomicwrite.txt":  false,
+		"./atomicwrite.txt":     false,
+		"a//atomicwrite.txt":    false,
+		"a/":                    false,
+		"atomicwrite.txt.attrs": false,
+		"atomic__0x2f__write":   false,
+		"atomic\x01write.txt":   false,
+		"atomic write é.txt":    true,
+	}
+
+	for key, expected := range tests {
+
+		path, ok := localKeyPath(root, key)
+
+		if ok != expected {
+			t.Fatalf("Unexpected result for %q: %t", key, ok)
+		}
+
+		if ok && path != filepath.Join(root, filepath.FromSlash(key)) {
+			t.Fatalf("Unexpected path for %q: %s", key, path)
+		}
+	}
+
+	path, err := localBucketPath("file://" + filepath.ToSlash(root))
+
+	if err != nil {
+		t.Fatalf("Failed to derive local bucket path, %v", err)
+	}
+
+	if path != root {
+		t.Fatalf("Unexpected bucket path: %s", path)
+	}
+
+	_, err = localBucketPath("mem://")
+
+	if err == nil {
+		t.Fatalf("Expected mem:// bucket to fail")
+	}
+}
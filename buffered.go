@@ -0,0 +1,124 @@
+package atomicwrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// type BufferedAtomicWriter implements an atomic io.WriteCloser instance which accumulates all the data written in memory and
+// only writes it to the underlying bucket, using an AtomicWriter instance, when the `Close` method is invoked. This avoids
+// creating an intermediate temporary file until the data is ready to be committed, which is useful for small writes, and
+// is the equivalent of `os.WriteFile` with atomic semantics. Since all the data is held in memory it is not suitable for
+// large writes. BufferedAtomicWriter instances are safe for concurrent use by multiple goroutines.
+type BufferedAtomicWriter struct {
+	// The context.Context instance passed to the `NewBuffered` constructor
+	ctx context.Context
+	// The URI passed to the `NewBuffered` constructor
+	uri string
+	// The options used to create the AtomicWriter instance when data is committed
+	opts []Option
+	// The data written so far
+	buf bytes.Buffer
+	// The state of the writer (open, closed or aborted)
+	state int32
+	// A mutex guarding buf and state
+	mu sync.Mutex
+}
+
+// NewBuffered returns a new BufferedAtomicWriter instance for 'uri'. 'uri' and 'opts' are the same as the `New` constructor
+// however the bucket is not opened, and options are not applied, until the `Close` method is invoked.
+func NewBuffered(ctx context.Context, uri string, opts ...Option) (*BufferedAtomicWriter, error) {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	err := o.validateURIs(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = parseURI(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bw := &BufferedAtomicWriter{
+		ctx:  ctx,
+		uri:  uri,
+		opts: opts,
+	}
+
+	return bw, nil
+}
+
+// Write appends 'b' to the in-memory buffer.
+func (bw *BufferedAtomicWriter) Write(b []byte) (int, error) {
+
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	switch bw.state {
+	case state_aborted:
+		return 0, ErrAborted
+	case state_closed:
+		return 0, fmt.Errorf("Atomic writer has been closed")
+	}
+
+	return bw.buf.Write(b)
+}
+
+// Len returns the number of bytes written so far.
+func (bw *BufferedAtomicWriter) Len() int {
+
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	return bw.buf.Len()
+}
+
+// Close writes the buffered data to an intermediate temporary file and commits it to the final path in one operation. Subsequent
+// calls to Close are no-ops which return nil, unless the writer has been aborted in which case ErrAborted is returned.
+func (bw *BufferedAtomicWriter) Close() error {
+
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	switch bw.state {
+	case state_aborted:
+		return ErrAborted
+	case state_closed:
+		return nil
+	}
+
+	bw.state = state_closed
+
+	body := bw.buf.Bytes()
+	bw.buf = bytes.Buffer{}
+
+	return writeBytes(bw.ctx, bw.uri, body, bw.opts...)
+}
+
+// Abort discards the buffered data. Since nothing has been written to the underlying bucket there is nothing to remove. Abort
+// is a no-op if the writer has already been closed or aborted so it is safe to defer calling it immediately after the writer
+// has been created.
+func (bw *BufferedAtomicWriter) Abort() error {
+
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	if bw.state != state_open {
+		return nil
+	}
+
+	bw.state = state_aborted
+	bw.buf = bytes.Buffer{}
+
+	return nil
+}
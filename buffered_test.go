@@ -0,0 +1,117 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewBuffered(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	bw, err := NewBuffered(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to create buffered writer, %v", err)
+	}
+
+	_, err = bw.Write([]byte("hello "))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	_, err = bw.Write([]byte("world"))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	if bw.Len() != 11 {
+		t.Fatalf("Unexpected length: %d", bw.Len())
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no files before Close, got %d", len(entries))
+	}
+
+	err = bw.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close buffered writer, %v", err)
+	}
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != "hello world" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+
+	_, err = bw.Write([]byte("again"))
+
+	if err == nil {
+		t.Fatalf("Expected write after Close to fail")
+	}
+
+	err = bw.Close()
+
+	if err != nil {
+		t.Fatalf("Expected subsequent Close to be a no-op, %v", err)
+	}
+}
+
+func TestNewBufferedAbort(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	bw, err := NewBuffered(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to create buffered writer, %v", err)
+	}
+
+	_, err = bw.Write([]byte("hello world"))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	err = bw.Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort buffered writer, %v", err)
+	}
+
+	err = bw.Close()
+
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("Expected ErrAborted, got %v", err)
+	}
+
+	_, err = os.Stat(path)
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected %s not to exist, %v", path, err)
+	}
+}
@@ -0,0 +1,49 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+)
+
+// NewFromChan atomically writes each byte slice received from 'ch' to 'uri' until 'ch' is closed, at which point the data is
+// committed. This allows a producer goroutine to feed data to storage without managing the lifecycle of the writer. If 'ctx'
+// is cancelled before 'ch' is closed, or if writing fails, the writer is aborted and nothing is written to 'uri'. 'uri' and
+// 'opts' are the same as the `New` constructor.
+func NewFromChan(ctx context.Context, uri string, ch <-chan []byte, opts ...Option) error {
+
+	wr, err := New(ctx, uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create atomic writer, %w", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	for {
+
+		select {
+		case <-ctx.Done():
+			aw.Abort()
+			return ctx.Err()
+		case b, ok := <-ch:
+
+			if !ok {
+
+				err = wr.Close()
+
+				if err != nil {
+					return fmt.Errorf("Failed to close atomic writer, %w", err)
+				}
+
+				return nil
+			}
+
+			_, err = wr.Write(b)
+
+			if err != nil {
+				aw.Abort()
+				return fmt.Errorf("Failed to write data, %w", err)
+			}
+		}
+	}
+}
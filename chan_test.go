@@ -0,0 +1,80 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFromChan(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	ch := make(chan []byte)
+
+	go func() {
+
+		for _, s := range []string{"hello", " ", "world"} {
+			ch <- []byte(s)
+		}
+
+		close(ch)
+	}()
+
+	err := NewFromChan(ctx, path, ch)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != "hello world" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+}
+
+func TestNewFromChanCancel(t *testing.T) {
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch := make(chan []byte)
+	errs := make(chan error)
+
+	go func() {
+		errs <- NewFromChan(ctx, path, ch)
+	}()
+
+	ch <- []byte("hello")
+	cancel()
+
+	err := <-errs
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no files after cancellation, got %d", len(entries))
+	}
+}
@@ -0,0 +1,84 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+)
+
+// Clone copies 'src_key' to 'dst_key' in the bucket defined by 'bucket_uri', replacing 'dst_key' if it already exists. Unlike the
+// `Move` method 'src_key' is preserved. The copy is performed using the bucket's native copy operation, for example S3 `CopyObject`
+// or GCS object rewrites, so data is not downloaded and uploaded again. Each of the gocloud.dev/blob drivers replaces 'dst_key' in
+// a single step (the fileblob driver writes to a temporary file and renames it) so readers of 'dst_key' will only ever see the
+// previous data or the new data and no additional staging is needed. If 'src_key' does not exist an error wrapping ErrNotFound is
+// returned. If the `WithIfNotExists` option is defined and 'dst_key' already exists an error wrapping ErrAlreadyExists is returned;
+// as with `PutIfAbsent` this is checked immediately before copying. Only the `WithBucketOpener`, `WithFlock`, `WithIfNotExists` and
+// `WithKeyNormalizer` options are used.
+func Clone(ctx context.Context, bucket_uri string, src_key string, dst_key string, opts ...Option) error {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	src_key = normalizeKey(o.key_normalizers, src_key)
+	dst_key = normalizeKey(o.key_normalizers, dst_key)
+
+	if src_key == "" || dst_key == "" {
+		return fmt.Errorf("Key is empty")
+	}
+
+	if src_key == dst_key {
+		return fmt.Errorf("Source and destination keys are the same")
+	}
+
+	unlock, err := o.lockKey(bucket_uri, dst_key)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	bucket, err := o.openBucket(ctx, bucket_uri)
+
+	if err != nil {
+		return err
+	}
+
+	defer o.closeBucket(bucket)
+
+	return copyKey(ctx, bucket, src_key, dst_key, o.if_not_exists)
+}
+
+// copyKey copies 'src_key' to 'dst_key' in 'bucket' using the bucket's native copy operation. If 'create_only' is true an error
+// wrapping ErrAlreadyExists is returned if 'dst_key' exists.
+func copyKey(ctx context.Context, bucket *blob.Bucket, src_key string, dst_key string, create_only bool) error {
+
+	if create_only {
+
+		exists, err := bucket.Exists(ctx, dst_key)
+
+		if err != nil {
+			return fmt.Errorf("Failed to determine whether %s exists, %w", dst_key, err)
+		}
+
+		if exists {
+			return fmt.Errorf("%s already exists, %w", dst_key, ErrAlreadyExists)
+		}
+	}
+
+	err := bucket.Copy(ctx, dst_key, src_key, nil)
+
+	if gcerrors.Code(err) == gcerrors.NotFound {
+		return fmt.Errorf("%s does not exist, %w", src_key, ErrNotFound)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed to copy %s to %s, %w", src_key, dst_key, err)
+	}
+
+	return nil
+}
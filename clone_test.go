@@ -0,0 +1,62 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"gocloud.dev/blob"
+	"path/filepath"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	err := writeBytes(ctx, filepath.Join(root, "a.txt"), []byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write a.txt, %v", err)
+	}
+
+	err = Clone(ctx, bucket_uri, "a.txt", "b/a.txt", WithIfNotExists())
+
+	if err != nil {
+		t.Fatalf("Failed to clone a.txt, %v", err)
+	}
+
+	err = Clone(ctx, bucket_uri, "a.txt", "b/a.txt", WithIfNotExists())
+
+	if !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+	}
+
+	err = Clone(ctx, bucket_uri, "c.txt", "d.txt")
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	bucket, err := blob.OpenBucket(ctx, bucket_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to open bucket, %v", err)
+	}
+
+	defer bucket.Close()
+
+	for _, key := range []string{"a.txt", "b/a.txt"} {
+
+		v, err := bucket.ReadAll(ctx, key)
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", key, err)
+		}
+
+		if string(v) != HELLO_WORLD {
+			t.Fatalf("Unexpected body for %s: %s", key, string(v))
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package atomicwrite
+
+import (
+	"cloud.google.com/go/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"gocloud.dev/gcerrors"
+	"net/http"
+)
+
+// ErrPreconditionFailed is returned by the `Close` method when a condition defined by the `WithIfNotExists` or `WithIfMatch`
+// options is not met.
+var ErrPreconditionFailed = errors.New("Precondition failed")
+
+// ErrAlreadyExists is returned by the `PutIfAbsent` method when the key being written already exists.
+var ErrAlreadyExists = errors.New("Already exists")
+
+// WithIfNotExists returns an Option which causes the `Close` method to discard the data written, and return ErrPreconditionFailed,
+// if the final path already exists. The gocloud.dev/blob package does not support conditional writes so the final path is checked
+// immediately before data is copied to it; another writer may still create it in between. Combine this option with the `WithFlock`
+// option, or use the `NewLocking` constructor, to serialize writers.
+func WithIfNotExists() Option {
+
+	return func(o *options) {
+		o.if_not_exists = true
+	}
+}
+
+// WithIfMatch returns an Option which causes the `Close` method to discard the data written, and return ErrPreconditionFailed,
+// unless the final path exists and its ETag (as reported by the underlying bucket) is 'etag'. If 'etag' is "*" the final path
+// only needs to exist. As with the `WithIfNotExists` option the ETag is checked immediately before data is copied to the final path.
+func WithIfMatch(etag string) Option {
+
+	return func(o *options) {
+		o.if_match = etag
+	}
+}
+
+// PutIfAbsent atomically writes 'data' to 'uri' only if 'uri' does not already exist, returning an error wrapping ErrAlreadyExists
+// if it does. This is useful for "claiming" a key in distributed systems. 'uri' and 'opts' are the same as the `New` constructor.
+// For S3 buckets (opened using the default AWS SDK) and GCS buckets the data is written to 'uri' using the backend's native
+// conditional write, the "If-None-Match: *" header and the "ifGenerationMatch=0" precondition respectively, so the write fails if
+// another writer creates 'uri' first. For all other buckets, as with the `WithIfNotExists` option, 'uri' is checked immediately
+// before data is copied to it. Another writer may still create 'uri' in between (a time-of-check to time-of-use race) in which
+// case its data is replaced. If every writer uses this method on a local filesystem pass the `WithFlock` option to remove that race.
+func PutIfAbsent(ctx context.Context, uri string, data []byte, opts ...Option) error {
+
+	opts = append(opts, WithIfNotExists(), WithFinalBeforeWrite(ifNotExistsBeforeWrite))
+
+	err := writeBytes(ctx, uri, data, opts...)
+
+	if errors.Is(err, ErrPreconditionFailed) || isPreconditionFailed(err) {
+		return fmt.Errorf("%s already exists, %w", uri, ErrAlreadyExists)
+	}
+
+	return err
+}
+
+// checkPreconditions returns an error wrapping ErrPreconditionFailed if the conditions defined by the `WithIfNotExists` or
+// `WithIfMatch` options are not met for the final path. The caller must hold aw.mu.
+func (aw *AtomicWriter) checkPreconditions(ctx context.Context) error {
+
+	if !aw.if_not_exists && aw.if_match == "" {
+		return nil
+	}
+
+	attrs, err := aw.bucket.Attributes(ctx, aw.final_path)
+
+	exists := true
+
+	if err != nil {
+
+		if gcerrors.Code(err) != gcerrors.NotFound {
+			return fmt.Errorf("Failed to retrieve attributes for %s, %w", aw.final_path, err)
+		}
+
+		exists = false
+	}
+
+	switch {
+	case aw.if_not_exists && exists:
+		return fmt.Errorf("%s already exists, %w", aw.final_path, ErrPreconditionFailed)
+	case aw.if_match != "" && !exists:
+		return fmt.Errorf("%s does not exist, %w", aw.final_path, ErrPreconditionFailed)
+	case aw.if_match != "" && aw.if_match != "*" && attrs.ETag != aw.if_match:
+		return fmt.Errorf("ETag for %s does not match, %w", aw.final_path, ErrPreconditionFailed)
+	}
+
+	return nil
+}
+
+// ifNotExistsBeforeWrite is a BeforeWriteFunc which configures S3 and GCS writers to only create an object if it does not already
+// exist, using the backend's native conditional write. Writers for other backends are left unchanged.
+func ifNotExistsBeforeWrite(asFunc func(interface{}) bool) error {
+
+	var uploader *s3manager.Uploader
+
+	if asFunc(&uploader) {
+
+		// Uploads are completed by either a PutObject or a CompleteMultipartUpload request; sending the header with the
+		// requests for individual parts is not supported
+
+		set_header := func(r *request.Request) {
+
+			switch r.Operation.Name {
+			case "PutObject", "CompleteMultipartUpload":
+				r.HTTPRequest.Header.Set("If-None-Match", "*")
+			}
+		}
+
+		uploader.RequestOptions = append(uploader.RequestOptions, set_header)
+		return nil
+	}
+
+	var obj **storage.ObjectHandle
+
+	if asFunc(&obj) {
+		*obj = (*obj).If(storage.Conditions{DoesNotExist: true})
+		return nil
+	}
+
+	return nil
+}
+
+// isPreconditionFailed returns true if 'err' reports that the condition set by ifNotExistsBeforeWrite was not met.
+func isPreconditionFailed(err error) bool {
+
+	if err == nil {
+		return false
+	}
+
+	if gcerrors.Code(err) == gcerrors.FailedPrecondition {
+		return true
+	}
+
+	// The gocloud.dev/blob/s3blob package does not map S3 errors to gcerrors.FailedPrecondition and
+	// the AWS SDK's errors do not implement Unwrap so the chain of errors is followed by hand
+
+	var aws_err awserr.Error
+
+	if !errors.As(err, &aws_err) {
+		return false
+	}
+
+	for aws_err != nil {
+
+		req_err, ok := aws_err.(awserr.RequestFailure)
+
+		if ok && req_err.StatusCode() == http.StatusPreconditionFailed {
+			return true
+		}
+
+		next, ok := aws_err.OrigErr().(awserr.Error)
+
+		if !ok {
+			break
+		}
+
+		aws_err = next
+	}
+
+	return false
+}
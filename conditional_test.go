@@ -0,0 +1,314 @@
+package atomicwrite
+
+import (
+	"cloud.google.com/go/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConditionalWrites(t *testing.T) {
+
+	ctx := context.Background()
+
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return bucket, nil
+	}
+
+	uri := "mem://atomicwrite.txt"
+
+	err := writeBytes(ctx, uri, []byte(HELLO_WORLD), WithBucketOpener(opener), WithIfMatch("*"))
+
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Expected ErrPreconditionFailed for missing file, got %v", err)
+	}
+
+	err = writeBytes(ctx, uri, []byte(HELLO_WORLD), WithBucketOpener(opener), WithIfNotExists())
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", uri, err)
+	}
+
+	err = writeBytes(ctx, uri, []byte(HELLO_WORLD), WithBucketOpener(opener), WithIfNotExists())
+
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Expected ErrPreconditionFailed for existing file, got %v", err)
+	}
+
+	attrs, err := bucket.Attributes(ctx, "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to retrieve attributes, %v", err)
+	}
+
+	err = writeBytes(ctx, uri, []byte(HELLO_WORLD), WithBucketOpener(opener), WithIfMatch("\"nope\""))
+
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Expected ErrPreconditionFailed for mismatched ETag, got %v", err)
+	}
+
+	for _, etag := range []string{attrs.ETag, "*"} {
+
+		err = writeBytes(ctx, uri, []byte(HELLO_WORLD), WithBucketOpener(opener), WithIfMatch(etag))
+
+		if err != nil {
+			t.Fatalf("Failed to write %s with ETag %s, %v", uri, etag, err)
+		}
+	}
+
+	iter := bucket.List(nil)
+	count := 0
+
+	for {
+		_, err := iter.Next(ctx)
+
+		if err != nil {
+			break
+		}
+
+		count += 1
+	}
+
+	if count != 1 {
+		t.Fatalf("Expected failed writes to be cleaned up, got %d objects", count)
+	}
+}
+
+func TestPutIfAbsent(t *testing.T) {
+
+	ctx := context.Background()
+
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return bucket, nil
+	}
+
+	uri := "mem://atomicwrite.txt"
+
+	err := PutIfAbsent(ctx, uri, []byte(HELLO_WORLD), WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to put %s, %v", uri, err)
+	}
+
+	err = PutIfAbsent(ctx, uri, []byte("Goodbye world"), WithBucketOpener(opener))
+
+	if !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+	}
+
+	v, err := bucket.ReadAll(ctx, "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to read atomicwrite.txt, %v", err)
+	}
+
+	if string(v) != HELLO_WORLD {
+		t.Fatalf("Unexpected body: %s", string(v))
+	}
+}
+
+func TestIfNotExistsBeforeWriteS3(t *testing.T) {
+
+	mu := new(sync.Mutex)
+	exists := false
+	var headers []string
+
+	handler := func(rsp http.ResponseWriter, req *http.Request) {
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if req.Method != http.MethodPut {
+			http.Error(rsp, "Unsupported method", http.StatusMethodNotAllowed)
+			return
+		}
+
+		headers = append(headers, req.Header.Get("If-None-Match"))
+
+		if exists {
+			rsp.WriteHeader(http.StatusPreconditionFailed)
+			rsp.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`))
+			return
+		}
+
+		exists = true
+		rsp.WriteHeader(http.StatusOK)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	cfg := &aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(server.URL),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:       aws.Int(0),
+	}
+
+	sess, err := session.NewSession(cfg)
+
+	if err != nil {
+		t.Fatalf("Failed to create session, %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+
+		uploader := s3manager.NewUploader(sess)
+
+		as := func(i interface{}) bool {
+
+			p, ok := i.(**s3manager.Uploader)
+
+			if ok {
+				*p = uploader
+			}
+
+			return ok
+		}
+
+		err := ifNotExistsBeforeWrite(as)
+
+		if err != nil {
+			t.Fatalf("Failed to configure uploader, %v", err)
+		}
+
+		input := &s3manager.UploadInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("atomicwrite.txt"),
+			Body:   strings.NewReader(HELLO_WORLD),
+		}
+
+		_, err = uploader.Upload(input)
+
+		switch i {
+		case 0:
+
+			if err != nil {
+				t.Fatalf("Failed to upload, %v", err)
+			}
+
+		default:
+
+			if !isPreconditionFailed(fmt.Errorf("Failed to close, %w", err)) {
+				t.Fatalf("Expected precondition failed error, got %v", err)
+			}
+		}
+	}
+
+	if len(headers) != 2 || headers[0] != "*" || headers[1] != "*" {
+		t.Fatalf("Unexpected If-None-Match headers: %v", headers)
+	}
+}
+
+func TestIfNotExistsBeforeWriteGCS(t *testing.T) {
+
+	ctx := context.Background()
+
+	mu := new(sync.Mutex)
+	var conditions []string
+
+	handler := func(rsp http.ResponseWriter, req *http.Request) {
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		conditions = append(conditions, req.URL.Query().Get("ifGenerationMatch"))
+
+		rsp.Header().Set("Content-Type", "application/json")
+		rsp.WriteHeader(http.StatusPreconditionFailed)
+		rsp.Write([]byte(`{"error":{"code":412,"message":"Precondition Failed"}}`))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	client, err := storage.NewClient(ctx, option.WithEndpoint(server.URL+"/storage/v1/"), option.WithoutAuthentication())
+
+	if err != nil {
+		t.Fatalf("Failed to create client, %v", err)
+	}
+
+	defer client.Close()
+
+	obj := client.Bucket("bucket").Object("atomicwrite.txt")
+	obj_p := &obj
+
+	as := func(i interface{}) bool {
+
+		p, ok := i.(***storage.ObjectHandle)
+
+		if ok {
+			*p = obj_p
+		}
+
+		return ok
+	}
+
+	err = ifNotExistsBeforeWrite(as)
+
+	if err != nil {
+		t.Fatalf("Failed to configure object handle, %v", err)
+	}
+
+	wr := (*obj_p).NewWriter(ctx)
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	var api_err *googleapi.Error
+
+	if !errors.As(err, &api_err) || api_err.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected precondition failed error, got %v", err)
+	}
+
+	if len(conditions) != 1 || conditions[0] != "0" {
+		t.Fatalf("Unexpected ifGenerationMatch parameters: %v", conditions)
+	}
+}
+
+func TestIfNotExistsBeforeWriteOther(t *testing.T) {
+
+	ctx := context.Background()
+
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+
+	wr_opts := &blob.WriterOptions{
+		BeforeWrite: ifNotExistsBeforeWrite,
+	}
+
+	err := bucket.WriteAll(ctx, "atomicwrite.txt", []byte(HELLO_WORLD), wr_opts)
+
+	if err != nil {
+		t.Fatalf("Failed to write atomicwrite.txt, %v", err)
+	}
+
+	if isPreconditionFailed(errors.New("Failed")) {
+		t.Fatalf("Expected error not to be reported as a precondition failure")
+	}
+}
@@ -0,0 +1,82 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// DEFERRED_KEY is the provisional key, relative to the bucket, from which the intermediate temporary file for DeferredWriter
+// instances is derived.
+const DEFERRED_KEY string = "atomicwrite-deferred"
+
+// type DeferredWriter implements an atomic writer whose final key is not known until its data is committed, for example when the
+// key is derived from a hash of the data. Since its `Close` method requires a key it does not implement the io.WriteCloser interface.
+type DeferredWriter struct {
+	// The underlying AtomicWriter instance
+	writer *AtomicWriter
+}
+
+// NewDeferred returns a new DeferredWriter instance which writes data to an intermediate temporary file in the bucket defined by
+// 'bucket_uri' (for example "file:///data", "/data" or "s3://bucket?region=us-east-1"), at the root of the bucket. The final key is passed
+// to the `Close` method. 'opts' are the same as the `New` constructor.
+func NewDeferred(ctx context.Context, bucket_uri string, opts ...Option) (*DeferredWriter, error) {
+
+	u, err := url.Parse(bucket_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse bucket URI, %w", err)
+	}
+
+	uri := filepath.Join(bucket_uri, DEFERRED_KEY)
+
+	if u.Scheme != "" {
+		u.Path = path.Join("/", u.Path, DEFERRED_KEY)
+		uri = u.String()
+	}
+
+	wr, err := newAtomicWriter(ctx, uri, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dw := &DeferredWriter{
+		writer: wr,
+	}
+
+	return dw, nil
+}
+
+// Write writes 'b' to the intermediate temporary file.
+func (dw *DeferredWriter) Write(b []byte) (int, error) {
+	return dw.writer.Write(b)
+}
+
+// Close copies the data written to the intermediate temporary file to 'final_key' (relative to the bucket passed to the
+// `NewDeferred` constructor) and removes the temporary file. 'final_key' is transformed by any functions defined by the
+// `WithKeyNormalizer` option. As with the `AtomicWriter.Close` method subsequent calls return the result of the first call.
+func (dw *DeferredWriter) Close(final_key string) error {
+
+	aw := dw.writer
+
+	if atomic.LoadInt32(&aw.state) != state_open {
+		return aw.Close()
+	}
+
+	err := aw.SetFinalPath(final_key)
+
+	if err != nil {
+		return err
+	}
+
+	return aw.Close()
+}
+
+// Abort discards the data written to the intermediate temporary file.
+func (dw *DeferredWriter) Abort() error {
+	return dw.writer.Abort()
+}
@@ -0,0 +1,129 @@
+package atomicwrite
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewDeferred(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	for _, bucket_uri := range []string{root, "file://" + root} {
+
+		dw, err := NewDeferred(ctx, bucket_uri)
+
+		if err != nil {
+			t.Fatalf("Failed to create deferred writer for %s, %v", bucket_uri, err)
+		}
+
+		_, err = dw.Write([]byte(HELLO_WORLD))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		sum := sha256.Sum256([]byte(HELLO_WORLD))
+		key := hex.EncodeToString(sum[:]) + ".txt"
+
+		err = dw.Close(key)
+
+		if err != nil {
+			t.Fatalf("Failed to close deferred writer, %v", err)
+		}
+
+		err = dw.Close("other.txt")
+
+		if err != nil {
+			t.Fatalf("Expected subsequent close to be a no-op, %v", err)
+		}
+
+		body, err := os.ReadFile(filepath.Join(root, key))
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", key, err)
+		}
+
+		if string(body) != HELLO_WORLD {
+			t.Fatalf("Unexpected body: %s", string(body))
+		}
+
+		_, err = os.Stat(filepath.Join(root, "other.txt"))
+
+		if !os.IsNotExist(err) {
+			t.Fatalf("Expected other.txt not to exist")
+		}
+	}
+}
+
+func TestNewDeferredBucket(t *testing.T) {
+
+	ctx := context.Background()
+
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return bucket, nil
+	}
+
+	dw, err := NewDeferred(ctx, "mem://", WithBucketOpener(opener), WithKeyNormalizer(LowercaseKeyNormalizer))
+
+	if err != nil {
+		t.Fatalf("Failed to create deferred writer, %v", err)
+	}
+
+	_, err = dw.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = dw.Close("")
+
+	if err == nil {
+		t.Fatalf("Expected empty key to fail")
+	}
+
+	err = dw.Close("A/AtomicWrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to close deferred writer, %v", err)
+	}
+
+	body, err := bucket.ReadAll(ctx, "a/atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to read a/atomicwrite.txt, %v", err)
+	}
+
+	if string(body) != HELLO_WORLD {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+
+	dw, err = NewDeferred(ctx, "mem://", WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to create deferred writer, %v", err)
+	}
+
+	err = dw.Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort deferred writer, %v", err)
+	}
+
+	err = dw.Close("b.txt")
+
+	if err != ErrAborted {
+		t.Fatalf("Expected ErrAborted, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrConflict is returned by the `DeleteIfMatch` method when the ETag of the key being deleted does not match.
+var ErrConflict = errors.New("Conflict")
+
+// DeleteIfMatch deletes 'key' from the bucket defined by 'bucket_uri' only if its ETag (as reported by the underlying bucket) is
+// 'etag', returning an error wrapping ErrConflict if it is not. If 'etag' is "*" 'key' only needs to exist. This is the delete
+// counterpart of the `WithIfMatch` option. The gocloud.dev/blob package does not expose native conditional deletes so the ETag is
+// checked immediately before 'key' is deleted; another writer may still replace 'key' in between (a time-of-check to time-of-use
+// race) in which case its data is deleted. For local filesystems pass the `WithFlock` option, and use it for all writers, to remove
+// that race. Only the `WithBucketOpener`, `WithFlock` and `WithKeyNormalizer` options are used.
+func DeleteIfMatch(ctx context.Context, bucket_uri string, key string, etag string, opts ...Option) error {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if etag == "" {
+		return fmt.Errorf("ETag is empty")
+	}
+
+	key = normalizeKey(o.key_normalizers, key)
+
+	if key == "" {
+		return fmt.Errorf("Key is empty")
+	}
+
+	bucket, err := o.openBucket(ctx, bucket_uri)
+
+	if err != nil {
+		return err
+	}
+
+	defer o.closeBucket(bucket)
+
+	unlock, err := o.lockKey(bucket_uri, key)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	attrs, err := bucket.Attributes(ctx, key)
+
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve attributes for %s, %w", key, err)
+	}
+
+	if etag != "*" && attrs.ETag != etag {
+		return fmt.Errorf("ETag for %s does not match, %w", key, ErrConflict)
+	}
+
+	err = bucket.Delete(ctx, key)
+
+	if err != nil {
+		return fmt.Errorf("Failed to delete %s, %w", key, err)
+	}
+
+	return nil
+}
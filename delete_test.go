@@ -0,0 +1,66 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeleteIfMatch(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	err := writeBytes(ctx, filepath.Join(root, "atomicwrite.txt"), []byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write atomicwrite.txt, %v", err)
+	}
+
+	bucket, err := blob.OpenBucket(ctx, bucket_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to open bucket, %v", err)
+	}
+
+	defer bucket.Close()
+
+	attrs, err := bucket.Attributes(ctx, "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to retrieve attributes, %v", err)
+	}
+
+	err = DeleteIfMatch(ctx, bucket_uri, "atomicwrite.txt", "\"nope\"")
+
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+
+	err = DeleteIfMatch(ctx, bucket_uri, "atomicwrite.txt", attrs.ETag)
+
+	if err != nil {
+		t.Fatalf("Failed to delete atomicwrite.txt, %v", err)
+	}
+
+	exists, err := bucket.Exists(ctx, "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to determine whether atomicwrite.txt exists, %v", err)
+	}
+
+	if exists {
+		t.Fatalf("Expected atomicwrite.txt to be deleted")
+	}
+
+	err = DeleteIfMatch(ctx, bucket_uri, "atomicwrite.txt", "*")
+
+	if gcerrors.Code(err) != gcerrors.NotFound {
+		t.Fatalf("Expected NotFound, got %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+// package deltalake implements methods for atomically writing Delta Lake transaction log entries to a gocloud.dev/blob bucket.
+package deltalake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sfomuseum/go-atomicwrite"
+	"path"
+	"regexp"
+	"strconv"
+)
+
+// LOG_PREFIX is the prefix, relative to the root of a Delta Lake table, of the table's transaction log.
+const LOG_PREFIX string = "_delta_log/"
+
+// MAX_COMMIT_ATTEMPTS is the maximum number of times `CommitDeltaTransaction` will try to commit a transaction before giving up.
+const MAX_COMMIT_ATTEMPTS int = 10
+
+// ErrTooManyConflicts is returned by `CommitDeltaTransaction` when a transaction could not be committed after MAX_COMMIT_ATTEMPTS
+// attempts because other writers kept committing the same version first.
+var ErrTooManyConflicts = errors.New("Too many conflicting commits")
+
+// re_log_entry matches the keys of transaction log entries, capturing the version.
+var re_log_entry = regexp.MustCompile(`^_delta_log/(\d{20})\.json$`)
+
+// type DeltaAction is a single action in a Delta Lake transaction log entry. Exactly one of its properties must be set. See
+// https://github.com/delta-io/delta/blob/master/PROTOCOL.md#actions for details.
+type DeltaAction struct {
+	// Add adds a data file to the table
+	Add *AddFile `json:"add,omitempty"`
+	// Remove removes a data file from the table
+	Remove *RemoveFile `json:"remove,omitempty"`
+	// MetaData changes the table's metadata
+	MetaData *Metadata `json:"metaData,omitempty"`
+	// Protocol changes the table's protocol versions
+	Protocol *Protocol `json:"protocol,omitempty"`
+	// Txn records the version of an application-specific transaction
+	Txn *Transaction `json:"txn,omitempty"`
+	// CommitInfo records arbitrary information about the commit
+	CommitInfo map[string]interface{} `json:"commitInfo,omitempty"`
+}
+
+// type AddFile is a Delta Lake "add" action.
+type AddFile struct {
+	// The path of the data file, relative to the root of the table (or an absolute URI)
+	Path string `json:"path"`
+	// The values of the table's partition columns for the data file
+	PartitionValues map[string]string `json:"partitionValues"`
+	// The size of the data file in bytes
+	Size int64 `json:"size"`
+	// The time the data file was created, in milliseconds since the Unix epoch
+	ModificationTime int64 `json:"modificationTime"`
+	// A boolean flag indicating whether the action changes the table's data, as opposed to rearranging it
+	DataChange bool `json:"dataChange"`
+	// Optional statistics about the data file, encoded as JSON
+	Stats string `json:"stats,omitempty"`
+	// Optional tags for the data file
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// type RemoveFile is a Delta Lake "remove" action.
+type RemoveFile struct {
+	// The path of the data file, relative to the root of the table (or an absolute URI)
+	Path string `json:"path"`
+	// The time the data file was removed, in milliseconds since the Unix epoch
+	DeletionTimestamp int64 `json:"deletionTimestamp,omitempty"`
+	// A boolean flag indicating whether the action changes the table's data, as opposed to rearranging it
+	DataChange bool `json:"dataChange"`
+	// A boolean flag indicating whether PartitionValues, Size and Tags are defined
+	ExtendedFileMetadata bool `json:"extendedFileMetadata,omitempty"`
+	// The values of the table's partition columns for the data file
+	PartitionValues map[string]string `json:"partitionValues,omitempty"`
+	// The size of the data file in bytes
+	Size int64 `json:"size,omitempty"`
+	// Optional tags for the data file
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// type Metadata is a Delta Lake "metaData" action.
+type Metadata struct {
+	// The unique identifier of the table
+	ID string `json:"id"`
+	// The optional name of the table
+	Name string `json:"name,omitempty"`
+	// The optional description of the table
+	Description string `json:"description,omitempty"`
+	// The format of the table's data files
+	Format Format `json:"format"`
+	// The table's schema, encoded as JSON
+	SchemaString string `json:"schemaString"`
+	// The names of the columns the table is partitioned by
+	PartitionColumns []string `json:"partitionColumns"`
+	// The time the table was created, in milliseconds since the Unix epoch
+	CreatedTime int64 `json:"createdTime,omitempty"`
+	// The table's configuration options
+	Configuration map[string]string `json:"configuration"`
+}
+
+// type Format describes the format of a Delta Lake table's data files.
+type Format struct {
+	// The name of the format, for example "parquet"
+	Provider string `json:"provider"`
+	// Format-specific options
+	Options map[string]string `json:"options"`
+}
+
+// type Protocol is a Delta Lake "protocol" action.
+type Protocol struct {
+	// The minimum version of the protocol that readers must implement to read the table
+	MinReaderVersion int `json:"minReaderVersion"`
+	// The minimum version of the protocol that writers must implement to write to the table
+	MinWriterVersion int `json:"minWriterVersion"`
+}
+
+// type Transaction is a Delta Lake "txn" action.
+type Transaction struct {
+	// The unique identifier of the application performing the transaction
+	AppID string `json:"appId"`
+	// The application-specific version of the transaction
+	Version int64 `json:"version"`
+	// The time the transaction was performed, in milliseconds since the Unix epoch
+	LastUpdated int64 `json:"lastUpdated,omitempty"`
+}
+
+// CommitDeltaTransaction atomically writes 'actions' as the next entry in the transaction log of the Delta Lake table whose root
+// is defined by 'table_uri' (for example "file:///data/table" or "s3://bucket?prefix=table/&region=us-east-1") and returns the
+// version that was committed. Writers are serialized using optimistic concurrency control: the latest version is read, the next
+// version is written only if it does not already exist and, if another writer committed that version first, the process is
+// repeated up to MAX_COMMIT_ATTEMPTS times. Conflicting commits are retried as-is; callers which need to check whether their
+// actions conflict with those of other writers (for example two writers removing the same file) must do so themselves. 'opts'
+// are applied to each write, as with the `atomicwrite.New` constructor, and to reading the latest version. As noted in the
+// documentation for `atomicwrite.WithIfNotExists` the existence check happens immediately before data is committed rather than
+// atomically; on a local filesystem pass the `atomicwrite.WithFlock` option to remove that race.
+func CommitDeltaTransaction(ctx context.Context, table_uri string, actions []DeltaAction, opts ...atomicwrite.Option) (int64, error) {
+
+	body, err := encodeActions(actions)
+
+	if err != nil {
+		return -1, err
+	}
+
+	write_opts := make([]atomicwrite.Option, len(opts))
+	copy(write_opts, opts)
+
+	write_opts = append(write_opts, atomicwrite.WithIfNotExists())
+
+	for i := 0; i < MAX_COMMIT_ATTEMPTS; i++ {
+
+		latest, err := LatestVersion(ctx, table_uri, opts...)
+
+		if err != nil {
+			return -1, err
+		}
+
+		version := latest + 1
+
+		err = writeLogEntry(ctx, table_uri, version, body, write_opts...)
+
+		if errors.Is(err, atomicwrite.ErrPreconditionFailed) {
+			continue
+		}
+
+		if err != nil {
+			return -1, err
+		}
+
+		return version, nil
+	}
+
+	return -1, fmt.Errorf("Failed to commit transaction after %d attempts, %w", MAX_COMMIT_ATTEMPTS, ErrTooManyConflicts)
+}
+
+// LatestVersion returns the latest version in the transaction log of the Delta Lake table whose root is defined by 'table_uri',
+// or -1 if the transaction log is empty. 'opts' are passed to `atomicwrite.ListCommitted` so that the transaction log is read using
+// the same `atomicwrite.WithBucketOpener` and `atomicwrite.WithBucketWrapper` options it is written with.
+func LatestVersion(ctx context.Context, table_uri string, opts ...atomicwrite.Option) (int64, error) {
+
+	objects, err := atomicwrite.ListCommitted(ctx, table_uri, LOG_PREFIX, "", opts...)
+
+	if err != nil {
+		return -1, fmt.Errorf("Failed to list transaction log, %w", err)
+	}
+
+	latest := int64(-1)
+
+	for _, obj := range objects {
+
+		m := re_log_entry.FindStringSubmatch(obj.Key)
+
+		if m == nil {
+			continue
+		}
+
+		v, err := strconv.ParseInt(m[1], 10, 64)
+
+		if err != nil {
+			return -1, fmt.Errorf("Failed to parse version for %s, %w", obj.Key, err)
+		}
+
+		if v > latest {
+			latest = v
+		}
+	}
+
+	return latest, nil
+}
+
+// LogKey returns the key, relative to the root of a Delta Lake table, of the transaction log entry for 'version'.
+func LogKey(version int64) string {
+	return path.Join(LOG_PREFIX, fmt.Sprintf("%020d.json", version))
+}
+
+// writeLogEntry atomically writes 'body' to the transaction log entry for 'version'.
+func writeLogEntry(ctx context.Context, table_uri string, version int64, body []byte, opts ...atomicwrite.Option) error {
+
+	wr, err := atomicwrite.NewDeferred(ctx, table_uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create writer, %w", err)
+	}
+
+	_, err = wr.Write(body)
+
+	if err != nil {
+		wr.Abort()
+		return fmt.Errorf("Failed to write transaction log entry, %w", err)
+	}
+
+	return wr.Close(LogKey(version))
+}
+
+// encodeActions encodes 'actions' as newline-delimited JSON, returning an error if any of them do not define exactly one action.
+func encodeActions(actions []DeltaAction) ([]byte, error) {
+
+	if len(actions) == 0 {
+		return nil, fmt.Errorf("No actions to commit")
+	}
+
+	var buf bytes.Buffer
+
+	for idx, a := range actions {
+
+		count := 0
+
+		for _, set := range []bool{a.Add != nil, a.Remove != nil, a.MetaData != nil, a.Protocol != nil, a.Txn != nil, a.CommitInfo != nil} {
+
+			if set {
+				count += 1
+			}
+		}
+
+		if count != 1 {
+			return nil, fmt.Errorf("Action at offset %d defines %d actions, expected exactly one", idx, count)
+		}
+
+		enc, err := json.Marshal(a)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to encode action at offset %d, %w", idx, err)
+		}
+
+		buf.Write(enc)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,199 @@
+package deltalake
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sfomuseum/go-atomicwrite"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitDeltaTransaction(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	table_uri := "file://" + filepath.ToSlash(root)
+
+	latest, err := LatestVersion(ctx, table_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to derive latest version, %v", err)
+	}
+
+	if latest != -1 {
+		t.Fatalf("Expected latest version of empty table to be -1, got %d", latest)
+	}
+
+	create := []DeltaAction{
+		{Protocol: &Protocol{MinReaderVersion: 1, MinWriterVersion: 2}},
+		{MetaData: &Metadata{ID: "test", Format: Format{Provider: "parquet"}, SchemaString: "{}"}},
+	}
+
+	version, err := CommitDeltaTransaction(ctx, table_uri, create)
+
+	if err != nil {
+		t.Fatalf("Failed to commit transaction, %v", err)
+	}
+
+	if version != 0 {
+		t.Fatalf("Expected version 0, got %d", version)
+	}
+
+	add := []DeltaAction{
+		{Add: &AddFile{Path: "part-00000.parquet", Size: 1234, DataChange: true}},
+	}
+
+	version, err = CommitDeltaTransaction(ctx, table_uri, add)
+
+	if err != nil {
+		t.Fatalf("Failed to commit transaction, %v", err)
+	}
+
+	if version != 1 {
+		t.Fatalf("Expected version 1, got %d", version)
+	}
+
+	path := filepath.Join(root, filepath.FromSlash(LogKey(0)))
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(v), "\n"), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 actions, got %d", len(lines))
+	}
+
+	var action map[string]interface{}
+
+	err = json.Unmarshal([]byte(lines[0]), &action)
+
+	if err != nil {
+		t.Fatalf("Failed to decode action, %v", err)
+	}
+
+	_, ok := action["protocol"]
+
+	if !ok || len(action) != 1 {
+		t.Fatalf("Unexpected action: %s", lines[0])
+	}
+}
+
+func TestCommitDeltaTransactionConflict(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	table_uri := "file://" + filepath.ToSlash(root)
+
+	actions := []DeltaAction{
+		{CommitInfo: map[string]interface{}{"operation": "WRITE"}},
+	}
+
+	// Simulate another writer committing version 0 while this writer is writing it
+
+	conflicted := false
+
+	conflict := func(asFunc func(interface{}) bool) error {
+
+		if conflicted {
+			return nil
+		}
+
+		conflicted = true
+
+		path := filepath.Join(root, filepath.FromSlash(LogKey(0)))
+
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(path, []byte("{\"commitInfo\":{}}\n"), 0644)
+	}
+
+	version, err := CommitDeltaTransaction(ctx, table_uri, actions, atomicwrite.WithBeforeWrite(conflict))
+
+	if err != nil {
+		t.Fatalf("Failed to commit transaction, %v", err)
+	}
+
+	if version != 1 {
+		t.Fatalf("Expected conflicting commit to be retried as version 1, got %d", version)
+	}
+}
+
+func TestCommitDeltaTransactionInvalid(t *testing.T) {
+
+	ctx := context.Background()
+
+	table_uri := "file://" + filepath.ToSlash(t.TempDir())
+
+	tests := [][]DeltaAction{
+		nil,
+		{{}},
+		{{Add: &AddFile{Path: "a.parquet"}, Remove: &RemoveFile{Path: "b.parquet"}}},
+	}
+
+	for _, actions := range tests {
+
+		_, err := CommitDeltaTransaction(ctx, table_uri, actions)
+
+		if err == nil {
+			t.Fatalf("Expected invalid actions %v to fail", actions)
+		}
+	}
+}
+
+func TestCommitDeltaTransactionWithBucketOpener(t *testing.T) {
+
+	ctx := context.Background()
+
+	mem := memblob.OpenBucket(nil)
+	defer mem.Close()
+
+	// Each mem:// URI opens a new, empty, bucket so the latest version is only found if it is
+	// read using the same opener that the transaction log is written with
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return mem, nil
+	}
+
+	actions := []DeltaAction{
+		{CommitInfo: map[string]interface{}{"operation": "WRITE"}},
+	}
+
+	for i := int64(0); i < 2; i++ {
+
+		version, err := CommitDeltaTransaction(ctx, "mem://", actions, atomicwrite.WithBucketOpener(opener))
+
+		if err != nil {
+			t.Fatalf("Failed to commit transaction, %v", err)
+		}
+
+		if version != i {
+			t.Fatalf("Expected version %d, got %d", i, version)
+		}
+	}
+
+	latest, err := LatestVersion(ctx, "mem://", atomicwrite.WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to derive latest version, %v", err)
+	}
+
+	if latest != 1 {
+		t.Fatalf("Expected latest version 1, got %d", latest)
+	}
+}
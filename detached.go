@@ -0,0 +1,40 @@
+package atomicwrite
+
+import (
+	"context"
+	"time"
+)
+
+// type detachedContext is a context.Context which carries the values of its parent context but is never cancelled and has no
+// deadline. It is used to remove temporary files, which must happen even if the context used to write data has been cancelled,
+// while preserving values like request IDs or tracing spans so that those operations can be correlated with the write.
+type detachedContext struct {
+	// The parent context whose values are carried
+	parent context.Context
+}
+
+// detachContext returns a context.Context which carries the values of 'ctx' but is never cancelled and has no deadline. This is
+// the equivalent of the `context.WithoutCancel` method introduced in Go 1.21.
+func detachContext(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+// Deadline returns false since a detachedContext has no deadline.
+func (c detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns nil since a detachedContext is never cancelled.
+func (c detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err returns nil since a detachedContext is never cancelled.
+func (c detachedContext) Err() error {
+	return nil
+}
+
+// Value returns the value associated with 'key' in the parent context.
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
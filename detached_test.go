@@ -0,0 +1,114 @@
+package atomicwrite
+
+import (
+	"context"
+	"gocloud.dev/blob"
+	"sync"
+	"testing"
+)
+
+type detachedTestKey string
+
+// recordingDeleteBucket is a mockBucket which records the contexts passed to its `Delete` method.
+type recordingDeleteBucket struct {
+	*mockBucket
+	mu       sync.Mutex
+	contexts []context.Context
+}
+
+func (b *recordingDeleteBucket) Delete(ctx context.Context, key string) error {
+
+	b.mu.Lock()
+	b.contexts = append(b.contexts, ctx)
+	b.mu.Unlock()
+
+	return b.mockBucket.Delete(ctx, key)
+}
+
+func TestDetachContext(t *testing.T) {
+
+	key := detachedTestKey("request-id")
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key, "1234"))
+
+	detached := detachContext(ctx)
+
+	cancel()
+
+	if detached.Err() != nil {
+		t.Fatalf("Expected detached context not to be cancelled, %v", detached.Err())
+	}
+
+	if detached.Done() != nil {
+		t.Fatalf("Expected detached context to have a nil Done channel")
+	}
+
+	_, ok := detached.Deadline()
+
+	if ok {
+		t.Fatalf("Expected detached context to have no deadline")
+	}
+
+	if detached.Value(key) != "1234" {
+		t.Fatalf("Unexpected value: %v", detached.Value(key))
+	}
+}
+
+func TestAtomicWriteCleanupContext(t *testing.T) {
+
+	key := detachedTestKey("request-id")
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key, "1234"))
+	defer cancel()
+
+	_, mock := newMockBucket()
+
+	rec := &recordingDeleteBucket{mockBucket: mock}
+
+	bucket := blob.NewBucket(rec)
+	defer bucket.Close()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return bucket, nil
+	}
+
+	wr, err := New(ctx, "mock://bucket/atomicwrite.txt", WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	// Cancel the context before aborting to ensure the temporary file is still removed
+
+	cancel()
+
+	err = wr.(*AtomicWriter).Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort writer, %v", err)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if len(rec.contexts) == 0 {
+		t.Fatalf("Expected temporary file to be deleted")
+	}
+
+	for _, c := range rec.contexts {
+
+		if c.Err() != nil {
+			t.Fatalf("Expected cleanup context not to be cancelled, %v", c.Err())
+		}
+
+		if c.Value(key) != "1234" {
+			t.Fatalf("Expected cleanup context to carry request ID, got %v", c.Value(key))
+		}
+	}
+}
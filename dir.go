@@ -0,0 +1,229 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// AtomicReplaceDir replaces the directory at 'dst_uri' with a copy of the directory tree at 'src_uri'. The tree is first
+// copied to a temporary directory alongside 'dst_uri' (so it is on the same device, which some filesystems require for
+// renames) and then swapped in to place. If 'dst_uri' does not exist the temporary directory is simply renamed. On Linux
+// an existing directory is exchanged with the temporary directory in a single `renameat2(RENAME_EXCHANGE)` system call so
+// readers will only ever see the old tree or the new one. On other platforms, or filesystems which do not support exchanging
+// paths, the existing directory is renamed out of the way first so there is a brief window in which 'dst_uri' does not exist.
+// Either way the old tree is removed afterwards. Regular files, directories and symbolic links are copied; all other file types
+// return an error. Both 'src_uri' and 'dst_uri' must be local filesystem paths or `file://` URIs; all other URIs return
+// ErrNotSupported.
+func AtomicReplaceDir(ctx context.Context, src_uri string, dst_uri string) error {
+
+	src_path, err := localPath(src_uri)
+
+	if err != nil {
+		return err
+	}
+
+	dst_path, err := localPath(dst_uri)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(src_path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to stat %s, %w", src_path, err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", src_path)
+	}
+
+	dst_exists := false
+
+	info, err = os.Lstat(dst_path)
+
+	switch {
+	case os.IsNotExist(err):
+		// pass
+	case err != nil:
+		return fmt.Errorf("Failed to stat %s, %w", dst_path, err)
+	case !info.IsDir():
+		return fmt.Errorf("%s is not a directory", dst_path)
+	default:
+		dst_exists = true
+	}
+
+	atomic_path, err := os.MkdirTemp(filepath.Dir(dst_path), filepath.Base(dst_path)+"-")
+
+	if err != nil {
+		return fmt.Errorf("Failed to create temporary directory for %s, %w", dst_path, err)
+	}
+
+	// On success atomic_path either no longer exists or contains the old tree
+
+	defer os.RemoveAll(atomic_path)
+
+	err = copyDir(ctx, src_path, atomic_path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to copy %s, %w", src_path, err)
+	}
+
+	if !dst_exists {
+
+		err = os.Rename(atomic_path, dst_path)
+
+		if err != nil {
+			return fmt.Errorf("Failed to rename %s to %s, %w", atomic_path, dst_path, err)
+		}
+
+		return nil
+	}
+
+	err = exchangePaths(atomic_path, dst_path)
+
+	switch {
+	case errors.Is(err, ErrNotSupported):
+		return swapDir(atomic_path, dst_path)
+	case err != nil:
+		return fmt.Errorf("Failed to exchange %s and %s, %w", atomic_path, dst_path, err)
+	default:
+		return nil
+	}
+}
+
+// swapDir replaces 'dst_path' with 'atomic_path' by renaming 'dst_path' out of the way, renaming 'atomic_path' to
+// 'dst_path' and then removing the old tree. If the second rename fails the old tree is restored.
+func swapDir(atomic_path string, dst_path string) error {
+
+	old_path := atomic_path + "-old"
+
+	err := os.Rename(dst_path, old_path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to rename %s to %s, %w", dst_path, old_path, err)
+	}
+
+	err = os.Rename(atomic_path, dst_path)
+
+	if err != nil {
+		os.Rename(old_path, dst_path)
+		return fmt.Errorf("Failed to rename %s to %s, %w", atomic_path, dst_path, err)
+	}
+
+	return os.RemoveAll(old_path)
+}
+
+// copyDir copies the directory tree at 'src_path' to the existing directory 'dst_path'.
+func copyDir(ctx context.Context, src_path string, dst_path string) error {
+
+	// Directory permissions are applied after the tree has been copied in case they are read-only
+
+	dir_paths := make([]string, 0)
+	dir_modes := make(map[string]fs.FileMode)
+
+	walk_func := func(path string, d fs.DirEntry, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// pass
+		}
+
+		rel_path, err := filepath.Rel(src_path, path)
+
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst_path, rel_path)
+
+		info, err := d.Info()
+
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+
+			dir_paths = append(dir_paths, target)
+			dir_modes[target] = info.Mode().Perm()
+
+			if rel_path == "." {
+				return nil
+			}
+
+			return os.Mkdir(target, 0700)
+
+		case d.Type()&fs.ModeSymlink != 0:
+
+			link, err := os.Readlink(path)
+
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(link, target)
+
+		case d.Type().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return fmt.Errorf("Unsupported file type for %s", path)
+		}
+	}
+
+	err := filepath.WalkDir(src_path, walk_func)
+
+	if err != nil {
+		return err
+	}
+
+	for i := len(dir_paths) - 1; i >= 0; i-- {
+
+		err := os.Chmod(dir_paths[i], dir_modes[dir_paths[i]])
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyFile copies the regular file at 'src_path' to 'dst_path'.
+func copyFile(src_path string, dst_path string, mode fs.FileMode) error {
+
+	r, err := os.Open(src_path)
+
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	wr, err := os.OpenFile(dst_path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(wr, r)
+
+	if err != nil {
+		wr.Close()
+		return err
+	}
+
+	return wr.Close()
+}
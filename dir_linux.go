@@ -0,0 +1,23 @@
+//go:build linux
+// +build linux
+
+package atomicwrite
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/sys/unix"
+)
+
+// exchangePaths atomically exchanges 'path_a' and 'path_b' using the `renameat2(RENAME_EXCHANGE)` system call.
+// ErrNotSupported is returned if the kernel or filesystem does not support exchanging paths.
+func exchangePaths(path_a string, path_b string) error {
+
+	err := unix.Renameat2(unix.AT_FDCWD, path_a, unix.AT_FDCWD, path_b, unix.RENAME_EXCHANGE)
+
+	if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOSYS) {
+		return fmt.Errorf("Failed to exchange paths, %v, %w", err, ErrNotSupported)
+	}
+
+	return err
+}
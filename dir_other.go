@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package atomicwrite
+
+// exchangePaths returns ErrNotSupported since atomically exchanging paths is only supported on Linux.
+func exchangePaths(path_a string, path_b string) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,146 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicReplaceDir(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	src_path := filepath.Join(root, "src")
+	dst_path := filepath.Join(root, "dst")
+
+	for _, p := range []string{src_path, filepath.Join(src_path, "sub"), dst_path} {
+
+		err := os.MkdirAll(p, 0755)
+
+		if err != nil {
+			t.Fatalf("Failed to create %s, %v", p, err)
+		}
+	}
+
+	files := map[string]string{
+		filepath.Join(src_path, "index.html"):    HELLO_WORLD,
+		filepath.Join(src_path, "sub", "a.txt"):  "a",
+		filepath.Join(dst_path, "obsolete.html"): "obsolete",
+	}
+
+	for p, body := range files {
+
+		err := os.WriteFile(p, []byte(body), 0644)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", p, err)
+		}
+	}
+
+	err := os.Symlink("index.html", filepath.Join(src_path, "latest.html"))
+
+	if err != nil {
+		t.Fatalf("Failed to create symbolic link, %v", err)
+	}
+
+	for _, dst := range []string{dst_path, filepath.Join(root, "new")} {
+
+		err := AtomicReplaceDir(ctx, src_path, dst)
+
+		if err != nil {
+			t.Fatalf("Failed to replace %s, %v", dst, err)
+		}
+
+		body, err := os.ReadFile(filepath.Join(dst, "latest.html"))
+
+		if err != nil {
+			t.Fatalf("Failed to read latest.html, %v", err)
+		}
+
+		if string(body) != HELLO_WORLD {
+			t.Fatalf("Unexpected body: %s", string(body))
+		}
+
+		_, err = os.Stat(filepath.Join(dst, "sub", "a.txt"))
+
+		if err != nil {
+			t.Fatalf("Failed to stat sub/a.txt, %v", err)
+		}
+
+		_, err = os.Stat(filepath.Join(dst, "obsolete.html"))
+
+		if !os.IsNotExist(err) {
+			t.Fatalf("Expected obsolete.html to be removed, %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries in %s, got %d", root, len(entries))
+	}
+}
+
+func TestSwapDir(t *testing.T) {
+
+	root := t.TempDir()
+
+	atomic_path := filepath.Join(root, "dst-1234")
+	dst_path := filepath.Join(root, "dst")
+
+	for _, p := range []string{atomic_path, dst_path} {
+
+		err := os.Mkdir(p, 0755)
+
+		if err != nil {
+			t.Fatalf("Failed to create %s, %v", p, err)
+		}
+
+		err = os.WriteFile(filepath.Join(p, filepath.Base(p)), nil, 0644)
+
+		if err != nil {
+			t.Fatalf("Failed to write file in %s, %v", p, err)
+		}
+	}
+
+	err := swapDir(atomic_path, dst_path)
+
+	if err != nil {
+		t.Fatalf("Failed to swap directories, %v", err)
+	}
+
+	_, err = os.Stat(filepath.Join(dst_path, "dst-1234"))
+
+	if err != nil {
+		t.Fatalf("Expected new tree in %s, %v", dst_path, err)
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry in %s, got %d", root, len(entries))
+	}
+}
+
+func TestAtomicReplaceDirNotSupported(t *testing.T) {
+
+	ctx := context.Background()
+
+	err := AtomicReplaceDir(ctx, t.TempDir(), "mem://dst")
+
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// type Encoder is an interface for streaming encoders. It is implemented by `json.Encoder`, `gob.Encoder` and `xml.Encoder` as
+// well as third-party encoders like `yaml.Encoder` (gopkg.in/yaml.v3), `toml.Encoder` (github.com/BurntSushi/toml) and
+// `msgpack.Encoder` (github.com/vmihailov/msgpack/v5).
+type Encoder interface {
+	// Encode writes the encoding of 'v'.
+	Encode(v interface{}) error
+}
+
+// type EncoderFunc is a function which returns an Encoder writing to 'wr'. For example `func(wr io.Writer) Encoder {
+// return yaml.NewEncoder(wr) }`. Encoder-specific settings, like indentation, are applied by the function.
+type EncoderFunc func(wr io.Writer) Encoder
+
+// WriteEncoded encodes 'v' using the Encoder returned by 'new_encoder' and atomically writes the result to 'uri'. If the Encoder
+// also implements the io.Closer interface, as `yaml.Encoder` does, its `Close` method is invoked (to flush any buffered data)
+// before the data is committed. If encoding fails the writer is aborted and nothing is written to 'uri'.
+func WriteEncoded(ctx context.Context, uri string, v interface{}, new_encoder EncoderFunc, opts ...Option) error {
+
+	wr, err := New(ctx, uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create atomic writer, %w", err)
+	}
+
+	enc := new_encoder(wr)
+
+	err = enc.Encode(v)
+
+	if err == nil {
+
+		if cl, ok := enc.(io.Closer); ok {
+			err = cl.Close()
+		}
+	}
+
+	if err != nil {
+		wr.(*AtomicWriter).Abort()
+		return fmt.Errorf("Failed to encode value, %w", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close atomic writer, %w", err)
+	}
+
+	return nil
+}
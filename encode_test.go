@@ -0,0 +1,99 @@
+package atomicwrite
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// type bufferedEncoder is an Encoder which buffers its output until it is closed, like `yaml.Encoder`.
+type bufferedEncoder struct {
+	wr *bufio.Writer
+}
+
+func (e *bufferedEncoder) Encode(v interface{}) error {
+
+	if v == nil {
+		return errors.New("Can not encode nil")
+	}
+
+	_, err := fmt.Fprintf(e.wr, "%v\n", v)
+	return err
+}
+
+func (e *bufferedEncoder) Close() error {
+	return e.wr.Flush()
+}
+
+func TestWriteEncoded(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	json_path := filepath.Join(root, "atomicwrite.json")
+
+	new_json := func(wr io.Writer) Encoder {
+		enc := json.NewEncoder(wr)
+		enc.SetIndent("", "  ")
+		return enc
+	}
+
+	err := WriteEncoded(ctx, json_path, map[string]string{"hello": "world"}, new_json)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", json_path, err)
+	}
+
+	v, err := os.ReadFile(json_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", json_path, err)
+	}
+
+	if string(v) != "{\n  \"hello\": \"world\"\n}\n" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	new_buffered := func(wr io.Writer) Encoder {
+		return &bufferedEncoder{bufio.NewWriter(wr)}
+	}
+
+	err = WriteEncoded(ctx, path, HELLO_WORLD, new_buffered)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	v, err = os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != HELLO_WORLD+"\n" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+
+	path = filepath.Join(root, "nil.txt")
+
+	err = WriteEncoded(ctx, path, nil, new_buffered)
+
+	if err == nil {
+		t.Fatalf("Expected encoding nil to fail")
+	}
+
+	_, err = os.Stat(path)
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected %s not to exist, %v", path, err)
+	}
+}
@@ -0,0 +1,348 @@
+package atomicwrite
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// AES_GCM_CHUNK_SIZE is the size, in bytes, of each chunk of plaintext encrypted by the `WithAESGCMEncryption` option.
+const AES_GCM_CHUNK_SIZE int = 64 * 1024
+
+// AES_GCM_KEY_SIZE is the size, in bytes, of the keys used by the `WithAESGCMEncryption` option (AES-256).
+const AES_GCM_KEY_SIZE int = 32
+
+// ErrDecrypt is returned when encrypted data can not be decrypted, for example because the wrong key was used or the data has
+// been modified or truncated.
+var ErrDecrypt = errors.New("Failed to decrypt data")
+
+// WithAESGCMEncryption returns an Option which encrypts data, using AES-256-GCM with 'key' (which must be 32 bytes), before it is
+// written to the intermediate temporary file. The final path contains the same ciphertext. Since GCM can not encrypt a stream
+// of unknown length data is encrypted in chunks of AES_GCM_CHUNK_SIZE bytes. A random nonce is generated for each writer and
+// prepended to the ciphertext; the nonce for each chunk is derived from it using the chunk's index and the last chunk is marked
+// as such so that truncated data can be detected. Use the `NewAESGCMDecryptReader` method to decrypt data. This option can not
+// be combined with the `Snapshot` or `Flush` methods (which return ErrNotSupported) or the `WithBackgroundFlush` option. Data
+// streamed by the `WithTeeToHTTP` option is not encrypted.
+func WithAESGCMEncryption(key []byte) Option {
+
+	return func(o *options) {
+		o.encryption_key = key
+	}
+}
+
+// type EncryptWriterFunc is a function which returns an io.WriteCloser instance that encrypts data written to it and writes
+// the ciphertext to 'wr'. Closing the returned writer must flush any remaining ciphertext but must not close 'wr'. This is the
+// same signature as the `age.Encrypt` function (with recipients bound by a closure).
+type EncryptWriterFunc func(wr io.Writer) (io.WriteCloser, error)
+
+// WithEncryptWriter returns an Option which encrypts data, using the writer returned by 'fn', before it is written to the
+// intermediate temporary file. The final path contains the same ciphertext. This allows third-party encryption formats, like
+// age, to be used without this package depending on them. As with the `WithAESGCMEncryption` option encrypted writers can not
+// be combined with the `Snapshot` or `Flush` methods or the `WithBackgroundFlush` option. If both options are specified the
+// `WithAESGCMEncryption` option is used.
+func WithEncryptWriter(fn EncryptWriterFunc) Option {
+
+	return func(o *options) {
+		o.encrypt_writer = fn
+	}
+}
+
+// type encryptWriter implements the io.WriteCloser interface writing data to an encrypting writer which writes ciphertext to
+// an underlying writer.
+type encryptWriter struct {
+	io.WriteCloser
+	// The underlying writer that ciphertext is written to
+	wr io.WriteCloser
+}
+
+// newEncryptWriter returns a new encryptWriter instance which encrypts data, using the writer returned by 'fn', and writes it to 'wr'.
+func newEncryptWriter(wr io.WriteCloser, fn EncryptWriterFunc) (*encryptWriter, error) {
+
+	enc_wr, err := fn(wr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ew := &encryptWriter{
+		WriteCloser: enc_wr,
+		wr:          wr,
+	}
+
+	return ew, nil
+}
+
+// Close closes the encrypting writer and then the underlying writer.
+func (ew *encryptWriter) Close() error {
+
+	err := ew.WriteCloser.Close()
+
+	if err != nil {
+		ew.wr.Close()
+		return fmt.Errorf("Failed to close encrypted writer, %w", err)
+	}
+
+	return ew.wr.Close()
+}
+
+// newAESGCM returns a new AES-GCM `cipher.AEAD` instance for 'key'.
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+
+	if len(key) != AES_GCM_KEY_SIZE {
+		return nil, fmt.Errorf("Invalid key length, expected %d bytes but got %d", AES_GCM_KEY_SIZE, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create cipher, %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create GCM cipher, %w", err)
+	}
+
+	return aead, nil
+}
+
+// chunkNonce returns the nonce for chunk 'counter' derived from 'nonce'.
+func chunkNonce(nonce []byte, counter uint64) []byte {
+
+	chunk_nonce := make([]byte, len(nonce))
+	copy(chunk_nonce, nonce)
+
+	offset := len(chunk_nonce) - 8
+	binary.BigEndian.PutUint64(chunk_nonce[offset:], binary.BigEndian.Uint64(chunk_nonce[offset:])^counter)
+
+	return chunk_nonce
+}
+
+// chunkAdditionalData returns the additional authenticated data for a chunk indicating whether it is the last chunk.
+func chunkAdditionalData(last bool) []byte {
+
+	if last {
+		return []byte{1}
+	}
+
+	return []byte{0}
+}
+
+// type aesGCMWriter implements the io.WriteCloser interface encrypting data, in chunks, before writing it to an underlying writer.
+type aesGCMWriter struct {
+	// The underlying writer that ciphertext is written to
+	wr io.WriteCloser
+	// The AES-GCM cipher used to encrypt data
+	aead cipher.AEAD
+	// The random nonce that the nonce for each chunk is derived from
+	nonce []byte
+	// The index of the next chunk
+	counter uint64
+	// The plaintext which has not been encrypted yet
+	buf []byte
+}
+
+// newAESGCMWriter returns a new aesGCMWriter instance which encrypts data with 'key' and writes it to 'wr'. The random nonce is
+// written to 'wr' immediately.
+func newAESGCMWriter(wr io.WriteCloser, key []byte) (*aesGCMWriter, error) {
+
+	aead, err := newAESGCM(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	_, err = io.ReadFull(rand.Reader, nonce)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to generate nonce, %w", err)
+	}
+
+	_, err = wr.Write(nonce)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write nonce, %w", err)
+	}
+
+	ew := &aesGCMWriter{
+		wr:    wr,
+		aead:  aead,
+		nonce: nonce,
+		buf:   make([]byte, 0, AES_GCM_CHUNK_SIZE),
+	}
+
+	return ew, nil
+}
+
+// Write buffers 'b' encrypting and writing each complete chunk. A complete chunk is only encrypted once more data has been
+// written, since until then it might be the last chunk.
+func (ew *aesGCMWriter) Write(b []byte) (int, error) {
+
+	n := 0
+
+	for len(b) > 0 {
+
+		if len(ew.buf) == AES_GCM_CHUNK_SIZE {
+
+			err := ew.seal(false)
+
+			if err != nil {
+				return n, err
+			}
+		}
+
+		sz := AES_GCM_CHUNK_SIZE - len(ew.buf)
+
+		if sz > len(b) {
+			sz = len(b)
+		}
+
+		ew.buf = append(ew.buf, b[:sz]...)
+		b = b[sz:]
+		n += sz
+	}
+
+	return n, nil
+}
+
+// Close encrypts and writes the last (possibly empty) chunk and closes the underlying writer.
+func (ew *aesGCMWriter) Close() error {
+
+	err := ew.seal(true)
+
+	if err != nil {
+		ew.wr.Close()
+		return err
+	}
+
+	return ew.wr.Close()
+}
+
+// seal encrypts the buffered plaintext and writes it to the underlying writer.
+func (ew *aesGCMWriter) seal(last bool) error {
+
+	ciphertext := ew.aead.Seal(nil, chunkNonce(ew.nonce, ew.counter), ew.buf, chunkAdditionalData(last))
+
+	_, err := ew.wr.Write(ciphertext)
+
+	if err != nil {
+		return fmt.Errorf("Failed to write encrypted chunk %d, %w", ew.counter, err)
+	}
+
+	ew.counter += 1
+	ew.buf = ew.buf[:0]
+
+	return nil
+}
+
+// type aesGCMReader implements the io.Reader interface decrypting data written by an aesGCMWriter instance.
+type aesGCMReader struct {
+	// The underlying reader that ciphertext is read from
+	r *bufio.Reader
+	// The AES-GCM cipher used to decrypt data
+	aead cipher.AEAD
+	// The random nonce that the nonce for each chunk is derived from
+	nonce []byte
+	// The index of the next chunk
+	counter uint64
+	// The decrypted plaintext which has not been read yet
+	buf []byte
+	// A boolean flag indicating whether the last chunk has been decrypted
+	done bool
+}
+
+// NewAESGCMDecryptReader returns an io.Reader which decrypts data read from 'r' that was encrypted by the `WithAESGCMEncryption`
+// option using 'key'. Reads return an error wrapping ErrDecrypt if the data can not be decrypted, including if it has been
+// truncated.
+func NewAESGCMDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+
+	aead, err := newAESGCM(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	_, err = io.ReadFull(r, nonce)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read nonce, %w", err)
+	}
+
+	dr := &aesGCMReader{
+		r:     bufio.NewReader(r),
+		aead:  aead,
+		nonce: nonce,
+	}
+
+	return dr, nil
+}
+
+// Read reads decrypted data in to 'b'.
+func (dr *aesGCMReader) Read(b []byte) (int, error) {
+
+	for len(dr.buf) == 0 {
+
+		if dr.done {
+			return 0, io.EOF
+		}
+
+		err := dr.open()
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, dr.buf)
+	dr.buf = dr.buf[n:]
+
+	return n, nil
+}
+
+// open reads and decrypts the next chunk. A chunk is the last chunk if it is followed by the end of the data.
+func (dr *aesGCMReader) open() error {
+
+	ciphertext := make([]byte, AES_GCM_CHUNK_SIZE+dr.aead.Overhead())
+
+	n, err := io.ReadFull(dr.r, ciphertext)
+
+	last := false
+
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return fmt.Errorf("Failed to read encrypted chunk %d, %w", dr.counter, err)
+	default:
+
+		_, err := dr.r.Peek(1)
+
+		if err == io.EOF {
+			last = true
+		} else if err != nil {
+			return fmt.Errorf("Failed to read encrypted chunk %d, %w", dr.counter+1, err)
+		}
+	}
+
+	plaintext, err := dr.aead.Open(nil, chunkNonce(dr.nonce, dr.counter), ciphertext[:n], chunkAdditionalData(last))
+
+	if err != nil {
+		return fmt.Errorf("Failed to decrypt chunk %d, %w", dr.counter, ErrDecrypt)
+	}
+
+	dr.counter += 1
+	dr.buf = plaintext
+	dr.done = last
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package atomicwrite
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithAESGCMEncryption(t *testing.T) {
+
+	ctx := context.Background()
+
+	key := make([]byte, AES_GCM_KEY_SIZE)
+
+	_, err := rand.Read(key)
+
+	if err != nil {
+		t.Fatalf("Failed to generate key, %v", err)
+	}
+
+	sizes := []int{
+		0,
+		len(HELLO_WORLD),
+		AES_GCM_CHUNK_SIZE,
+		AES_GCM_CHUNK_SIZE*3 + 1,
+	}
+
+	root := t.TempDir()
+
+	for _, sz := range sizes {
+
+		plaintext := make([]byte, sz)
+
+		_, err := rand.Read(plaintext)
+
+		if err != nil {
+			t.Fatalf("Failed to generate plaintext, %v", err)
+		}
+
+		path := filepath.Join(root, "atomicwrite.enc")
+
+		err = writeBytes(ctx, path, plaintext, WithAESGCMEncryption(key))
+
+		if err != nil {
+			t.Fatalf("Failed to write %d bytes, %v", sz, err)
+		}
+
+		ciphertext, err := os.ReadFile(path)
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", path, err)
+		}
+
+		if sz > 0 && bytes.Contains(ciphertext, plaintext) {
+			t.Fatalf("Expected %d bytes to be encrypted", sz)
+		}
+
+		r, err := NewAESGCMDecryptReader(bytes.NewReader(ciphertext), key)
+
+		if err != nil {
+			t.Fatalf("Failed to create decrypt reader for %d bytes, %v", sz, err)
+		}
+
+		body, err := io.ReadAll(r)
+
+		if err != nil {
+			t.Fatalf("Failed to decrypt %d bytes, %v", sz, err)
+		}
+
+		if !bytes.Equal(body, plaintext) {
+			t.Fatalf("Unexpected plaintext for %d bytes", sz)
+		}
+
+		// Truncated data, including data truncated at a chunk boundary, must not decrypt
+
+		truncated := [][]byte{
+			ciphertext[:len(ciphertext)-1],
+		}
+
+		if sz > AES_GCM_CHUNK_SIZE {
+			truncated = append(truncated, ciphertext[:12+AES_GCM_CHUNK_SIZE+16])
+		}
+
+		for _, c := range truncated {
+
+			r, err := NewAESGCMDecryptReader(bytes.NewReader(c), key)
+
+			if err != nil {
+				t.Fatalf("Failed to create decrypt reader for truncated data, %v", err)
+			}
+
+			_, err = io.ReadAll(r)
+
+			if !errors.Is(err, ErrDecrypt) {
+				t.Fatalf("Expected ErrDecrypt for truncated data, got %v", err)
+			}
+		}
+
+		other := make([]byte, AES_GCM_KEY_SIZE)
+
+		r, err = NewAESGCMDecryptReader(bytes.NewReader(ciphertext), other)
+
+		if err != nil {
+			t.Fatalf("Failed to create decrypt reader, %v", err)
+		}
+
+		_, err = io.ReadAll(r)
+
+		if !errors.Is(err, ErrDecrypt) {
+			t.Fatalf("Expected ErrDecrypt for wrong key, got %v", err)
+		}
+	}
+}
+
+func TestWithAESGCMEncryptionErrors(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.enc")
+
+	_, err := New(ctx, path, WithAESGCMEncryption([]byte("short")))
+
+	if err == nil {
+		t.Fatalf("Expected invalid key to fail")
+	}
+
+	key := make([]byte, AES_GCM_KEY_SIZE)
+
+	_, err = New(ctx, path, WithAESGCMEncryption(key), WithBackgroundFlush(time.Second))
+
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported for background flush, got %v", err)
+	}
+
+	wr, err := New(ctx, path, WithAESGCMEncryption(key))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.(*AtomicWriter).Snapshot()
+
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported for snapshot, got %v", err)
+	}
+
+	err = wr.(*AtomicWriter).Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort writer, %v", err)
+	}
+}
+
+func TestWithEncryptWriter(t *testing.T) {
+
+	ctx := context.Background()
+
+	// gzip is not encryption but it has the same shape as an encrypting writer,
+	// for example age.Encrypt, and can be reversed without any extra dependencies
+
+	fn := func(wr io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(wr), nil
+	}
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.gz")
+
+	err := writeBytes(ctx, path, []byte(HELLO_WORLD), WithEncryptWriter(fn))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	fh, err := os.Open(path)
+
+	if err != nil {
+		t.Fatalf("Failed to open %s, %v", path, err)
+	}
+
+	defer fh.Close()
+
+	r, err := gzip.NewReader(fh)
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	body, err := io.ReadAll(r)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(body) != HELLO_WORLD {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+
+	failing := func(wr io.Writer) (io.WriteCloser, error) {
+		return nil, errors.New("Failed")
+	}
+
+	_, err = New(ctx, path, WithEncryptWriter(failing))
+
+	if err == nil {
+		t.Fatalf("Expected encrypt writer to fail")
+	}
+}
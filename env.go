@@ -0,0 +1,25 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrMissingEnv is returned when the environment variable passed to the `NewFromEnv` constructor is unset or empty.
+var ErrMissingEnv = errors.New("Missing environment variable")
+
+// NewFromEnv returns a new AtomicWriter instance (as an io.WriteCloser) for the URI defined by the environment variable
+// named 'env_key'. If the environment variable is unset or empty an error wrapping ErrMissingEnv is returned.
+func NewFromEnv(ctx context.Context, env_key string, opts ...Option) (io.WriteCloser, error) {
+
+	uri := os.Getenv(env_key)
+
+	if uri == "" {
+		return nil, fmt.Errorf("%s is unset or empty, %w", env_key, ErrMissingEnv)
+	}
+
+	return New(ctx, uri, opts...)
+}
@@ -0,0 +1,65 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewFromEnv(t *testing.T) {
+
+	ctx := context.Background()
+
+	env_key := "ATOMICWRITE_TEST_URI"
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	t.Setenv(env_key, path)
+
+	wr, err := NewFromEnv(ctx, env_key)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	body, err := readBytes(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(body) != HELLO_WORLD {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+}
+
+func TestNewFromEnvMissing(t *testing.T) {
+
+	ctx := context.Background()
+
+	env_key := "ATOMICWRITE_TEST_URI"
+	t.Setenv(env_key, "")
+
+	_, err := NewFromEnv(ctx, env_key)
+
+	if !errors.Is(err, ErrMissingEnv) {
+		t.Fatalf("Expected ErrMissingEnv, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), env_key) {
+		t.Fatalf("Expected error to name %s, got %v", env_key, err)
+	}
+}
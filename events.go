@@ -0,0 +1,136 @@
+package atomicwrite
+
+import (
+	"time"
+)
+
+// type WriterEventKind defines the different kinds of lifecycle events emitted by AtomicWriter instances.
+type WriterEventKind int
+
+const (
+	// EventOpened is emitted when the intermediate temporary file has been opened for writing.
+	EventOpened WriterEventKind = iota
+	// EventWritten is emitted after each successful call to the `Write` method.
+	EventWritten
+	// EventCommitted is emitted when data has been successfully copied to the final path.
+	EventCommitted
+	// EventAborted is emitted when the `Abort` or `DiscardAsync` methods are invoked.
+	EventAborted
+	// EventStagingDeleted is emitted when the intermediate temporary file has been removed.
+	EventStagingDeleted
+	// EventError is emitted when an error is encountered committing or discarding data.
+	EventError
+	// EventCollision is emitted each time a candidate path for the intermediate temporary file already exists and a new path is tried.
+	EventCollision
+)
+
+// String returns the name of the event kind.
+func (k WriterEventKind) String() string {
+
+	switch k {
+	case EventOpened:
+		return "opened"
+	case EventWritten:
+		return "written"
+	case EventCommitted:
+		return "committed"
+	case EventAborted:
+		return "aborted"
+	case EventStagingDeleted:
+		return "staging_deleted"
+	case EventError:
+		return "error"
+	case EventCollision:
+		return "collision"
+	default:
+		return "unknown"
+	}
+}
+
+// type WriterEvent describes a lifecycle event emitted by an AtomicWriter instance.
+type WriterEvent struct {
+	// The kind of event
+	Kind WriterEventKind
+	// A random identifier for the writer which emitted the event. Unlike AtomicPath it does not change when the `Snapshot` or
+	// `Flush` methods are invoked so it can be used to correlate the events emitted by a single writer
+	WriterID string
+	// The final path (relative to bucket) that data will be written to
+	FinalPath string
+	// The temporary path (relative to bucket) that data is written to before being committed
+	AtomicPath string
+	// The number of bytes written, for EventWritten events
+	Bytes int
+	// The error encountered, for EventError events
+	Error error
+	// The number of the attempt (starting at 1) to derive a temporary path, for EventCollision events
+	Attempt int
+	// The time the event was emitted
+	Time time.Time
+}
+
+// type WriterEventListener is an interface for receiving lifecycle events emitted by AtomicWriter instances.
+// Events are delivered synchronously, from the goroutine performing the operation that triggered them, so
+// implementations should return quickly.
+type WriterEventListener interface {
+	// OnEvent is invoked for each lifecycle event emitted by an AtomicWriter instance.
+	OnEvent(WriterEvent)
+}
+
+// WithEventListener returns an Option which registers 'l' to receive the lifecycle events emitted by an
+// AtomicWriter instance. This option may be specified multiple times to register multiple listeners.
+func WithEventListener(l WriterEventListener) Option {
+
+	return func(o *options) {
+		o.listeners = append(o.listeners, l)
+	}
+}
+
+// emit dispatches a WriterEvent of kind 'kind' to all the listeners registered with 'aw'. The final and temporary paths are read
+// while holding aw.mu, which must not already be held by the caller, and listeners are invoked after it has been released.
+func (aw *AtomicWriter) emit(kind WriterEventKind, bytes int, err error) {
+
+	if len(aw.listeners) == 0 {
+		return
+	}
+
+	aw.mu.Lock()
+	e := aw.newEvent(kind, bytes, err)
+	aw.mu.Unlock()
+
+	emitEvent(aw.listeners, e)
+}
+
+// emitLocked dispatches a WriterEvent of kind 'kind' to all the listeners registered with 'aw'. The caller must hold aw.mu.
+func (aw *AtomicWriter) emitLocked(kind WriterEventKind, bytes int, err error) {
+
+	if len(aw.listeners) == 0 {
+		return
+	}
+
+	emitEvent(aw.listeners, aw.newEvent(kind, bytes, err))
+}
+
+// newEvent returns a WriterEvent of kind 'kind' for the current final and temporary paths. The caller must hold aw.mu.
+func (aw *AtomicWriter) newEvent(kind WriterEventKind, bytes int, err error) WriterEvent {
+
+	e := WriterEvent{
+		Kind:       kind,
+		WriterID:   aw.id,
+		FinalPath:  aw.final_path,
+		AtomicPath: aw.atomic_path,
+		Bytes:      bytes,
+		Error:      err,
+	}
+
+	return e
+}
+
+// emitEvent assigns the current time to 'e' and dispatches it to 'listeners'.
+func emitEvent(listeners []WriterEventListener, e WriterEvent) {
+
+	e.Time = time.Now()
+
+	for _, l := range listeners {
+		l.OnEvent(e)
+	}
+}
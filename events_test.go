@@ -0,0 +1,227 @@
+package atomicwrite
+
+import (
+	"bytes"
+	"context"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/driver"
+	"log"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type testEventListener struct {
+	kinds      []WriterEventKind
+	writer_ids []string
+}
+
+func (l *testEventListener) OnEvent(e WriterEvent) {
+	l.kinds = append(l.kinds, e.Kind)
+	l.writer_ids = append(l.writer_ids, e.WriterID)
+}
+
+// collisionBucket is a mockBucket which reports that the first 'collisions' keys it is asked about already exist.
+type collisionBucket struct {
+	*mockBucket
+	collisions int
+}
+
+func (b *collisionBucket) Attributes(ctx context.Context, key string) (*driver.Attributes, error) {
+
+	if b.collisions > 0 {
+		b.collisions -= 1
+		return &driver.Attributes{}, nil
+	}
+
+	return b.mockBucket.Attributes(ctx, key)
+}
+
+func TestAtomicWriteEvents(t *testing.T) {
+
+	ctx := context.Background()
+
+	tests := map[string][]WriterEventKind{
+		"close": {EventOpened, EventWritten, EventCommitted, EventStagingDeleted},
+		"abort": {EventOpened, EventWritten, EventAborted, EventStagingDeleted},
+	}
+
+	for label, expected := range tests {
+
+		l := &testEventListener{}
+
+		wr, err := New(ctx, "mem://atomicwrite.txt", WithEventListener(l))
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		_, err = wr.Write([]byte(HELLO_WORLD))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		switch label {
+		case "abort":
+			err = wr.(*AtomicWriter).Abort()
+		default:
+			err = wr.Close()
+		}
+
+		if err != nil {
+			t.Fatalf("Failed to %s writer, %v", label, err)
+		}
+
+		if len(l.kinds) != len(expected) {
+			t.Fatalf("Unexpected number of events for %s: %v", label, l.kinds)
+		}
+
+		for i, k := range expected {
+
+			if l.kinds[i] != k {
+				t.Fatalf("Unexpected event at position %d for %s, expected %s but got %s", i, label, k, l.kinds[i])
+			}
+		}
+	}
+}
+
+func TestAtomicWriteEventsWriterID(t *testing.T) {
+
+	ctx := context.Background()
+
+	l := &testEventListener{}
+
+	ids := make(map[string]bool)
+
+	for i := 0; i < 2; i++ {
+
+		wr, err := New(ctx, "mem://atomicwrite.txt", WithEventListener(l))
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		aw := wr.(*AtomicWriter)
+
+		_, err = aw.Write([]byte(HELLO_WORLD))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		// The temporary path changes when the writer is flushed but its ID does not
+
+		err = aw.Flush()
+
+		if err != nil {
+			t.Fatalf("Failed to flush writer, %v", err)
+		}
+
+		err = aw.Close()
+
+		if err != nil {
+			t.Fatalf("Failed to close writer, %v", err)
+		}
+
+		for _, id := range l.writer_ids {
+
+			if id == "" {
+				t.Fatalf("Expected event to have a writer ID")
+			}
+
+			ids[id] = true
+		}
+
+		l.writer_ids = nil
+	}
+
+	if len(ids) != 2 {
+		t.Fatalf("Expected 2 distinct writer IDs, got %d", len(ids))
+	}
+}
+
+func TestAtomicWriteCollisionEvents(t *testing.T) {
+
+	ctx := context.Background()
+
+	_, mock := newMockBucket()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return blob.NewBucket(&collisionBucket{mockBucket: mock, collisions: 2}), nil
+	}
+
+	l := &testEventListener{}
+
+	var buf bytes.Buffer
+	logger := log.New(&buf, "", 0)
+
+	wr, err := New(ctx, "mock://atomicwrite.txt", WithBucketOpener(opener), WithEventListener(l), WithLogger(logger))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	expected := []WriterEventKind{EventCollision, EventCollision, EventOpened}
+
+	for i, k := range expected {
+
+		if l.kinds[i] != k {
+			t.Fatalf("Unexpected event at position %d, expected %s but got %s", i, k, l.kinds[i])
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log messages, got %d: %s", len(lines), buf.String())
+	}
+}
+
+func TestAtomicWriteEventsSetFinalPathConcurrent(t *testing.T) {
+
+	ctx := context.Background()
+
+	// Run with -race; events emitted by Abort read the final path which SetFinalPath updates
+
+	for i := 0; i < 50; i++ {
+
+		l := &testEventListener{}
+
+		aw, err := newAtomicWriter(ctx, "mem://atomicwrite.txt", WithEventListener(l))
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		started := make(chan struct{})
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			close(started)
+			aw.SetFinalPath("other.txt")
+		}()
+
+		<-started
+		runtime.Gosched()
+
+		err = aw.Abort()
+
+		if err != nil {
+			t.Fatalf("Failed to abort writer, %v", err)
+		}
+
+		<-done
+
+		if len(l.kinds) < 2 || l.kinds[1] != EventAborted {
+			t.Fatalf("Unexpected events: %v", l.kinds)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+	"io"
+	"time"
+)
+
+// EXPIRES_AT_METADATA_KEY is the metadata key, assigned by the `WithExpiresAt` option, which records the time (formatted as
+// RFC 3339) after which an object may be deleted.
+const EXPIRES_AT_METADATA_KEY string = "expires-at"
+
+// WithExpiresAt returns an Option which records 't' as the time after which the final path may be deleted, in the metadata for
+// the final path (using the EXPIRES_AT_METADATA_KEY key). Neither S3 nor GCS support setting an expiry time when an object is
+// written ("x-amz-expiration" is a response header derived from bucket lifecycle rules) so expired objects are removed by the
+// `DeleteExpired` method rather than by the backend itself. Later calls to this option replace earlier ones.
+func WithExpiresAt(t time.Time) Option {
+
+	return func(o *options) {
+		o.expires_at = t
+	}
+}
+
+// DeleteExpired deletes the objects in the bucket defined by 'bucket_uri' whose keys start with 'prefix' and whose expiry time,
+// recorded by the `WithExpiresAt` option, is before the current time. It returns the keys of the objects that were deleted.
+// Objects without a valid expiry time are ignored. The bucket is opened using the function defined by the `WithBucketOpener` option,
+// if present; all other options are ignored.
+func DeleteExpired(ctx context.Context, bucket_uri string, prefix string, opts ...Option) ([]string, error) {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	bucket, err := o.openBucket(ctx, bucket_uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer o.closeBucket(bucket)
+
+	now := time.Now()
+
+	iter := bucket.List(&blob.ListOptions{Prefix: prefix})
+
+	deleted := make([]string, 0)
+
+	for {
+
+		obj, err := iter.Next(ctx)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return deleted, fmt.Errorf("Failed to list bucket %s, %w", bucket_uri, err)
+		}
+
+		if obj.IsDir {
+			continue
+		}
+
+		attrs, err := bucket.Attributes(ctx, obj.Key)
+
+		if err != nil {
+
+			if gcerrors.Code(err) == gcerrors.NotFound {
+				continue
+			}
+
+			return deleted, fmt.Errorf("Failed to retrieve attributes for %s, %w", obj.Key, err)
+		}
+
+		v, ok := attrs.Metadata[EXPIRES_AT_METADATA_KEY]
+
+		if !ok {
+			continue
+		}
+
+		expires_at, err := time.Parse(time.RFC3339, v)
+
+		if err != nil || !expires_at.Before(now) {
+			continue
+		}
+
+		err = bucket.Delete(ctx, obj.Key)
+
+		if err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+			return deleted, fmt.Errorf("Failed to delete %s, %w", obj.Key, err)
+		}
+
+		deleted = append(deleted, obj.Key)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,90 @@
+package atomicwrite
+
+import (
+	"context"
+	"gocloud.dev/blob"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithExpiresAt(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	now := time.Now()
+
+	tests := map[string]time.Time{
+		"expired.txt": now.Add(-1 * time.Hour),
+		"future.txt":  now.Add(1 * time.Hour),
+	}
+
+	for fname, expires_at := range tests {
+
+		path := filepath.Join(root, fname)
+
+		err := writeBytes(ctx, path, []byte("hello world"), WithExpiresAt(expires_at))
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", path, err)
+		}
+	}
+
+	permanent := filepath.Join(root, "permanent.txt")
+
+	err := writeBytes(ctx, permanent, []byte("hello world"))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", permanent, err)
+	}
+
+	bucket_uri := "file://" + filepath.ToSlash(root)
+
+	bucket, err := blob.OpenBucket(ctx, bucket_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to open bucket, %v", err)
+	}
+
+	defer bucket.Close()
+
+	attrs, err := bucket.Attributes(ctx, "future.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to retrieve attributes, %v", err)
+	}
+
+	expected := tests["future.txt"].UTC().Format(time.RFC3339)
+
+	if attrs.Metadata[EXPIRES_AT_METADATA_KEY] != expected {
+		t.Fatalf("Unexpected expiry time: %s", attrs.Metadata[EXPIRES_AT_METADATA_KEY])
+	}
+
+	deleted, err := DeleteExpired(ctx, bucket_uri, "")
+
+	if err != nil {
+		t.Fatalf("Failed to delete expired objects, %v", err)
+	}
+
+	if len(deleted) != 1 || deleted[0] != "expired.txt" {
+		t.Fatalf("Unexpected deleted objects: %v", deleted)
+	}
+
+	for _, fname := range []string{"future.txt", "permanent.txt"} {
+
+		_, err := os.Stat(filepath.Join(root, fname))
+
+		if err != nil {
+			t.Fatalf("Expected %s to exist, %v", fname, err)
+		}
+	}
+
+	_, err = os.Stat(filepath.Join(root, "expired.txt"))
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected expired.txt not to exist, %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package atomicwrite
+
+import (
+	"context"
+	"io"
+)
+
+// type WriterFactory is an interface for creating io.WriteCloser instances. Packages which accept a WriterFactory, rather
+// than calling the `New` constructor directly, can be tested without writing to real buckets.
+type WriterFactory interface {
+	// New returns a new io.WriteCloser instance for 'uri'.
+	New(ctx context.Context, uri string, opts ...Option) (io.WriteCloser, error)
+}
+
+// type DefaultWriterFactory implements the WriterFactory interface returning AtomicWriter instances.
+type DefaultWriterFactory struct {
+	WriterFactory
+}
+
+// type NopWriterFactory implements the WriterFactory interface returning io.WriteCloser instances which discard all
+// data written to them.
+type NopWriterFactory struct {
+	WriterFactory
+}
+
+// type nopWriteCloser implements the io.WriteCloser interface discarding all data written to it.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// New returns a new AtomicWriter instance. This is the equivalent of calling the `New` constructor.
+func (f *DefaultWriterFactory) New(ctx context.Context, uri string, opts ...Option) (io.WriteCloser, error) {
+	return New(ctx, uri, opts...)
+}
+
+// New returns a new io.WriteCloser instance which discards all data written to it. 'uri' and 'opts' are ignored.
+func (f *NopWriterFactory) New(ctx context.Context, uri string, opts ...Option) (io.WriteCloser, error) {
+
+	wr := &nopWriteCloser{
+		Writer: io.Discard,
+	}
+
+	return wr, nil
+}
+
+// Close is a no-op which returns nil.
+func (wr *nopWriteCloser) Close() error {
+	return nil
+}
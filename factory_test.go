@@ -0,0 +1,49 @@
+package atomicwrite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriterFactory(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	factories := map[string]WriterFactory{
+		"default": &DefaultWriterFactory{},
+		"nop":     &NopWriterFactory{},
+	}
+
+	for label, f := range factories {
+
+		path := filepath.Join(root, label+".txt")
+
+		wr, err := f.New(ctx, path)
+
+		if err != nil {
+			t.Fatalf("Failed to create %s writer, %v", label, err)
+		}
+
+		_, err = wr.Write([]byte(HELLO_WORLD))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes to %s writer, %v", label, err)
+		}
+
+		err = wr.Close()
+
+		if err != nil {
+			t.Fatalf("Failed to close %s writer, %v", label, err)
+		}
+
+		_, err = os.Stat(path)
+
+		if (label == "default") != (err == nil) {
+			t.Fatalf("Unexpected state for %s writer's path, %v", label, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// type multiError implements the error interface for zero or more errors.
+type multiError []error
+
+// Error returns the error messages of each error in 'e', separated by newlines.
+func (e multiError) Error() string {
+
+	msgs := make([]string, len(e))
+
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors in 'e'. This allows `errors.Is` and `errors.As` to inspect each error (in Go 1.20 and higher).
+func (e multiError) Unwrap() []error {
+	return e
+}
+
+// WithAdditionalPaths returns an Option which defines one or more additional URIs that data is copied to when the `Close`
+// method is invoked, after it has been copied to the final path. Each additional URI is written to sequentially and
+// independently of the others. If any of them fail an error listing each failure is returned by `Close` but the final path
+// is not rolled back (and the `Committed` method will still return true). Additional URIs in the same bucket as the final path
+// use the same `blob.Bucket` instance; others are opened using the function defined by the `WithBucketOpener` option. This is
+// useful for writing to both a versioned path and a "latest" path. This option may be specified multiple times.
+func WithAdditionalPaths(uris ...string) Option {
+
+	return func(o *options) {
+		o.additional_uris = append(o.additional_uris, uris...)
+	}
+}
+
+// commitAdditional copies the data in the intermediate temporary file to each of the URIs defined by the
+// `WithAdditionalPaths` option.
+func (aw *AtomicWriter) commitAdditional(ctx context.Context) error {
+
+	errs := make(multiError, 0)
+
+	for _, uri := range aw.additional_uris {
+
+		err := aw.commitAdditionalURI(ctx, uri)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed to copy data to %s, %w", uri, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// commitAdditionalURI copies the data in the intermediate temporary file to 'uri'.
+func (aw *AtomicWriter) commitAdditionalURI(ctx context.Context, uri string) error {
+	return aw.copyToURI(ctx, uri, 0, -1)
+}
+
+// copyToURI copies 'length' bytes of the data in the intermediate temporary file, starting at 'offset', to 'uri'. If 'length'
+// is negative all the data following 'offset' is copied.
+func (aw *AtomicWriter) copyToURI(ctx context.Context, uri string, offset int64, length int64) error {
+
+	bucket_uri, key, err := parseURI(uri)
+
+	if err != nil {
+		return err
+	}
+
+	key = normalizeKey(aw.key_normalizers, key)
+
+	if key == "" {
+		return fmt.Errorf("Failed to derive key from URI, normalized key is empty")
+	}
+
+	bucket := aw.bucket
+
+	if bucket_uri != aw.bucket_uri {
+
+		b, err := aw.bucket_opener(ctx, bucket_uri)
+
+		if err != nil {
+			return fmt.Errorf("Failed to open bucket %s, %w", bucket_uri, err)
+		}
+
+		defer aw.bucket_closer(b)
+		bucket = b
+	}
+
+	r, err := aw.bucket.NewRangeReader(ctx, aw.atomic_path, offset, length, nil)
+
+	if err != nil {
+		return fmt.Errorf("Failed to open atomic reader, %w", err)
+	}
+
+	defer r.Close()
+
+	// Cancelling the context before closing the writer discards any data written to it
+
+	wr_ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wr, err := bucket.NewWriter(wr_ctx, key, aw.final_opts)
+
+	if err != nil {
+		return fmt.Errorf("Failed to open %s for writing, %w", key, err)
+	}
+
+	buf := make([]byte, aw.copy_buffer_size)
+
+	_, err = io.CopyBuffer(struct{ io.Writer }{wr}, struct{ io.Reader }{r}, buf)
+
+	if err != nil {
+		cancel()
+		wr.Close()
+		return fmt.Errorf("Failed to copy data, %w", err)
+	}
+
+	return wr.Close()
+}
@@ -0,0 +1,124 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithAdditionalPaths(t *testing.T) {
+
+	root := t.TempDir()
+	other := t.TempDir()
+
+	path := filepath.Join(root, "v1.txt")
+
+	additional := []string{
+		filepath.Join(root, "latest.txt"),
+		filepath.Join(other, "v1.txt"),
+	}
+
+	err := testAtomicWrite(path, WithAdditionalPaths(additional...))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	for _, p := range append([]string{path}, additional...) {
+
+		body, err := os.ReadFile(p)
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", p, err)
+		}
+
+		if string(body) != HELLO_WORLD {
+			t.Fatalf("Unexpected body for %s: %s", p, string(body))
+		}
+	}
+}
+
+func TestWithAdditionalPathsError(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	path := filepath.Join(root, "v1.txt")
+
+	wr, err := New(ctx, path, WithAdditionalPaths("mem://", filepath.Join(root, "latest.txt")))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	var errs multiError
+
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("Expected a single additional path error, got %v", err)
+	}
+
+	if !wr.(*AtomicWriter).Committed() {
+		t.Fatalf("Expected writer to be committed")
+	}
+
+	_, err = os.Stat(filepath.Join(root, "latest.txt"))
+
+	if err != nil {
+		t.Fatalf("Expected latest.txt to be written, %v", err)
+	}
+}
+
+func TestWithAdditionalPathsSharedBucketOpener(t *testing.T) {
+
+	ctx := context.Background()
+
+	mem := memblob.OpenBucket(nil)
+	defer mem.Close()
+
+	other := memblob.OpenBucket(nil)
+	defer other.Close()
+
+	// Every call returns the same instance for each bucket URI, as a caching opener would
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+
+		if bucket_uri == "mem://other" {
+			return other, nil
+		}
+
+		return mem, nil
+	}
+
+	// The second write fails if the first closed the bucket for the additional path
+
+	for i := 0; i < 2; i++ {
+
+		err := testAtomicWrite("mem://v1.txt", WithBucketOpener(opener), WithAdditionalPaths("mem://other/latest.txt"))
+
+		if err != nil {
+			t.Fatalf("Failed to write (attempt %d), %v", i, err)
+		}
+	}
+
+	body, err := other.ReadAll(ctx, "latest.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to read latest.txt, %v", err)
+	}
+
+	if string(body) != HELLO_WORLD {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+}
@@ -0,0 +1,18 @@
+package atomicwrite
+
+// LOCK_EXTENSION is the extension appended to the final path to derive the lock file used by the `WithFlock` option.
+const LOCK_EXTENSION string = ".lock"
+
+// WithFlock returns an Option which acquires an exclusive advisory lock, using `flock(2)`, before data is copied to the final
+// path and releases it once the copy has completed (or failed). This prevents multiple processes using this option from
+// committing to the same file at the same time. The lock is taken on a separate lock file, the final path with a ".lock"
+// extension, rather than the final path itself because the final path is replaced, not rewritten, when data is committed.
+// The lock file is created if necessary and is not removed. This option is only supported for local filesystem paths (and
+// `file://` URIs) on platforms which support `flock(2)`; otherwise the `New` constructor or the `Close` method return an
+// error wrapping ErrNotSupported.
+func WithFlock() Option {
+
+	return func(o *options) {
+		o.flock = true
+	}
+}
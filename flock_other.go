@@ -0,0 +1,9 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package atomicwrite
+
+// lockFile returns ErrNotSupported since `flock(2)` is not available on this platform.
+func lockFile(path string) (func() error, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,161 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithFlock(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	wr, err := New(ctx, path, WithFlock())
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	// Hold the lock, as another process would, so that Close blocks until it is released
+
+	unlock, err := lockFile(path + LOCK_EXTENSION)
+
+	if err != nil {
+		t.Fatalf("Failed to acquire lock, %v", err)
+	}
+
+	errs := make(chan error)
+
+	go func() {
+		errs <- wr.Close()
+	}()
+
+	select {
+	case err := <-errs:
+		t.Fatalf("Expected close to block while lock is held, %v", err)
+	case <-time.After(50 * time.Millisecond):
+		// pass
+	}
+
+	_, err = os.Stat(path)
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected final path not to exist while lock is held, %v", err)
+	}
+
+	err = unlock()
+
+	if err != nil {
+		t.Fatalf("Failed to release lock, %v", err)
+	}
+
+	err = <-errs
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(body) != HELLO_WORLD {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+}
+
+func TestWithFlockNotSupported(t *testing.T) {
+
+	ctx := context.Background()
+
+	_, err := New(ctx, "mem://atomicwrite.txt", WithFlock())
+
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestLockKey(t *testing.T) {
+
+	root := t.TempDir()
+
+	o := defaultOptions()
+	WithFlock()(o)
+
+	unlock, err := o.lockKey("file://"+root, "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to lock key, %v", err)
+	}
+
+	defer unlock()
+
+	// The lock file must be the same one used by AtomicWriter instances created with the WithFlock option
+
+	_, err = os.Stat(filepath.Join(root, "atomicwrite.txt"+LOCK_EXTENSION))
+
+	if err != nil {
+		t.Fatalf("Failed to stat lock file, %v", err)
+	}
+
+	_, err = o.lockKey("mem://", "atomicwrite.txt")
+
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestWithFlockSetFinalPath(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	err := os.Mkdir(filepath.Join(root, "a"), 0755)
+
+	if err != nil {
+		t.Fatalf("Failed to create directory, %v", err)
+	}
+
+	wr, err := New(ctx, filepath.Join(root, "a", "atomicwrite.bin"), WithFlock())
+
+	if err != nil {
+		t.Fatalf("Failed to create new writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	err = aw.SetFinalPath("b/atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to set final path, %v", err)
+	}
+
+	expected := filepath.Join(root, "a", "b", "atomicwrite.txt") + LOCK_EXTENSION
+
+	if aw.lock_path != expected {
+		t.Fatalf("Unexpected lock path: %s", aw.lock_path)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package atomicwrite
+
+import (
+	"golang.org/x/sys/unix"
+	"os"
+	"path/filepath"
+)
+
+// lockFile acquires an exclusive advisory lock on 'path', creating it (and its parent directory) if necessary, blocking until
+// the lock is available. It returns a function which releases the lock.
+func lockFile(path string) (func() error, error) {
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = unix.Flock(int(f.Fd()), unix.LOCK_EX)
+
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	unlock := func() error {
+
+		err := unix.Flock(int(f.Fd()), unix.LOCK_UN)
+
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		return f.Close()
+	}
+
+	return unlock, nil
+}
@@ -0,0 +1,128 @@
+package atomicwrite
+
+import (
+	"fmt"
+	"gocloud.dev/gcerrors"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// WithBackgroundFlush returns an Option which starts a background goroutine, when the AtomicWriter instance is created,
+// that invokes the `Flush` method every 'interval'. The goroutine is stopped, and has exited, before the `Close` or `Abort`
+// methods return. Errors returned by `Flush` are dispatched to listeners as EventError events. Since each flush copies all
+// the data written so far 'interval' should be chosen accordingly.
+func WithBackgroundFlush(interval time.Duration) Option {
+
+	return func(o *options) {
+		o.flush_interval = interval
+	}
+}
+
+// Flush persists the data written so far to the underlying bucket. Data written by a `blob.Writer` instance is not persisted
+// until the writer is closed so the current intermediate temporary file is closed, its contents are copied to a new intermediate
+// temporary file which subsequent writes are appended to, and the old temporary file is kept (replacing the one kept by the
+// previous call to Flush) until the writer is closed or aborted. Each call to Flush copies all the data written so far; if no
+// data has been written since the last call it is a no-op. If anything fails after the current temporary file has been closed
+// the writer is aborted.
+func (aw *AtomicWriter) Flush() error {
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	switch atomic.LoadInt32(&aw.state) {
+	case state_aborted:
+		return ErrAborted
+	case state_closed:
+		return fmt.Errorf("Atomic writer has been closed")
+	}
+
+	written := atomic.LoadInt64(&aw.written)
+
+	if aw.checkpoint_path != "" && written == aw.flushed {
+		return nil
+	}
+
+	checkpoint_path, err := aw.roll()
+
+	if err != nil {
+		return err
+	}
+
+	aw.deleteCheckpoint()
+
+	aw.checkpoint_path = checkpoint_path
+	aw.flushed = written
+
+	return nil
+}
+
+// startFlush launches a goroutine which invokes the `Flush` method every 'interval' until the `stopFlush` method is invoked
+// or the writer is no longer open.
+func (aw *AtomicWriter) startFlush(interval time.Duration) {
+
+	aw.flush_done = make(chan struct{})
+	aw.flush_wg.Add(1)
+
+	go func() {
+
+		defer aw.flush_wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+
+			select {
+			case <-aw.flush_done:
+				return
+			case <-ticker.C:
+				// pass
+			}
+
+			if atomic.LoadInt32(&aw.state) != state_open {
+				return
+			}
+
+			err := aw.Flush()
+
+			if err != nil {
+				aw.mu.Lock()
+				aw.emitLocked(EventError, 0, err)
+				aw.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// stopFlush stops the goroutine launched by the `startFlush` method, if present, and waits for it to exit.
+func (aw *AtomicWriter) stopFlush() {
+
+	if aw.flush_done == nil {
+		return
+	}
+
+	aw.flush_once.Do(func() {
+		close(aw.flush_done)
+	})
+
+	aw.flush_wg.Wait()
+}
+
+// deleteCheckpoint removes the temporary file kept by the most recent call to the `Flush` method, if present. The caller
+// must hold aw.mu.
+func (aw *AtomicWriter) deleteCheckpoint() {
+
+	if aw.checkpoint_path == "" {
+		return
+	}
+
+	err := aw.bucket.Delete(aw.cleanup_ctx, aw.checkpoint_path)
+
+	if err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+		log.Printf("Failed to delete %s, %v", aw.checkpoint_path, err)
+		aw.emitLocked(EventError, 0, fmt.Errorf("Failed to delete %s, %w", aw.checkpoint_path, err))
+	}
+
+	aw.checkpoint_path = ""
+}
@@ -0,0 +1,203 @@
+package atomicwrite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFlush(t *testing.T) {
+
+	tests := map[string]time.Duration{
+		"manual":     0,
+		"background": 10 * time.Millisecond,
+	}
+
+	for label, interval := range tests {
+
+		ctx := context.Background()
+
+		root := t.TempDir()
+		uri := "file://" + filepath.Join(root, "atomicwrite.txt") + "?metadata=skip"
+
+		wr, err := New(ctx, uri, WithBackgroundFlush(interval))
+
+		if err != nil {
+			t.Fatalf("Failed to create writer for %s, %v", label, err)
+		}
+
+		aw := wr.(*AtomicWriter)
+
+		_, err = aw.Write([]byte(HELLO_WORLD))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes for %s, %v", label, err)
+		}
+
+		switch label {
+		case "manual":
+
+			err := aw.Flush()
+
+			if err != nil {
+				t.Fatalf("Failed to flush writer, %v", err)
+			}
+
+		default:
+			time.Sleep(interval * 10)
+		}
+
+		// The checkpoint file is the only complete file in root; the current temporary
+		// file is still being written to by fileblob
+
+		aw.mu.Lock()
+		checkpoint_path := aw.checkpoint_path
+		aw.mu.Unlock()
+
+		if checkpoint_path == "" {
+			t.Fatalf("Expected checkpoint for %s", label)
+		}
+
+		body, err := os.ReadFile(filepath.Join(root, checkpoint_path))
+
+		if err != nil {
+			t.Fatalf("Failed to read checkpoint for %s, %v", label, err)
+		}
+
+		if string(body) != HELLO_WORLD {
+			t.Fatalf("Unexpected checkpoint for %s: %s", label, string(body))
+		}
+
+		err = aw.Close()
+
+		if err != nil {
+			t.Fatalf("Failed to close writer for %s, %v", label, err)
+		}
+
+		entries, err := os.ReadDir(root)
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", root, err)
+		}
+
+		if len(entries) != 1 {
+			t.Fatalf("Expected 1 entry in %s for %s, got %d", root, label, len(entries))
+		}
+
+		body, err = os.ReadFile(filepath.Join(root, "atomicwrite.txt"))
+
+		if err != nil {
+			t.Fatalf("Failed to read final path for %s, %v", label, err)
+		}
+
+		if string(body) != HELLO_WORLD {
+			t.Fatalf("Unexpected body for %s: %s", label, string(body))
+		}
+	}
+}
+
+func TestFlushAbort(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	uri := "file://" + filepath.Join(root, "atomicwrite.txt") + "?metadata=skip"
+
+	wr, err := New(ctx, uri, WithBackgroundFlush(time.Millisecond))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	for i := 0; i < 10; i++ {
+
+		_, err = aw.Write([]byte(HELLO_WORLD))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	err = aw.Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort writer, %v", err)
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected 0 entries in %s, got %d", root, len(entries))
+	}
+}
+
+func TestFlushRepeated(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	uri := "file://" + filepath.Join(root, "atomicwrite.txt") + "?metadata=skip"
+
+	wr, err := New(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	expected := ""
+
+	for i := 0; i < 25; i++ {
+
+		_, err = aw.Write([]byte(HELLO_WORLD))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes (flush %d), %v", i, err)
+		}
+
+		expected += HELLO_WORLD
+
+		err = aw.Flush()
+
+		if err != nil {
+			t.Fatalf("Failed to flush writer (flush %d), %v", i, err)
+		}
+
+		// Temporary paths are derived from the final path so their length does not grow
+
+		aw.mu.Lock()
+		atomic_path := aw.atomic_path
+		aw.mu.Unlock()
+
+		if len(atomic_path) > len("atomicwrite-")+20+len(".txt") {
+			t.Fatalf("Unexpected temporary path length after flush %d: %s", i, atomic_path)
+		}
+	}
+
+	err = aw.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	body, err := os.ReadFile(filepath.Join(root, "atomicwrite.txt"))
+
+	if err != nil {
+		t.Fatalf("Failed to read final path, %v", err)
+	}
+
+	if string(body) != expected {
+		t.Fatalf("Unexpected body, expected %d bytes but got %d", len(expected), len(body))
+	}
+}
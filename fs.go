@@ -0,0 +1,455 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// type FSCloser is the interface implemented by the fs.FS instances returned by the `NewFS` constructor. The `Close` method
+// closes the underlying bucket.
+type FSCloser interface {
+	fs.StatFS
+	fs.ReadDirFS
+	io.Closer
+}
+
+// type bucketFS implements the FSCloser interface for reading data from a gocloud.dev/blob bucket.
+type bucketFS struct {
+	fs.FS
+	// The context.Context instance used for bucket operations
+	ctx context.Context
+	// The underlying blob.Bucket instance where data is read from
+	bucket *blob.Bucket
+	// The function used to close the underlying bucket, which is a no-op for buckets owned by the caller
+	bucket_closer func(*blob.Bucket) error
+}
+
+var _ FSCloser = (*bucketFS)(nil)
+
+// type bucketFile implements the fs.File interface for a snapshot copy of a blob.
+type bucketFile struct {
+	fs.File
+	// The name of the file, as passed to the `Open` method
+	name string
+	// The snapshotReader instance for the file
+	reader *snapshotReader
+	// The modification time of the original blob
+	mod_time time.Time
+}
+
+// type bucketFileInfo implements the fs.FileInfo interface for a blob.
+type bucketFileInfo struct {
+	fs.FileInfo
+	// The base name of the blob
+	name string
+	// The size of the blob in bytes
+	size int64
+	// The modification time of the blob
+	mod_time time.Time
+	// A boolean flag signaling whether the blob is a virtual directory
+	is_dir bool
+}
+
+// type bucketDir implements the fs.ReadDirFile interface for a virtual directory.
+type bucketDir struct {
+	fs.ReadDirFile
+	// The fs.FileInfo instance for the directory
+	info fs.FileInfo
+	// The fs.FileInfo instances for the entries in the directory
+	infos []fs.FileInfo
+	// The number of entries already returned by the `ReadDir` method
+	offset int
+}
+
+// type bucketDirEntry implements the fs.DirEntry interface for a blob or virtual directory.
+type bucketDirEntry struct {
+	fs.DirEntry
+	// The fs.FileInfo instance for the entry
+	info fs.FileInfo
+}
+
+// NewFS returns a new FSCloser instance that serves data from the gocloud.dev/blob bucket defined by 'bucket_uri'. The instance
+// implements the fs.FS, fs.StatFS and fs.ReadDirFS interfaces. Files are read from snapshot copies of each blob (created when
+// the `Open` method is invoked and removed when the file is closed) so that data read is not affected by concurrent writes,
+// atomic or otherwise. The instance should be closed, which closes the bucket, when it is no longer needed; buckets returned by
+// the function defined by the `WithBucketOpener` option are left open. Only the `WithBucketOpener` and `WithBucketWrapper`
+// options are used.
+func NewFS(ctx context.Context, bucket_uri string, opts ...Option) (FSCloser, error) {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	bucket, err := o.wrappedBucketOpener()(ctx, bucket_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open bucket %s, %w", bucket_uri, err)
+	}
+
+	b := &bucketFS{
+		ctx:           ctx,
+		bucket:        bucket,
+		bucket_closer: o.closeBucket,
+	}
+
+	return b, nil
+}
+
+// Open opens the named file for reading.
+func (b *bucketFS) Open(name string) (fs.File, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == "." {
+		return b.openDir(name)
+	}
+
+	// The snapshot copy will have its own modification time so we need to
+	// retrieve the attributes for the original blob first
+
+	attrs, err := b.bucket.Attributes(b.ctx, name)
+
+	if gcerrors.Code(err) == gcerrors.NotFound {
+		return b.openDir(name)
+	}
+
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fsError(err)}
+	}
+
+	r, err := openSnapshot(b.ctx, b.bucket, name)
+
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fsError(err)}
+	}
+
+	f := &bucketFile{
+		name:     name,
+		reader:   r,
+		mod_time: attrs.ModTime,
+	}
+
+	return f, nil
+}
+
+// Close closes the underlying bucket, unless it was returned by the function defined by the `WithBucketOpener` option. Files
+// opened by the `Open` method should be closed first.
+func (b *bucketFS) Close() error {
+	return b.bucket_closer(b.bucket)
+}
+
+// Stat returns a fs.FileInfo instance describing the named file, derived from the attributes of the underlying blob.
+func (b *bucketFS) Stat(name string) (fs.FileInfo, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == "." {
+		return b.statDir(name)
+	}
+
+	attrs, err := b.bucket.Attributes(b.ctx, name)
+
+	if gcerrors.Code(err) == gcerrors.NotFound {
+		return b.statDir(name)
+	}
+
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fsError(err)}
+	}
+
+	info := &bucketFileInfo{
+		name:     path.Base(name),
+		size:     attrs.Size,
+		mod_time: attrs.ModTime,
+	}
+
+	return info, nil
+}
+
+// ReadDir reads the named directory and returns a list of directory entries sorted by filename. Directories are
+// derived from blob keys using "/" as a delimiter, the same way `blob.Bucket.List` does.
+func (b *bucketFS) ReadDir(name string) ([]fs.DirEntry, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	infos, err := b.listDir(name)
+
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fsError(err)}
+	}
+
+	if len(infos) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, len(infos))
+
+	for i, info := range infos {
+		entries[i] = &bucketDirEntry{info: info}
+	}
+
+	return entries, nil
+}
+
+// listDir returns the list of fs.FileInfo instances for the blobs and virtual directories contained by the
+// directory 'name' sorted by filename. Temporary and snapshot files are excluded.
+func (b *bucketFS) listDir(name string) ([]fs.FileInfo, error) {
+
+	prefix := ""
+
+	if name != "." {
+		prefix = name + "/"
+	}
+
+	list_opts := &blob.ListOptions{
+		Prefix:    prefix,
+		Delimiter: "/",
+	}
+
+	infos := make([]fs.FileInfo, 0)
+
+	iter := b.bucket.List(list_opts)
+
+	for {
+
+		obj, err := iter.Next(b.ctx)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip the snapshot copies created by the `Open` method (and any other writes in progress)
+
+		if !obj.IsDir && isAtomicPath(obj.Key, "") {
+			continue
+		}
+
+		info := &bucketFileInfo{
+			name:     path.Base(strings.TrimSuffix(obj.Key, "/")),
+			size:     obj.Size,
+			mod_time: obj.ModTime,
+			is_dir:   obj.IsDir,
+		}
+
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+
+	return infos, nil
+}
+
+// statDir returns a fs.FileInfo instance for the virtual directory 'name' or a fs.ErrNotExist error if
+// there are no blobs, other than temporary and snapshot files, whose keys are prefixed by 'name'.
+func (b *bucketFS) statDir(name string) (fs.FileInfo, error) {
+
+	if name != "." {
+
+		list_opts := &blob.ListOptions{
+			Prefix: name + "/",
+		}
+
+		iter := b.bucket.List(list_opts)
+
+		for {
+
+			obj, err := iter.Next(b.ctx)
+
+			if err == io.EOF {
+				return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+			}
+
+			if err != nil {
+				return nil, &fs.PathError{Op: "stat", Path: name, Err: fsError(err)}
+			}
+
+			if !isAtomicPath(obj.Key, "") {
+				break
+			}
+		}
+	}
+
+	info := &bucketFileInfo{
+		name:   path.Base(name),
+		is_dir: true,
+	}
+
+	return info, nil
+}
+
+// openDir returns a fs.ReadDirFile instance for the virtual directory 'name'.
+func (b *bucketFS) openDir(name string) (fs.File, error) {
+
+	info, err := b.statDir(name)
+
+	if err != nil {
+		err.(*fs.PathError).Op = "open"
+		return nil, err
+	}
+
+	infos, err := b.listDir(name)
+
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fsError(err)}
+	}
+
+	d := &bucketDir{
+		info:  info,
+		infos: infos,
+	}
+
+	return d, nil
+}
+
+// Stat returns a fs.FileInfo instance describing the file.
+func (f *bucketFile) Stat() (fs.FileInfo, error) {
+
+	info := &bucketFileInfo{
+		name:     path.Base(f.name),
+		size:     f.reader.Size(),
+		mod_time: f.mod_time,
+	}
+
+	return info, nil
+}
+
+// Read reads up to len(b) bytes from the file.
+func (f *bucketFile) Read(b []byte) (int, error) {
+	return f.reader.Read(b)
+}
+
+// Close closes the file and removes its snapshot copy.
+func (f *bucketFile) Close() error {
+	return f.reader.Close()
+}
+
+// Stat returns a fs.FileInfo instance describing the directory.
+func (d *bucketDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read returns an error since directories can not be read.
+func (d *bucketDir) Read(b []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+// Close closes the directory.
+func (d *bucketDir) Close() error {
+	return nil
+}
+
+// ReadDir returns the next 'n' entries in the directory or, if 'n' is less than or equal to zero, all of the
+// remaining entries in the directory. See the documentation for `fs.ReadDirFile` for details.
+func (d *bucketDir) ReadDir(n int) ([]fs.DirEntry, error) {
+
+	remaining := len(d.infos) - d.offset
+
+	if n > 0 && remaining == 0 {
+		return nil, io.EOF
+	}
+
+	if n > 0 && n < remaining {
+		remaining = n
+	}
+
+	entries := make([]fs.DirEntry, remaining)
+
+	for i := 0; i < remaining; i++ {
+		entries[i] = &bucketDirEntry{info: d.infos[d.offset+i]}
+	}
+
+	d.offset += remaining
+	return entries, nil
+}
+
+// Name returns the base name of the entry.
+func (e *bucketDirEntry) Name() string {
+	return e.info.Name()
+}
+
+// IsDir reports whether the entry is a (virtual) directory.
+func (e *bucketDirEntry) IsDir() bool {
+	return e.info.IsDir()
+}
+
+// Type returns the type bits for the entry.
+func (e *bucketDirEntry) Type() fs.FileMode {
+	return e.info.Mode().Type()
+}
+
+// Info returns the fs.FileInfo instance for the entry.
+func (e *bucketDirEntry) Info() (fs.FileInfo, error) {
+	return e.info, nil
+}
+
+// Name returns the base name of the blob.
+func (i *bucketFileInfo) Name() string {
+	return i.name
+}
+
+// Size returns the size of the blob in bytes.
+func (i *bucketFileInfo) Size() int64 {
+	return i.size
+}
+
+// Mode returns a synthetic read-only file mode for the blob.
+func (i *bucketFileInfo) Mode() fs.FileMode {
+
+	if i.is_dir {
+		return fs.ModeDir | 0555
+	}
+
+	return 0444
+}
+
+// ModTime returns the modification time of the blob.
+func (i *bucketFileInfo) ModTime() time.Time {
+	return i.mod_time
+}
+
+// IsDir reports whether the blob is a virtual directory.
+func (i *bucketFileInfo) IsDir() bool {
+	return i.is_dir
+}
+
+// Sys returns nil.
+func (i *bucketFileInfo) Sys() interface{} {
+	return nil
+}
+
+// fsError maps gocloud.dev/gcerrors error codes to their io/fs equivalents, where possible.
+func fsError(err error) error {
+
+	switch gcerrors.Code(err) {
+	case gcerrors.NotFound:
+		return fs.ErrNotExist
+	case gcerrors.PermissionDenied:
+		return fs.ErrPermission
+	case gcerrors.InvalidArgument:
+		return fs.ErrInvalid
+	default:
+		return err
+	}
+}
@@ -0,0 +1,294 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewFS(t *testing.T) {
+
+	ctx := context.Background()
+
+	tmpdir := t.TempDir()
+	path := filepath.Join(tmpdir, "atomicwrite.txt")
+
+	err := testAtomicWrite(path)
+
+	if err != nil {
+		t.Fatalf("Failed to write atomic file, %v", err)
+	}
+
+	bucket_fs, err := NewFS(ctx, fmt.Sprintf("file://%s?metadata=skip", tmpdir))
+
+	if err != nil {
+		t.Fatalf("Failed to create FS, %v", err)
+	}
+
+	defer bucket_fs.Close()
+
+	body, err := fs.ReadFile(bucket_fs, "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to read atomicwrite.txt, %v", err)
+	}
+
+	if string(body) != HELLO_WORLD {
+		t.Fatalf("Invalid data (%s) read from atomicwrite.txt", string(body))
+	}
+
+	_, err = bucket_fs.Open("missing.txt")
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Expected missing.txt not to exist, got %v", err)
+	}
+
+	_, err = bucket_fs.Open("../atomicwrite.txt")
+
+	if !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("Expected invalid path error, got %v", err)
+	}
+
+	entries, err := os.ReadDir(tmpdir)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", tmpdir, err)
+	}
+
+	for _, e := range entries {
+
+		if e.Name() != "atomicwrite.txt" && e.Name() != "atomicwrite.txt.attrs" {
+			t.Fatalf("Expected snapshot files to be removed, found %s", e.Name())
+		}
+	}
+}
+
+func TestNewFSStat(t *testing.T) {
+
+	ctx := context.Background()
+
+	tmpdir := t.TempDir()
+	path := filepath.Join(tmpdir, "atomicwrite.txt")
+
+	err := testAtomicWrite(path)
+
+	if err != nil {
+		t.Fatalf("Failed to write atomic file, %v", err)
+	}
+
+	bucket_fs, err := NewFS(ctx, fmt.Sprintf("file://%s", tmpdir))
+
+	if err != nil {
+		t.Fatalf("Failed to create FS, %v", err)
+	}
+
+	defer bucket_fs.Close()
+
+	_, ok := bucket_fs.(fs.StatFS)
+
+	if !ok {
+		t.Fatalf("Expected FS to implement fs.StatFS")
+	}
+
+	info, err := fs.Stat(bucket_fs, "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to stat atomicwrite.txt, %v", err)
+	}
+
+	dir_info, err := fs.Stat(os.DirFS(tmpdir), "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to stat atomicwrite.txt using os.DirFS, %v", err)
+	}
+
+	if info.Name() != dir_info.Name() {
+		t.Fatalf("Unexpected name: %s", info.Name())
+	}
+
+	if info.Size() != dir_info.Size() {
+		t.Fatalf("Unexpected size: %d", info.Size())
+	}
+
+	if !info.ModTime().Equal(dir_info.ModTime()) {
+		t.Fatalf("Unexpected modification time: %v", info.ModTime())
+	}
+
+	if info.IsDir() || !info.Mode().IsRegular() {
+		t.Fatalf("Unexpected mode: %v", info.Mode())
+	}
+
+	_, err = fs.Stat(bucket_fs, "missing.txt")
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Expected missing.txt not to exist, got %v", err)
+	}
+}
+
+func TestNewFSReadDir(t *testing.T) {
+
+	ctx := context.Background()
+
+	tmpdir := t.TempDir()
+
+	for _, fname := range []string{"a.txt", "b/c.txt", "b/d/e.txt"} {
+
+		path := filepath.Join(tmpdir, fname)
+
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+
+		if err != nil {
+			t.Fatalf("Failed to create parent directory for %s, %v", path, err)
+		}
+
+		err = testAtomicWrite(path)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", path, err)
+		}
+	}
+
+	bucket_fs, err := NewFS(ctx, fmt.Sprintf("file://%s", tmpdir))
+
+	if err != nil {
+		t.Fatalf("Failed to create FS, %v", err)
+	}
+
+	defer bucket_fs.Close()
+
+	entries, err := fs.ReadDir(bucket_fs, "b")
+
+	if err != nil {
+		t.Fatalf("Failed to read directory, %v", err)
+	}
+
+	if len(entries) != 2 || entries[0].Name() != "c.txt" || entries[1].Name() != "d" || !entries[1].IsDir() {
+		t.Fatalf("Unexpected directory entries: %v", entries)
+	}
+
+	err = fstest.TestFS(bucket_fs, "a.txt", "b/c.txt", "b/d/e.txt")
+
+	if err != nil {
+		t.Fatalf("FS failed fstest.TestFS, %v", err)
+	}
+}
+
+func TestNewFSReadDirWhileOpen(t *testing.T) {
+
+	ctx := context.Background()
+
+	tmpdir := t.TempDir()
+
+	for _, fname := range []string{"a.txt", "b/c.txt"} {
+
+		path := filepath.Join(tmpdir, fname)
+
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+
+		if err != nil {
+			t.Fatalf("Failed to create parent directory for %s, %v", path, err)
+		}
+
+		err = testAtomicWrite(path)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", path, err)
+		}
+	}
+
+	bucket_fs, err := NewFS(ctx, fmt.Sprintf("file://%s", tmpdir))
+
+	if err != nil {
+		t.Fatalf("Failed to create FS, %v", err)
+	}
+
+	defer bucket_fs.Close()
+
+	for _, fname := range []string{"a.txt", "b/c.txt"} {
+
+		f, err := bucket_fs.Open(fname)
+
+		if err != nil {
+			t.Fatalf("Failed to open %s, %v", fname, err)
+		}
+
+		defer f.Close()
+	}
+
+	entries, err := fs.ReadDir(bucket_fs, ".")
+
+	if err != nil {
+		t.Fatalf("Failed to read directory, %v", err)
+	}
+
+	if len(entries) != 2 || entries[0].Name() != "a.txt" || entries[1].Name() != "b" {
+		t.Fatalf("Unexpected directory entries: %v", entries)
+	}
+
+	entries, err = fs.ReadDir(bucket_fs, "b")
+
+	if err != nil {
+		t.Fatalf("Failed to read directory, %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].Name() != "c.txt" {
+		t.Fatalf("Unexpected directory entries: %v", entries)
+	}
+}
+
+func TestNewFSClose(t *testing.T) {
+
+	ctx := context.Background()
+
+	mem := memblob.OpenBucket(nil)
+	defer mem.Close()
+
+	opener := func(ctx context.Context, uri string) (*blob.Bucket, error) {
+		return mem, nil
+	}
+
+	bucket_fs, err := NewFS(ctx, "mem://", WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to create FS, %v", err)
+	}
+
+	err = bucket_fs.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close FS, %v", err)
+	}
+
+	// The bucket returned by the custom opener belongs to the caller and should still be open
+
+	err = mem.WriteAll(ctx, "a.txt", []byte("a.txt"), nil)
+
+	if err != nil {
+		t.Fatalf("Failed to write to bucket after closing FS, %v", err)
+	}
+
+	bucket_fs, err = NewFS(ctx, "mem://")
+
+	if err != nil {
+		t.Fatalf("Failed to create FS, %v", err)
+	}
+
+	err = bucket_fs.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close FS, %v", err)
+	}
+
+	_, err = fs.Stat(bucket_fs, "a.txt")
+
+	if err == nil {
+		t.Fatalf("Expected Stat to fail after closing FS")
+	}
+}
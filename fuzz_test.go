@@ -0,0 +1,207 @@
+//go:build go1.18
+// +build go1.18
+
+package atomicwrite
+
+import (
+	"context"
+	"gocloud.dev/blob"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzNewWithOptions(f *testing.F) {
+
+	ctx := context.Background()
+
+	root, err := filepath.EvalSymlinks(f.TempDir())
+
+	if err != nil {
+		f.Fatalf("Failed to derive temporary directory, %v", err)
+	}
+
+	// Relative paths are resolved relative to the current working directory so
+	// make sure that is somewhere we can check for (and clean up) stray files
+
+	cwd, err := os.Getwd()
+
+	if err != nil {
+		f.Fatalf("Failed to derive current working directory, %v", err)
+	}
+
+	err = os.Chdir(root)
+
+	if err != nil {
+		f.Fatalf("Failed to change directory, %v", err)
+	}
+
+	f.Cleanup(func() {
+		os.Chdir(cwd)
+	})
+
+	seeds := []string{
+		"atomicwrite.txt",
+		"./atomicwrite",
+		".atomicwrite.txt",
+		"atomic write é.txt",
+		"atomicwrite%20.txt",
+		"file://" + filepath.Join(root, "atomicwrite.txt"),
+		"file://" + filepath.Join(root, "atomicwrite.txt") + "?metadata=skip",
+		"file:///",
+		"mem://atomicwrite.txt",
+		"mem://bucket/a/b/atomicwrite.txt",
+		"mem://",
+		"s3://bucket/atomicwrite.txt?region=us-east-1",
+		"C:\\tmp\\atomicwrite.txt",
+		"\\\\server\\share\\atomicwrite.txt",
+		"",
+		"://",
+		"%zz",
+	}
+
+	for _, uri := range seeds {
+		f.Add(uri)
+	}
+
+	f.Fuzz(func(t *testing.T, uri string) {
+
+		// Don't write anywhere outside the temporary directory
+
+		path, err := localPath(uri)
+
+		if err == nil && !strings.HasPrefix(path, root+string(filepath.Separator)) {
+			t.Skip()
+		}
+
+		wr, err := NewWithOptions(ctx, uri, nil)
+
+		if err != nil {
+
+			if wr != nil {
+				t.Fatalf("Expected nil writer with error for %q", uri)
+			}
+
+			return
+		}
+
+		if wr == nil {
+			t.Fatalf("Expected writer or error for %q", uri)
+		}
+
+		err = wr.(*AtomicWriter).Abort()
+
+		if err != nil {
+			t.Fatalf("Failed to abort writer for %q, %v", uri, err)
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+
+			if err != nil {
+				return err
+			}
+
+			if !d.IsDir() {
+				t.Fatalf("Unexpected file %s left behind for %q", path, uri)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			t.Fatalf("Failed to walk %s, %v", root, err)
+		}
+	})
+}
+
+func FuzzTempNameGeneration(f *testing.F) {
+
+	ctx := context.Background()
+
+	seeds := []string{
+		"atomicwrite",
+		"atomicwrite.txt",
+		"atomicwrite.tar.gz",
+		"a.b.c.d",
+		".atomicwrite",
+		"..atomicwrite.txt",
+		"a/b/atomicwrite.txt",
+		"a/b/.atomicwrite",
+		"a/b/",
+		"atomicwrite.",
+		"",
+	}
+
+	for i, key := range seeds {
+		f.Add(key, uint8(i))
+	}
+
+	f.Add("atomicwrite.txt", uint8(max_atomic_path_tries))
+	f.Add("atomicwrite.txt", uint8(255))
+
+	f.Fuzz(func(t *testing.T, final_path string, collisions uint8) {
+
+		_, mock := newMockBucket()
+		bucket := blob.NewBucket(&collisionBucket{mockBucket: mock, collisions: int(collisions)})
+
+		defer bucket.Close()
+
+		attempts := 0
+
+		on_collision := func(attempt int, test_path string) {
+			attempts = attempt
+		}
+
+		atomic_path, err := deriveAtomicPath(ctx, bucket, final_path, on_collision)
+
+		switch {
+		case !utf8.ValidString(final_path):
+
+			// The gocloud.dev/blob package rejects keys which are not valid UTF-8
+
+			if err == nil {
+				t.Fatalf("Expected invalid key %q to fail", final_path)
+			}
+
+			return
+
+		case int(collisions) >= max_atomic_path_tries:
+
+			if err == nil {
+				t.Fatalf("Expected %q to fail after %d collisions", final_path, collisions)
+			}
+
+			if attempts != max_atomic_path_tries {
+				t.Fatalf("Expected %d attempts for %q, got %d", max_atomic_path_tries, final_path, attempts)
+			}
+
+			return
+		}
+
+		if err != nil {
+			t.Fatalf("Failed to derive temporary path for %q after %d collisions, %v", final_path, collisions, err)
+		}
+
+		if attempts != int(collisions) {
+			t.Fatalf("Expected %d attempts for %q, got %d", collisions, final_path, attempts)
+		}
+
+		if atomic_path == "" {
+			t.Fatalf("Empty temporary path for %q", final_path)
+		}
+
+		if atomic_path == final_path {
+			t.Fatalf("Temporary path for %q is the same as the final path", final_path)
+		}
+
+		dir, _ := path.Split(final_path)
+
+		if !strings.HasPrefix(atomic_path, dir) || strings.Contains(strings.TrimPrefix(atomic_path, dir), "/") {
+			t.Fatalf("Temporary path %q is not in the same directory as %q", atomic_path, final_path)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package atomicwrite
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Storage classes for objects in GCS buckets. Note that changing the storage class of an existing object requires
+// rewriting it so the storage class should be assigned when the final object is written.
+const (
+	GCS_STORAGE_CLASS_STANDARD string = "STANDARD"
+	GCS_STORAGE_CLASS_NEARLINE string = "NEARLINE"
+	GCS_STORAGE_CLASS_COLDLINE string = "COLDLINE"
+	GCS_STORAGE_CLASS_ARCHIVE  string = "ARCHIVE"
+)
+
+// GCSStorageClass returns the canonical (upper-case) name for the GCS storage class 'class', suitable for assigning to the
+// `ObjectAttrs.StorageClass` property of the `storage.Writer` instance used by gocloud.dev/blob/gcsblob writers. An error is
+// returned if 'class' is not a known storage class.
+func GCSStorageClass(class string) (string, error) {
+
+	class = strings.ToUpper(class)
+
+	switch class {
+	case GCS_STORAGE_CLASS_STANDARD, GCS_STORAGE_CLASS_NEARLINE, GCS_STORAGE_CLASS_COLDLINE, GCS_STORAGE_CLASS_ARCHIVE:
+		return class, nil
+	default:
+		return "", fmt.Errorf("Unsupported storage class '%s'", class)
+	}
+}
+
+// Retention modes for objects in GCS buckets. Objects with a "Locked" retention configuration can not be deleted,
+// and the configuration can not be shortened or removed, until the retention period expires.
+const (
+	GCS_RETENTION_MODE_LOCKED   string = "Locked"
+	GCS_RETENTION_MODE_UNLOCKED string = "Unlocked"
+)
+
+// GCSRetentionMode validates the GCS retention mode 'mode' and retention period ending at 'retain_until' and returns the
+// canonical name for 'mode', suitable for assigning to the `ObjectAttrs.Retention.Mode` property of the `storage.Writer`
+// instance used by gocloud.dev/blob/gcsblob writers. Mode names are case-insensitive. An error is returned if 'mode' is not
+// a known retention mode or 'retain_until' is not in the future.
+func GCSRetentionMode(mode string, retain_until time.Time) (string, error) {
+
+	switch strings.ToLower(mode) {
+	case "locked":
+		mode = GCS_RETENTION_MODE_LOCKED
+	case "unlocked":
+		mode = GCS_RETENTION_MODE_UNLOCKED
+	default:
+		return "", fmt.Errorf("Unsupported retention mode '%s'", mode)
+	}
+
+	if !retain_until.After(time.Now()) {
+		return "", fmt.Errorf("Retention period must end in the future")
+	}
+
+	return mode, nil
+}
@@ -0,0 +1,60 @@
+package atomicwrite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGCSStorageClass(t *testing.T) {
+
+	tests := map[string]string{
+		"nearline": GCS_STORAGE_CLASS_NEARLINE,
+		"ARCHIVE":  GCS_STORAGE_CLASS_ARCHIVE,
+	}
+
+	for class, expected := range tests {
+
+		v, err := GCSStorageClass(class)
+
+		if err != nil {
+			t.Fatalf("Failed to derive storage class for %s, %v", class, err)
+		}
+
+		if v != expected {
+			t.Fatalf("Unexpected storage class for %s: %s", class, v)
+		}
+	}
+
+	_, err := GCSStorageClass("GLACIER")
+
+	if err == nil {
+		t.Fatalf("Expected unsupported storage class to fail")
+	}
+}
+
+func TestGCSRetentionMode(t *testing.T) {
+
+	retain_until := time.Now().Add(time.Hour)
+
+	mode, err := GCSRetentionMode("locked", retain_until)
+
+	if err != nil {
+		t.Fatalf("Failed to derive retention mode, %v", err)
+	}
+
+	if mode != GCS_RETENTION_MODE_LOCKED {
+		t.Fatalf("Unexpected retention mode: %s", mode)
+	}
+
+	_, err = GCSRetentionMode("COMPLIANCE", retain_until)
+
+	if err == nil {
+		t.Fatalf("Expected unsupported retention mode to fail")
+	}
+
+	_, err = GCSRetentionMode(GCS_RETENTION_MODE_LOCKED, time.Now().Add(-time.Hour))
+
+	if err == nil {
+		t.Fatalf("Expected retention period in the past to fail")
+	}
+}
@@ -2,4 +2,15 @@ module github.com/sfomuseum/go-atomicwrite
 
 go 1.16
 
-require gocloud.dev v0.25.0
+require (
+	cloud.google.com/go/storage v1.21.0
+	github.com/aws/aws-sdk-go v1.43.31
+	github.com/prometheus/client_golang v1.12.2
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/metric v0.30.0
+	gocloud.dev v0.25.0
+	golang.org/x/sys v0.0.0-20220330033206-e17cdc41300f
+	google.golang.org/api v0.74.0
+	google.golang.org/grpc v1.45.0
+	google.golang.org/protobuf v1.28.0
+)
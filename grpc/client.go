@@ -0,0 +1,222 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"sync"
+)
+
+// CHUNK_SIZE is the maximum size, in bytes, of the chunks sent by GRPCWriter instances. It is well below the default 4MB maximum
+// message size for gRPC servers.
+const CHUNK_SIZE int = 1024 * 1024
+
+// type WriteChunkClient is the client side of a WriteChunk stream.
+type WriteChunkClient interface {
+	gogrpc.ClientStream
+	// Send sends 'req' to the server.
+	Send(req *WriteChunkRequest) error
+	// CloseAndRecv closes the stream and returns the server's response.
+	CloseAndRecv() (*WriteChunkResponse, error)
+}
+
+// type writeChunkClient implements the WriteChunkClient interface.
+type writeChunkClient struct {
+	gogrpc.ClientStream
+}
+
+func (c *writeChunkClient) Send(req *WriteChunkRequest) error {
+	return c.SendMsg(req)
+}
+
+func (c *writeChunkClient) CloseAndRecv() (*WriteChunkResponse, error) {
+
+	err := c.CloseSend()
+
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := new(WriteChunkResponse)
+
+	err = c.RecvMsg(rsp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// type GRPCWriter implements the io.WriteCloser interface for writing data atomically through an AtomicWriteService server.
+type GRPCWriter struct {
+	io.WriteCloser
+	// The context for the write
+	ctx context.Context
+	// The connection to the server
+	conn gogrpc.ClientConnInterface
+	// The ID of the write
+	write_id string
+	// The WriteChunk stream for the write
+	stream WriteChunkClient
+	// A mutex guarding stream and closed
+	mu sync.Mutex
+	// Whether the writer has been closed or aborted
+	closed bool
+}
+
+// NewGRPCWriter returns a new GRPCWriter instance which writes data to 'key' in the bucket 'bucket_uri' through the AtomicWriteService
+// server connected to by 'conn'. Data is streamed to the server as it is written and committed when the `Close` method is invoked.
+// 'ctx' is the context for the entire write; cancelling it aborts the write.
+func NewGRPCWriter(ctx context.Context, conn gogrpc.ClientConnInterface, bucket_uri string, key string) (io.WriteCloser, error) {
+
+	req := &BeginWriteRequest{
+		BucketURI: bucket_uri,
+		FinalKey:  key,
+	}
+
+	rsp := new(BeginWriteResponse)
+
+	err := invoke(ctx, conn, "BeginWrite", req, rsp)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to begin write, %w", err)
+	}
+
+	wr := &GRPCWriter{
+		ctx:      ctx,
+		conn:     conn,
+		write_id: rsp.WriteID,
+	}
+
+	cs, err := conn.NewStream(ctx, &service_desc.Streams[0], "/"+SERVICE_NAME+"/WriteChunk", gogrpc.ForceCodec(Codec{}))
+
+	if err != nil {
+		wr.abortWrite()
+		return nil, fmt.Errorf("Failed to open stream, %w", err)
+	}
+
+	wr.stream = &writeChunkClient{cs}
+
+	return wr, nil
+}
+
+// Write streams 'b' to the server, in chunks of at most CHUNK_SIZE bytes.
+func (wr *GRPCWriter) Write(b []byte) (int, error) {
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if wr.closed {
+		return 0, fmt.Errorf("Writer has been closed")
+	}
+
+	written := 0
+
+	for written < len(b) {
+
+		end := written + CHUNK_SIZE
+
+		if end > len(b) {
+			end = len(b)
+		}
+
+		req := &WriteChunkRequest{
+			WriteID: wr.write_id,
+			Data:    b[written:end],
+		}
+
+		err := wr.stream.Send(req)
+
+		if err == io.EOF {
+
+			// The server has closed the stream, the actual error is returned by RecvMsg
+			err = wr.stream.RecvMsg(new(WriteChunkResponse))
+
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+
+		if err != nil {
+			return written, fmt.Errorf("Failed to send chunk, %w", err)
+		}
+
+		written = end
+	}
+
+	return written, nil
+}
+
+// Close closes the stream and commits the data written to the server. Subsequent calls are no-ops.
+func (wr *GRPCWriter) Close() error {
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if wr.closed {
+		return nil
+	}
+
+	wr.closed = true
+
+	_, err := wr.stream.CloseAndRecv()
+
+	if err != nil {
+		wr.abortWrite()
+		return fmt.Errorf("Failed to close stream, %w", err)
+	}
+
+	req := &CommitWriteRequest{
+		WriteID: wr.write_id,
+	}
+
+	err = invoke(wr.ctx, wr.conn, "CommitWrite", req, new(CommitWriteResponse))
+
+	if err != nil {
+		return fmt.Errorf("Failed to commit write, %w", err)
+	}
+
+	return nil
+}
+
+// Abort closes the stream and discards the data written to the server. Subsequent calls, or calls after `Close`, are no-ops.
+func (wr *GRPCWriter) Abort() error {
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if wr.closed {
+		return nil
+	}
+
+	wr.closed = true
+
+	wr.stream.CloseSend()
+
+	return wr.abortWrite()
+}
+
+// abortWrite asks the server to abort the write. Writes which the server has already aborted are ignored.
+func (wr *GRPCWriter) abortWrite() error {
+
+	req := &AbortWriteRequest{
+		WriteID: wr.write_id,
+	}
+
+	err := invoke(wr.ctx, wr.conn, "AbortWrite", req, new(AbortWriteResponse))
+
+	if err != nil && status.Code(err) != codes.NotFound {
+		return fmt.Errorf("Failed to abort write, %w", err)
+	}
+
+	return nil
+}
+
+// invoke invokes the unary AtomicWriteService method 'method' with 'req', decoding the response in to 'rsp'.
+func invoke(ctx context.Context, conn gogrpc.ClientConnInterface, method string, req message, rsp message) error {
+	return conn.Invoke(ctx, "/"+SERVICE_NAME+"/"+method, req, rsp, gogrpc.ForceCodec(Codec{}))
+}
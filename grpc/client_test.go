@@ -0,0 +1,480 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/sfomuseum/go-atomicwrite"
+	_ "gocloud.dev/blob/fileblob"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestConn starts a gRPC server for 'srv' and returns a connection to it.
+func newTestConn(t *testing.T, srv *Server) *gogrpc.ClientConn {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("Failed to listen, %v", err)
+	}
+
+	s := gogrpc.NewServer(ServerOption())
+	RegisterAtomicWriteServiceServer(s, srv)
+
+	go s.Serve(l)
+
+	t.Cleanup(s.Stop)
+
+	conn, err := gogrpc.Dial(l.Addr().String(), gogrpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	if err != nil {
+		t.Fatalf("Failed to dial server, %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	return conn
+}
+
+func TestGRPCWriter(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	srv := NewServer(ctx, []string{bucket_uri})
+	conn := newTestConn(t, srv)
+
+	body := bytes.Repeat([]byte("Hello world"), CHUNK_SIZE/4)
+
+	wr, err := NewGRPCWriter(ctx, conn, bucket_uri, "a/atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write(body)
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	path := filepath.Join(root, "a", "atomicwrite.txt")
+
+	_, err = os.Stat(path)
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected %s not to exist before commit, %v", path, err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if !bytes.Equal(v, body) {
+		t.Fatalf("Unexpected body for %s", path)
+	}
+
+	if len(srv.writes) != 0 {
+		t.Fatalf("Expected no outstanding writes, got %d", len(srv.writes))
+	}
+}
+
+func TestGRPCWriterAbort(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	srv := NewServer(ctx, []string{bucket_uri})
+	conn := newTestConn(t, srv)
+
+	wr, err := NewGRPCWriter(ctx, conn, bucket_uri, "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte("Hello world"))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	err = wr.(*GRPCWriter).Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort writer, %v", err)
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no files after abort, got %d", len(entries))
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Expected close after abort to be a no-op, %v", err)
+	}
+}
+
+func TestGRPCWriterErrors(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	srv := NewServer(ctx, []string{bucket_uri}, atomicwrite.WithIfNotExists())
+	conn := newTestConn(t, srv)
+
+	_, err := NewGRPCWriter(ctx, conn, "file://"+t.TempDir(), "atomicwrite.txt")
+
+	if status.Code(errors.Unwrap(err)) != codes.PermissionDenied {
+		t.Fatalf("Expected PermissionDenied, got %v", err)
+	}
+
+	for _, key := range []string{"", "a/", "../atomicwrite.txt"} {
+
+		_, err := NewGRPCWriter(ctx, conn, bucket_uri, key)
+
+		if status.Code(errors.Unwrap(err)) != codes.InvalidArgument {
+			t.Fatalf("Expected InvalidArgument for %q, got %v", key, err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+
+		wr, err := NewGRPCWriter(ctx, conn, bucket_uri, "atomicwrite.txt")
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		_, err = wr.Write([]byte("Hello world"))
+
+		if err != nil {
+			t.Fatalf("Failed to write data, %v", err)
+		}
+
+		err = wr.Close()
+
+		switch i {
+		case 0:
+			if err != nil {
+				t.Fatalf("Failed to close writer, %v", err)
+			}
+		default:
+			if status.Code(errors.Unwrap(err)) != codes.FailedPrecondition {
+				t.Fatalf("Expected FailedPrecondition, got %v", err)
+			}
+		}
+	}
+}
+
+func TestServerClose(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	srv := NewServer(ctx, []string{bucket_uri})
+
+	req := &BeginWriteRequest{
+		BucketURI: bucket_uri,
+		FinalKey:  "atomicwrite.txt",
+	}
+
+	_, err := srv.BeginWrite(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to begin write, %v", err)
+	}
+
+	err = srv.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close server, %v", err)
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no files after close, got %d", len(entries))
+	}
+}
+
+// newTestStream opens a new WriteChunk stream on 'conn'.
+func newTestStream(t *testing.T, ctx context.Context, conn *gogrpc.ClientConn) WriteChunkClient {
+
+	cs, err := conn.NewStream(ctx, &service_desc.Streams[0], "/"+SERVICE_NAME+"/WriteChunk", gogrpc.ForceCodec(Codec{}))
+
+	if err != nil {
+		t.Fatalf("Failed to open stream, %v", err)
+	}
+
+	return &writeChunkClient{cs}
+}
+
+// waitFor waits for 'fn' to return true, failing the test if it does not within a few seconds.
+func waitFor(t *testing.T, fn func() bool) {
+
+	for i := 0; i < 200; i++ {
+
+		if fn() {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("Timed out waiting for condition")
+}
+
+func TestServerIdleTimeout(t *testing.T) {
+
+	default_timeout := idle_timeout
+	idle_timeout = 100 * time.Millisecond
+
+	defer func() {
+		idle_timeout = default_timeout
+	}()
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	srv := NewServer(ctx, []string{bucket_uri})
+	defer srv.Close()
+
+	conn := newTestConn(t, srv)
+
+	// A write which is never streamed to
+
+	req := &BeginWriteRequest{
+		BucketURI: bucket_uri,
+		FinalKey:  "atomicwrite.txt",
+	}
+
+	rsp, err := srv.BeginWrite(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to begin write, %v", err)
+	}
+
+	// A write whose stream stays open for longer than the idle timeout
+
+	wr, err := NewGRPCWriter(ctx, conn, bucket_uri, "b/atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte("Hello world"))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	waitFor(t, func() bool {
+		return srv.count() == 1
+	})
+
+	_, err = srv.CommitWrite(ctx, &CommitWriteRequest{WriteID: rsp.WriteID})
+
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected NotFound committing expired write, got %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	_, err = os.Stat(filepath.Join(root, "b", "atomicwrite.txt"))
+
+	if err != nil {
+		t.Fatalf("Failed to stat committed file, %v", err)
+	}
+
+	if srv.count() != 0 {
+		t.Fatalf("Expected no open writes, got %d", srv.count())
+	}
+}
+
+func TestServerMaxWrites(t *testing.T) {
+
+	default_max := max_writes
+	max_writes = 2
+
+	defer func() {
+		max_writes = default_max
+	}()
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	srv := NewServer(ctx, []string{bucket_uri})
+	defer srv.Close()
+
+	req := &BeginWriteRequest{
+		BucketURI: bucket_uri,
+		FinalKey:  "atomicwrite.txt",
+	}
+
+	ids := make([]string, 0)
+
+	for i := 0; i < 2; i++ {
+
+		rsp, err := srv.BeginWrite(ctx, req)
+
+		if err != nil {
+			t.Fatalf("Failed to begin write, %v", err)
+		}
+
+		ids = append(ids, rsp.WriteID)
+	}
+
+	_, err := srv.BeginWrite(ctx, req)
+
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v", err)
+	}
+
+	_, err = srv.AbortWrite(ctx, &AbortWriteRequest{WriteID: ids[0]})
+
+	if err != nil {
+		t.Fatalf("Failed to abort write, %v", err)
+	}
+
+	_, err = srv.BeginWrite(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to begin write after abort, %v", err)
+	}
+}
+
+func TestServerConcurrentStreams(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	srv := NewServer(ctx, []string{bucket_uri})
+	defer srv.Close()
+
+	conn := newTestConn(t, srv)
+
+	req := &BeginWriteRequest{
+		BucketURI: bucket_uri,
+		FinalKey:  "atomicwrite.txt",
+	}
+
+	rsp, err := srv.BeginWrite(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to begin write, %v", err)
+	}
+
+	id := rsp.WriteID
+
+	first := newTestStream(t, ctx, conn)
+
+	err = first.Send(&WriteChunkRequest{WriteID: id, Data: []byte("Hello")})
+
+	if err != nil {
+		t.Fatalf("Failed to send chunk, %v", err)
+	}
+
+	waitFor(t, func() bool {
+
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+
+		return srv.writes[id].streaming
+	})
+
+	second := newTestStream(t, ctx, conn)
+
+	err = second.Send(&WriteChunkRequest{WriteID: id, Data: []byte("Goodbye")})
+
+	if err != nil {
+		t.Fatalf("Failed to send chunk, %v", err)
+	}
+
+	_, err = second.CloseAndRecv()
+
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition for second stream, got %v", err)
+	}
+
+	_, err = srv.CommitWrite(ctx, &CommitWriteRequest{WriteID: id})
+
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition committing while streaming, got %v", err)
+	}
+
+	err = first.Send(&WriteChunkRequest{WriteID: id, Data: []byte(" world")})
+
+	if err != nil {
+		t.Fatalf("Failed to send chunk, %v", err)
+	}
+
+	_, err = first.CloseAndRecv()
+
+	if err != nil {
+		t.Fatalf("Failed to close stream, %v", err)
+	}
+
+	_, err = srv.CommitWrite(ctx, &CommitWriteRequest{WriteID: id})
+
+	if err != nil {
+		t.Fatalf("Failed to commit write, %v", err)
+	}
+
+	body, err := os.ReadFile(filepath.Join(root, "atomicwrite.txt"))
+
+	if err != nil {
+		t.Fatalf("Failed to read committed file, %v", err)
+	}
+
+	if string(body) != "Hello world" {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+}
@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/proto"
+)
+
+// default_codec is the default gRPC codec, registered by the google.golang.org/grpc/encoding/proto package. It is resolved when this
+// package is initialized so that it is not replaced if Codec is itself registered using `encoding.RegisterCodec`.
+var default_codec = encoding.GetCodec("proto")
+
+// type Codec implements the `encoding.Codec` interface for the messages defined in atomicwrite.proto. Since those messages are
+// encoded by hand, rather than with generated code, the default gRPC codec can not encode them. All other values are encoded using
+// the default gRPC codec so it is safe to use Codec with servers which also host other services.
+type Codec struct{}
+
+// Name returns "proto" since messages are encoded using the protobuf wire format.
+func (c Codec) Name() string {
+	return "proto"
+}
+
+// Marshal returns the wire encoding of 'v'.
+func (c Codec) Marshal(v interface{}) ([]byte, error) {
+
+	m, ok := v.(message)
+
+	if ok {
+		return m.marshal(), nil
+	}
+
+	return default_codec.Marshal(v)
+}
+
+// Unmarshal decodes 'data' in to 'v'.
+func (c Codec) Unmarshal(data []byte, v interface{}) error {
+
+	m, ok := v.(message)
+
+	if ok {
+		return m.unmarshal(data)
+	}
+
+	return default_codec.Unmarshal(data, v)
+}
+
+// ServerOption returns a `grpc.ServerOption` which configures a gRPC server to use Codec. It must be passed to the `grpc.NewServer`
+// constructor for servers which register a Server instance.
+func ServerOption() gogrpc.ServerOption {
+	return gogrpc.ForceServerCodec(Codec{})
+}
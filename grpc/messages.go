@@ -0,0 +1,254 @@
+package grpc
+
+import (
+	"fmt"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// type message is the interface implemented by the request and response messages defined in atomicwrite.proto. Messages are
+// encoded by hand, using the protowire package, so that they are wire-compatible with code generated from atomicwrite.proto.
+type message interface {
+	// marshal returns the protobuf wire encoding of the message.
+	marshal() []byte
+	// unmarshal replaces the contents of the message with the protobuf wire encoding 'b'.
+	unmarshal(b []byte) error
+}
+
+// type BeginWriteRequest defines a request to start a new write.
+type BeginWriteRequest struct {
+	// The URI of the bucket to write to
+	BucketURI string
+	// The key, relative to the bucket, to commit data to
+	FinalKey string
+}
+
+func (m *BeginWriteRequest) marshal() []byte {
+	b := appendString(nil, 1, m.BucketURI)
+	return appendString(b, 2, m.FinalKey)
+}
+
+func (m *BeginWriteRequest) unmarshal(b []byte) error {
+
+	*m = BeginWriteRequest{}
+
+	return consumeFields(b, func(num protowire.Number, v []byte, x uint64) {
+
+		switch num {
+		case 1:
+			m.BucketURI = string(v)
+		case 2:
+			m.FinalKey = string(v)
+		}
+	})
+}
+
+// type BeginWriteResponse defines the response to a BeginWriteRequest.
+type BeginWriteResponse struct {
+	// The ID of the new write
+	WriteID string
+}
+
+func (m *BeginWriteResponse) marshal() []byte {
+	return appendString(nil, 1, m.WriteID)
+}
+
+func (m *BeginWriteResponse) unmarshal(b []byte) error {
+
+	*m = BeginWriteResponse{}
+
+	return consumeFields(b, func(num protowire.Number, v []byte, x uint64) {
+
+		if num == 1 {
+			m.WriteID = string(v)
+		}
+	})
+}
+
+// type WriteChunkRequest defines a chunk of data streamed to a write.
+type WriteChunkRequest struct {
+	// The ID of the write
+	WriteID string
+	// The data to write
+	Data []byte
+}
+
+func (m *WriteChunkRequest) marshal() []byte {
+	b := appendString(nil, 1, m.WriteID)
+	return appendBytes(b, 2, m.Data)
+}
+
+func (m *WriteChunkRequest) unmarshal(b []byte) error {
+
+	*m = WriteChunkRequest{}
+
+	return consumeFields(b, func(num protowire.Number, v []byte, x uint64) {
+
+		switch num {
+		case 1:
+			m.WriteID = string(v)
+		case 2:
+			m.Data = append([]byte(nil), v...)
+		}
+	})
+}
+
+// type WriteChunkResponse defines the response to a stream of WriteChunkRequest messages.
+type WriteChunkResponse struct {
+	// The number of bytes written by the stream
+	BytesWritten uint64
+}
+
+func (m *WriteChunkResponse) marshal() []byte {
+	return appendVarint(nil, 1, m.BytesWritten)
+}
+
+func (m *WriteChunkResponse) unmarshal(b []byte) error {
+
+	*m = WriteChunkResponse{}
+
+	return consumeFields(b, func(num protowire.Number, v []byte, x uint64) {
+
+		if num == 1 {
+			m.BytesWritten = x
+		}
+	})
+}
+
+// type CommitWriteRequest defines a request to commit a write.
+type CommitWriteRequest struct {
+	// The ID of the write
+	WriteID string
+}
+
+func (m *CommitWriteRequest) marshal() []byte {
+	return appendString(nil, 1, m.WriteID)
+}
+
+func (m *CommitWriteRequest) unmarshal(b []byte) error {
+
+	*m = CommitWriteRequest{}
+
+	return consumeFields(b, func(num protowire.Number, v []byte, x uint64) {
+
+		if num == 1 {
+			m.WriteID = string(v)
+		}
+	})
+}
+
+// type CommitWriteResponse defines the response to a CommitWriteRequest.
+type CommitWriteResponse struct{}
+
+func (m *CommitWriteResponse) marshal() []byte {
+	return nil
+}
+
+func (m *CommitWriteResponse) unmarshal(b []byte) error {
+	return consumeFields(b, nil)
+}
+
+// type AbortWriteRequest defines a request to abort a write.
+type AbortWriteRequest struct {
+	// The ID of the write
+	WriteID string
+}
+
+func (m *AbortWriteRequest) marshal() []byte {
+	return appendString(nil, 1, m.WriteID)
+}
+
+func (m *AbortWriteRequest) unmarshal(b []byte) error {
+
+	*m = AbortWriteRequest{}
+
+	return consumeFields(b, func(num protowire.Number, v []byte, x uint64) {
+
+		if num == 1 {
+			m.WriteID = string(v)
+		}
+	})
+}
+
+// type AbortWriteResponse defines the response to an AbortWriteRequest.
+type AbortWriteResponse struct{}
+
+func (m *AbortWriteResponse) marshal() []byte {
+	return nil
+}
+
+func (m *AbortWriteResponse) unmarshal(b []byte) error {
+	return consumeFields(b, nil)
+}
+
+// appendString appends 's', as field 'num', to 'b'. Empty strings are omitted, as with proto3 generated code.
+func appendString(b []byte, num protowire.Number, s string) []byte {
+
+	if s == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// appendBytes appends 'v', as field 'num', to 'b'. Empty values are omitted, as with proto3 generated code.
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+
+	if len(v) == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// appendVarint appends 'x', as field 'num', to 'b'. Zero values are omitted, as with proto3 generated code.
+func appendVarint(b []byte, num protowire.Number, x uint64) []byte {
+
+	if x == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, x)
+}
+
+// consumeFields parses the protobuf wire encoding 'b' invoking 'fn' with the value of each length-delimited ('v') or varint ('x')
+// field. Fields of any other type are skipped.
+func consumeFields(b []byte, fn func(num protowire.Number, v []byte, x uint64)) error {
+
+	for len(b) > 0 {
+
+		num, typ, n := protowire.ConsumeTag(b)
+
+		if n < 0 {
+			return fmt.Errorf("Failed to parse tag, %w", protowire.ParseError(n))
+		}
+
+		b = b[n:]
+
+		var v []byte
+		var x uint64
+
+		switch typ {
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			x, n = protowire.ConsumeVarint(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+
+		if n < 0 {
+			return fmt.Errorf("Failed to parse field %d, %w", num, protowire.ParseError(n))
+		}
+
+		b = b[n:]
+
+		if fn != nil && (typ == protowire.BytesType || typ == protowire.VarintType) {
+			fn(num, v, x)
+		}
+	}
+
+	return nil
+}
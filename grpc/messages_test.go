@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"bytes"
+	"google.golang.org/protobuf/encoding/protowire"
+	"testing"
+)
+
+func TestMessages(t *testing.T) {
+
+	req := &WriteChunkRequest{
+		WriteID: "abc",
+		Data:    []byte("Hello world"),
+	}
+
+	b := req.marshal()
+
+	// Unknown fields, as might be added to atomicwrite.proto in the future, are skipped
+	b = protowire.AppendTag(b, 99, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, 1)
+
+	v := new(WriteChunkRequest)
+
+	err := v.unmarshal(b)
+
+	if err != nil {
+		t.Fatalf("Failed to unmarshal request, %v", err)
+	}
+
+	if v.WriteID != req.WriteID || !bytes.Equal(v.Data, req.Data) {
+		t.Fatalf("Unexpected request: %v", v)
+	}
+
+	rsp := &WriteChunkResponse{
+		BytesWritten: 1234,
+	}
+
+	r := new(WriteChunkResponse)
+
+	err = r.unmarshal(rsp.marshal())
+
+	if err != nil {
+		t.Fatalf("Failed to unmarshal response, %v", err)
+	}
+
+	if r.BytesWritten != rsp.BytesWritten {
+		t.Fatalf("Unexpected response: %v", r)
+	}
+
+	err = v.unmarshal([]byte{0x0a, 0x10})
+
+	if err == nil {
+		t.Fatalf("Expected truncated message to fail")
+	}
+
+	if len(new(CommitWriteResponse).marshal()) != 0 {
+		t.Fatalf("Expected empty response to have an empty encoding")
+	}
+}
@@ -0,0 +1,558 @@
+// package grpc implements a gRPC service, defined in atomicwrite.proto, which allows processes without direct access to a
+// gocloud.dev/blob bucket to write data atomically through a sidecar service, and a client for that service.
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/sfomuseum/go-atomicwrite"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SERVICE_NAME is the fully-qualified name of the AtomicWriteService defined in atomicwrite.proto.
+const SERVICE_NAME string = "atomicwrite.AtomicWriteService"
+
+// IDLE_TIMEOUT is the maximum time a write which has been started may go without a WriteChunk stream before it is aborted.
+const IDLE_TIMEOUT time.Duration = 5 * time.Minute
+
+// MAX_WRITES is the maximum number of writes which may be started but not committed or aborted at any one time.
+const MAX_WRITES int = 1024
+
+var idle_timeout = IDLE_TIMEOUT
+
+var max_writes = MAX_WRITES
+
+// type AtomicWriteServiceServer is the interface implemented by servers for the AtomicWriteService defined in atomicwrite.proto.
+type AtomicWriteServiceServer interface {
+	// BeginWrite starts a new write.
+	BeginWrite(ctx context.Context, req *BeginWriteRequest) (*BeginWriteResponse, error)
+	// WriteChunk writes the data streamed by the client to a write.
+	WriteChunk(stream WriteChunkServer) error
+	// CommitWrite commits a write.
+	CommitWrite(ctx context.Context, req *CommitWriteRequest) (*CommitWriteResponse, error)
+	// AbortWrite aborts a write.
+	AbortWrite(ctx context.Context, req *AbortWriteRequest) (*AbortWriteResponse, error)
+}
+
+// type WriteChunkServer is the server side of a WriteChunk stream.
+type WriteChunkServer interface {
+	gogrpc.ServerStream
+	// Recv returns the next chunk streamed by the client.
+	Recv() (*WriteChunkRequest, error)
+	// SendAndClose sends 'rsp' to the client and closes the stream.
+	SendAndClose(rsp *WriteChunkResponse) error
+}
+
+// type writeChunkServer implements the WriteChunkServer interface.
+type writeChunkServer struct {
+	gogrpc.ServerStream
+}
+
+func (s *writeChunkServer) Recv() (*WriteChunkRequest, error) {
+
+	req := new(WriteChunkRequest)
+
+	err := s.RecvMsg(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func (s *writeChunkServer) SendAndClose(rsp *WriteChunkResponse) error {
+	return s.SendMsg(rsp)
+}
+
+// service_desc is the `grpc.ServiceDesc` for the AtomicWriteService defined in atomicwrite.proto.
+var service_desc = gogrpc.ServiceDesc{
+	ServiceName: SERVICE_NAME,
+	HandlerType: (*AtomicWriteServiceServer)(nil),
+	Methods: []gogrpc.MethodDesc{
+		{
+			MethodName: "BeginWrite",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor gogrpc.UnaryServerInterceptor) (interface{}, error) {
+
+				req := new(BeginWriteRequest)
+
+				err := dec(req)
+
+				if err != nil {
+					return nil, err
+				}
+
+				fn := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(AtomicWriteServiceServer).BeginWrite(ctx, req.(*BeginWriteRequest))
+				}
+
+				return intercept(ctx, srv, "BeginWrite", req, interceptor, fn)
+			},
+		},
+		{
+			MethodName: "CommitWrite",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor gogrpc.UnaryServerInterceptor) (interface{}, error) {
+
+				req := new(CommitWriteRequest)
+
+				err := dec(req)
+
+				if err != nil {
+					return nil, err
+				}
+
+				fn := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(AtomicWriteServiceServer).CommitWrite(ctx, req.(*CommitWriteRequest))
+				}
+
+				return intercept(ctx, srv, "CommitWrite", req, interceptor, fn)
+			},
+		},
+		{
+			MethodName: "AbortWrite",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor gogrpc.UnaryServerInterceptor) (interface{}, error) {
+
+				req := new(AbortWriteRequest)
+
+				err := dec(req)
+
+				if err != nil {
+					return nil, err
+				}
+
+				fn := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(AtomicWriteServiceServer).AbortWrite(ctx, req.(*AbortWriteRequest))
+				}
+
+				return intercept(ctx, srv, "AbortWrite", req, interceptor, fn)
+			},
+		},
+	},
+	Streams: []gogrpc.StreamDesc{
+		{
+			StreamName: "WriteChunk",
+			Handler: func(srv interface{}, stream gogrpc.ServerStream) error {
+				return srv.(AtomicWriteServiceServer).WriteChunk(&writeChunkServer{stream})
+			},
+			ClientStreams: true,
+		},
+	},
+	Metadata: "atomicwrite.proto",
+}
+
+// intercept invokes 'fn' with 'req', using 'interceptor' if it is not nil.
+func intercept(ctx context.Context, srv interface{}, method string, req interface{}, interceptor gogrpc.UnaryServerInterceptor, fn gogrpc.UnaryHandler) (interface{}, error) {
+
+	if interceptor == nil {
+		return fn(ctx, req)
+	}
+
+	info := &gogrpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + SERVICE_NAME + "/" + method,
+	}
+
+	return interceptor(ctx, req, info, fn)
+}
+
+// RegisterAtomicWriteServiceServer registers 'srv' with the gRPC server 's'. 's' must have been created with the option returned by
+// the `ServerOption` method.
+func RegisterAtomicWriteServiceServer(s gogrpc.ServiceRegistrar, srv AtomicWriteServiceServer) {
+	s.RegisterService(&service_desc, srv)
+}
+
+// type Server implements the AtomicWriteServiceServer interface writing data using `atomicwrite.DeferredWriter` instances.
+type Server struct {
+	AtomicWriteServiceServer
+	// The context for each writer. Writes outlive the individual requests which create them.
+	ctx context.Context
+	// The bucket URIs which clients may write to
+	buckets map[string]bool
+	// The options for each writer
+	opts []atomicwrite.Option
+	// A mutex guarding writes
+	mu sync.Mutex
+	// The writes which have been started but not committed or aborted, keyed by their ID
+	writes map[string]*write
+}
+
+// type write defines a write which has been started but not committed or aborted.
+type write struct {
+	// The writer for the write
+	writer *atomicwrite.DeferredWriter
+	// The key, relative to the bucket, to commit data to
+	key string
+	// Whether a WriteChunk stream is currently writing to the write
+	streaming bool
+	// The time after which the write is aborted if no WriteChunk stream is writing to it
+	deadline time.Time
+	// The timer which aborts the write once its deadline has passed
+	timer *time.Timer
+}
+
+// NewServer returns a new Server instance which allows clients to write to the buckets defined by 'bucket_uris' (and only those
+// buckets). 'ctx' is the context for each writer and should not be cancelled until the server has stopped. 'opts' are applied to
+// each writer, as with the `atomicwrite.New` constructor. Writes whose WriteChunk stream fails are aborted. Writes which go without
+// a WriteChunk stream for IDLE_TIMEOUT, for example because the client disappeared, are aborted and any remaining writes are aborted
+// by the `Close` method. At most MAX_WRITES writes may be started but not committed or aborted at any one time.
+func NewServer(ctx context.Context, bucket_uris []string, opts ...atomicwrite.Option) *Server {
+
+	buckets := make(map[string]bool)
+
+	for _, uri := range bucket_uris {
+		buckets[uri] = true
+	}
+
+	s := &Server{
+		ctx:     ctx,
+		buckets: buckets,
+		opts:    opts,
+		writes:  make(map[string]*write),
+	}
+
+	return s
+}
+
+// BeginWrite starts a new write to 'req.FinalKey' in the bucket 'req.BucketURI' and returns its ID. If MAX_WRITES writes have
+// already been started, and not committed or aborted, a ResourceExhausted error is returned.
+func (s *Server) BeginWrite(ctx context.Context, req *BeginWriteRequest) (*BeginWriteResponse, error) {
+
+	if !s.buckets[req.BucketURI] {
+		return nil, status.Errorf(codes.PermissionDenied, "Bucket %s is not allowed", req.BucketURI)
+	}
+
+	if !atomicwrite.IsValidKey(req.FinalKey) {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid key")
+	}
+
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	id := hex.EncodeToString(b)
+
+	if s.count() >= max_writes {
+		return nil, status.Errorf(codes.ResourceExhausted, "Too many open writes")
+	}
+
+	wr, err := atomicwrite.NewDeferred(s.ctx, req.BucketURI, s.opts...)
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	s.mu.Lock()
+
+	// Check again in case other writes were started while this writer was being created
+
+	if len(s.writes) >= max_writes {
+
+		s.mu.Unlock()
+
+		err := wr.Abort()
+
+		if err != nil {
+			log.Printf("Failed to abort write %s, %v", id, err)
+		}
+
+		return nil, status.Errorf(codes.ResourceExhausted, "Too many open writes")
+	}
+
+	s.writes[id] = &write{
+		writer:   wr,
+		key:      req.FinalKey,
+		deadline: time.Now().Add(idle_timeout),
+		timer:    time.AfterFunc(idle_timeout, func() { s.expire(id) }),
+	}
+
+	s.mu.Unlock()
+
+	rsp := &BeginWriteResponse{
+		WriteID: id,
+	}
+
+	return rsp, nil
+}
+
+// WriteChunk writes each chunk streamed by the client to its write. Every chunk in a stream must have the same write ID. If the
+// stream fails the write is aborted. Only one stream may write to a write at a time; if a second stream is started for a write
+// which is already being streamed to it is rejected with a FailedPrecondition error.
+func (s *Server) WriteChunk(stream WriteChunkServer) error {
+
+	var w *write
+	var id string
+	var written uint64
+
+	for {
+
+		req, err := stream.Recv()
+
+		if err == io.EOF {
+
+			if w != nil {
+				s.release(id)
+			}
+
+			return stream.SendAndClose(&WriteChunkResponse{BytesWritten: written})
+		}
+
+		if err != nil {
+
+			if w != nil {
+				s.abort(id)
+			}
+
+			return err
+		}
+
+		if w == nil {
+
+			id = req.WriteID
+
+			claimed, err := s.claim(id)
+
+			if err != nil {
+				return err
+			}
+
+			w = claimed
+
+		} else if req.WriteID != id {
+			s.abort(id)
+			return status.Errorf(codes.InvalidArgument, "Chunk has a different write ID")
+		}
+
+		n, err := w.writer.Write(req.Data)
+		written += uint64(n)
+
+		if err != nil {
+			s.abort(id)
+			return statusError(err)
+		}
+	}
+}
+
+// CommitWrite commits the data written for the write 'req.WriteID'. Writes which are still being streamed to can not be committed.
+func (s *Server) CommitWrite(ctx context.Context, req *CommitWriteRequest) (*CommitWriteResponse, error) {
+
+	w, err := s.popIdle(req.WriteID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.writer.Close(w.key)
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &CommitWriteResponse{}, nil
+}
+
+// AbortWrite discards the data written for the write 'req.WriteID'.
+func (s *Server) AbortWrite(ctx context.Context, req *AbortWriteRequest) (*AbortWriteResponse, error) {
+
+	w := s.pop(req.WriteID)
+
+	if w == nil {
+		return nil, status.Errorf(codes.NotFound, "Write not found")
+	}
+
+	err := w.writer.Abort()
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &AbortWriteResponse{}, nil
+}
+
+// Close aborts all the writes which have been started but not committed or aborted.
+func (s *Server) Close() error {
+
+	s.mu.Lock()
+
+	writes := s.writes
+	s.writes = make(map[string]*write)
+
+	s.mu.Unlock()
+
+	errs := make([]string, 0)
+
+	for id, w := range writes {
+
+		w.timer.Stop()
+
+		err := w.writer.Abort()
+
+		if err != nil {
+			errs = append(errs, id+": "+err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New("Failed to abort writes, " + strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// count returns the number of writes which have been started but not committed or aborted.
+func (s *Server) count() int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.writes)
+}
+
+// claim returns the write with ID 'id' and marks it as being streamed to. It returns a NotFound error if the write does not exist
+// and a FailedPrecondition error if it is already being streamed to.
+func (s *Server) claim(id string) (*write, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.writes[id]
+
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "Write not found")
+	}
+
+	if w.streaming {
+		return nil, status.Errorf(codes.FailedPrecondition, "Write is already being streamed to")
+	}
+
+	w.streaming = true
+	w.timer.Stop()
+
+	return w, nil
+}
+
+// release marks the write with ID 'id' as no longer being streamed to and restarts its idle timeout.
+func (s *Server) release(id string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.writes[id]
+
+	if !ok {
+		return
+	}
+
+	w.streaming = false
+	w.deadline = time.Now().Add(idle_timeout)
+	w.timer.Reset(idle_timeout)
+}
+
+// popIdle returns and removes the write with ID 'id'. It returns a NotFound error if the write does not exist and a
+// FailedPrecondition error if it is being streamed to.
+func (s *Server) popIdle(id string) (*write, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.writes[id]
+
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "Write not found")
+	}
+
+	if w.streaming {
+		return nil, status.Errorf(codes.FailedPrecondition, "Write is being streamed to")
+	}
+
+	w.timer.Stop()
+	delete(s.writes, id)
+
+	return w, nil
+}
+
+// pop returns and removes the write with ID 'id', or nil if it does not exist.
+func (s *Server) pop(id string) *write {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.writes[id]
+
+	if ok {
+		w.timer.Stop()
+		delete(s.writes, id)
+	}
+
+	return w
+}
+
+// expire aborts and removes the write with ID 'id' if it is not being streamed to and its deadline has passed.
+func (s *Server) expire(id string) {
+
+	s.mu.Lock()
+
+	w, ok := s.writes[id]
+
+	// The deadline is checked because the timer may have fired while the write was being claimed or released
+
+	if !ok || w.streaming || time.Now().Before(w.deadline) {
+		s.mu.Unlock()
+		return
+	}
+
+	delete(s.writes, id)
+
+	s.mu.Unlock()
+
+	err := w.writer.Abort()
+
+	if err != nil {
+		log.Printf("Failed to abort write %s, %v", id, err)
+	}
+}
+
+// abort aborts and removes the write with ID 'id', logging any errors.
+func (s *Server) abort(id string) {
+
+	w := s.pop(id)
+
+	if w == nil {
+		return
+	}
+
+	err := w.writer.Abort()
+
+	if err != nil {
+		log.Printf("Failed to abort write %s, %v", id, err)
+	}
+}
+
+// statusError returns the gRPC status error for 'err'. Errors which are not mapped to a specific status code are logged and
+// returned as a generic internal error so that server details are not disclosed to clients.
+func statusError(err error) error {
+
+	switch {
+	case errors.Is(err, atomicwrite.ErrPreconditionFailed):
+		return status.Error(codes.FailedPrecondition, "Precondition failed")
+	case errors.Is(err, atomicwrite.ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, "Quota exceeded")
+	case errors.Is(err, atomicwrite.ErrAborted):
+		return status.Error(codes.Aborted, "Write has been aborted")
+	default:
+		log.Printf("Failed to write, %v", err)
+		return status.Error(codes.Internal, "Internal error")
+	}
+}
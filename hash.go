@@ -0,0 +1,94 @@
+package atomicwrite
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"path"
+)
+
+// hash_algorithms maps the names of the hashing algorithms supported by the `WriteAndHash` method to
+// functions returning a new hash.Hash instance for that algorithm.
+var hash_algorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// WriteAndHash writes 'data' to 'uri' and a sidecar file containing the hash of 'data', computed using 'algo', to
+// 'uri' + "." + 'algo' (for example "example.txt.sha256"). The sidecar file is formatted the same way as the output
+// of the `sha256sum` (or equivalent) tool so it can be verified using `sha256sum -c`. Supported algorithms are: md5,
+// sha1, sha256 and sha512. Both files are staged in intermediate temporary files before either is committed and if
+// either cannot be staged then neither is committed. The data file is committed before the sidecar file.
+func WriteAndHash(ctx context.Context, uri string, data []byte, algo string, opts ...Option) error {
+
+	new_hash, ok := hash_algorithms[algo]
+
+	if !ok {
+		return fmt.Errorf("Unsupported hashing algorithm '%s'", algo)
+	}
+
+	h := new_hash()
+	h.Write(data)
+
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	hash_uri, err := sidecarURI(uri, "."+algo)
+
+	if err != nil {
+		return fmt.Errorf("Failed to derive hash URI, %w", err)
+	}
+
+	data_wr, err := newAtomicWriter(ctx, uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create atomic writer for data, %w", err)
+	}
+
+	hash_wr, err := newAtomicWriter(ctx, hash_uri, opts...)
+
+	if err != nil {
+		data_wr.Abort()
+		return fmt.Errorf("Failed to create atomic writer for hash, %w", err)
+	}
+
+	abort := func() {
+		data_wr.Abort()
+		hash_wr.Abort()
+	}
+
+	_, err = data_wr.Write(data)
+
+	if err != nil {
+		abort()
+		return fmt.Errorf("Failed to write data, %w", err)
+	}
+
+	_, err = fmt.Fprintf(hash_wr, "%s  %s\n", sum, path.Base(data_wr.final_path))
+
+	if err != nil {
+		abort()
+		return fmt.Errorf("Failed to write hash, %w", err)
+	}
+
+	err = data_wr.Close()
+
+	if err != nil {
+		hash_wr.Abort()
+		return fmt.Errorf("Failed to commit data, %w", err)
+	}
+
+	err = hash_wr.Close()
+
+	if err != nil {
+		return fmt.Errorf("Data was written to %s but failed to commit hash, %w", uri, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package atomicwrite
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAndHash(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	err := WriteAndHash(ctx, path, []byte(HELLO_WORLD), "sha256")
+
+	if err != nil {
+		t.Fatalf("Failed to write and hash data, %v", err)
+	}
+
+	body, err := os.ReadFile(path + ".sha256")
+
+	if err != nil {
+		t.Fatalf("Failed to read hash file, %v", err)
+	}
+
+	sum := sha256.Sum256([]byte(HELLO_WORLD))
+	expected := fmt.Sprintf("%s  atomicwrite.txt\n", hex.EncodeToString(sum[:]))
+
+	if string(body) != expected {
+		t.Fatalf("Unexpected hash file contents: %s", string(body))
+	}
+
+	err = WriteAndHash(ctx, path, []byte(HELLO_WORLD), "crc32")
+
+	if err == nil {
+		t.Fatalf("Expected unsupported algorithm to fail")
+	}
+}
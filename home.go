@@ -0,0 +1,52 @@
+package atomicwrite
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// expandHome returns 'path' with a leading "~/" replaced by the current user's home directory, or a leading "~username/" replaced
+// by that user's home directory, as with most shells. Paths which do not start with "~", or which start with "~" but contain no path
+// separator (for example "~settings.json", a file in the current working directory), are returned unchanged.
+func expandHome(path string) (string, error) {
+
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+
+	idx := strings.IndexAny(path, "/"+string(filepath.Separator))
+
+	if idx == -1 {
+		return path, nil
+	}
+
+	username := path[1:idx]
+
+	var home string
+
+	if username == "" {
+
+		dir, err := os.UserHomeDir()
+
+		if err != nil {
+			return "", fmt.Errorf("Failed to derive home directory, %w", err)
+		}
+
+		home = dir
+
+	} else {
+
+		u, err := user.Lookup(username)
+
+		if err != nil {
+			return "", fmt.Errorf("Failed to derive home directory for %s, %w", username, err)
+		}
+
+		home = u.HomeDir
+	}
+
+	return filepath.Join(home, path[idx+1:]), nil
+}
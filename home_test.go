@@ -0,0 +1,60 @@
+package atomicwrite
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandHome(t *testing.T) {
+
+	home, err := os.UserHomeDir()
+
+	if err != nil {
+		t.Skipf("Unable to derive home directory, %v", err)
+	}
+
+	cwd, err := os.Getwd()
+
+	if err != nil {
+		t.Fatalf("Failed to derive current working directory, %v", err)
+	}
+
+	tests := map[string][2]string{
+		"~/config/settings.json": {fmt.Sprintf("file://%s", filepath.Join(home, "config")), "settings.json"},
+		"~/settings.json":        {fmt.Sprintf("file://%s", home), "settings.json"},
+		"~settings.json":         {fmt.Sprintf("file://%s", cwd), "~settings.json"},
+		"config/~/settings.json": {fmt.Sprintf("file://%s", filepath.Join(cwd, "config", "~")), "settings.json"},
+	}
+
+	u, err := user.Current()
+
+	if err == nil && u.Username != "" && u.HomeDir != "" {
+		tests[fmt.Sprintf("~%s/config/settings.json", u.Username)] = [2]string{fmt.Sprintf("file://%s", filepath.Join(u.HomeDir, "config")), "settings.json"}
+	}
+
+	for uri, expected := range tests {
+
+		bucket_uri, key, err := parseURI(uri)
+
+		if err != nil {
+			t.Fatalf("Failed to parse %s, %v", uri, err)
+		}
+
+		if bucket_uri != expected[0] {
+			t.Fatalf("Unexpected bucket URI for %s: %s", uri, bucket_uri)
+		}
+
+		if key != expected[1] {
+			t.Fatalf("Unexpected key for %s: %s", uri, key)
+		}
+	}
+
+	_, _, err = parseURI("~atomicwrite-no-such-user/settings.json")
+
+	if err == nil {
+		t.Fatalf("Expected unknown user to fail")
+	}
+}
@@ -0,0 +1,109 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HTTP_STATUS_METADATA_KEY is the metadata key used to store the HTTP status code for data written using
+// the `NewHTTPResponseWriter` constructor.
+const HTTP_STATUS_METADATA_KEY string = "http-status"
+
+// HTTP_HEADER_METADATA_PREFIX is the prefix for metadata keys used to store HTTP headers for data written using
+// the `NewHTTPResponseWriter` constructor. For example the "X-Request-Id" header is stored as "http-header-x-request-id".
+const HTTP_HEADER_METADATA_PREFIX string = "http-header-"
+
+// type httpResponseWriter implements the `http.ResponseWriter` and `io.Closer` interfaces for an AtomicWriter instance.
+type httpResponseWriter struct {
+	aw           *AtomicWriter
+	header       http.Header
+	status       int
+	wrote_header bool
+}
+
+// NewHTTPResponseWriter returns a new `http.ResponseWriter` instance which writes the response body to 'uri' using an
+// AtomicWriter instance. 'status' is the default HTTP status code and 'header' the (mutable) headers returned by the
+// `Header` method. The returned instance also implements `io.Closer`; when its `Close` method is invoked the response
+// body is committed and the final status code and headers are stored as metadata for the final path. This is useful
+// for caching HTTP responses and for test stubs.
+func NewHTTPResponseWriter(ctx context.Context, uri string, status int, header http.Header, opts ...Option) (http.ResponseWriter, error) {
+
+	aw, err := newAtomicWriter(ctx, uri, opts...)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create atomic writer, %w", err)
+	}
+
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	w := &httpResponseWriter{
+		aw:     aw,
+		header: header,
+		status: status,
+	}
+
+	return w, nil
+}
+
+// Header returns the headers that will be stored as metadata for the final path.
+func (w *httpResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader records 'code' as the HTTP status code. As with `net/http` only the first call has any effect and
+// it has no effect once the `Write` method has been invoked.
+func (w *httpResponseWriter) WriteHeader(code int) {
+
+	if w.wrote_header {
+		return
+	}
+
+	w.status = code
+	w.wrote_header = true
+}
+
+// Write writes 'b' to the underlying AtomicWriter instance.
+func (w *httpResponseWriter) Write(b []byte) (int, error) {
+	w.wrote_header = true
+	return w.aw.Write(b)
+}
+
+// Close stores the HTTP status code and headers as metadata for the final path and commits the underlying
+// AtomicWriter instance.
+func (w *httpResponseWriter) Close() error {
+
+	writer_opts := &blob.WriterOptions{}
+
+	if w.aw.final_opts != nil {
+		*writer_opts = *w.aw.final_opts
+	}
+
+	metadata := make(map[string]string)
+
+	for k, v := range writer_opts.Metadata {
+		metadata[k] = v
+	}
+
+	for k, v := range w.header {
+		metadata[HTTP_HEADER_METADATA_PREFIX+strings.ToLower(k)] = strings.Join(v, ", ")
+	}
+
+	metadata[HTTP_STATUS_METADATA_KEY] = strconv.Itoa(w.status)
+
+	writer_opts.Metadata = metadata
+
+	content_type := w.header.Get("Content-Type")
+
+	if content_type != "" {
+		writer_opts.ContentType = content_type
+	}
+
+	w.aw.final_opts = writer_opts
+	return w.aw.Close()
+}
@@ -0,0 +1,82 @@
+package atomicwrite
+
+import (
+	"context"
+	"gocloud.dev/blob"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewHTTPResponseWriter(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	path := filepath.Join(root, "response.txt")
+
+	rsp, err := NewHTTPResponseWriter(ctx, path, http.StatusOK, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to create response writer, %v", err)
+	}
+
+	rsp.Header().Set("Content-Type", "text/plain")
+	rsp.Header().Set("X-Request-Id", "1234")
+	rsp.WriteHeader(http.StatusCreated)
+
+	_, err = rsp.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	rsp.WriteHeader(http.StatusTeapot)
+
+	err = rsp.(io.Closer).Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close response writer, %v", err)
+	}
+
+	bucket, err := blob.OpenBucket(ctx, "file://"+root)
+
+	if err != nil {
+		t.Fatalf("Failed to open bucket, %v", err)
+	}
+
+	defer bucket.Close()
+
+	attrs, err := bucket.Attributes(ctx, "response.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to derive attributes, %v", err)
+	}
+
+	tests := map[string]string{
+		HTTP_STATUS_METADATA_KEY:                     "201",
+		HTTP_HEADER_METADATA_PREFIX + "x-request-id": "1234",
+	}
+
+	for k, v := range tests {
+
+		if attrs.Metadata[k] != v {
+			t.Fatalf("Unexpected value for %s metadata, expected %s but got %s", k, v, attrs.Metadata[k])
+		}
+	}
+
+	if attrs.ContentType != "text/plain" {
+		t.Fatalf("Unexpected content type: %s", attrs.ContentType)
+	}
+
+	body, err := readBytes(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(body) != HELLO_WORLD {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+}
@@ -0,0 +1,98 @@
+// package httphandler implements an http.Handler which atomically writes the bodies of PUT requests to a gocloud.dev/blob bucket.
+package httphandler
+
+import (
+	"errors"
+	"github.com/sfomuseum/go-atomicwrite"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// NewHandler returns an http.Handler which atomically writes the body of each PUT request to the key defined by the request's
+// path (without the leading "/") in the bucket defined by 'bucket_uri'. 'opts' are applied to each write, as with the
+// `atomicwrite.New` constructor. The handler returns:
+//
+//   - 201 Created if the body was written successfully
+//   - 400 Bad Request if the key is empty or contains ".." segments
+//   - 405 Method Not Allowed for methods other than PUT
+//   - 412 Precondition Failed if an If-None-Match or If-Match condition is not met
+//   - 413 Request Entity Too Large if writing the body exceeds a quota defined by 'opts'
+//   - 500 Internal Server Error for all other errors
+//
+// An "If-None-Match: *" header only writes the body if the key does not already exist (see `atomicwrite.WithIfNotExists`). An
+// "If-Match" header only writes the body if the key exists and has a matching ETag (see `atomicwrite.WithIfMatch`). As noted in
+// the documentation for those options conditions are checked immediately before data is committed, rather than atomically.
+func NewHandler(bucket_uri string, opts ...atomicwrite.Option) http.Handler {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		if req.Method != http.MethodPut {
+			rsp.Header().Set("Allow", http.MethodPut)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		key := strings.TrimPrefix(req.URL.Path, "/")
+
+		if !atomicwrite.IsValidKey(key) {
+			http.Error(rsp, "Invalid key", http.StatusBadRequest)
+			return
+		}
+
+		write_opts := make([]atomicwrite.Option, len(opts))
+		copy(write_opts, opts)
+
+		if req.Header.Get("If-None-Match") == "*" {
+			write_opts = append(write_opts, atomicwrite.WithIfNotExists())
+		}
+
+		if etag := req.Header.Get("If-Match"); etag != "" {
+			write_opts = append(write_opts, atomicwrite.WithIfMatch(etag))
+		}
+
+		ctx := req.Context()
+
+		wr, err := atomicwrite.NewDeferred(ctx, bucket_uri, write_opts...)
+
+		if err != nil {
+			log.Printf("Failed to create writer for %s, %v", key, err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		_, err = io.Copy(wr, req.Body)
+
+		if err != nil {
+			wr.Abort()
+			writeError(rsp, key, err)
+			return
+		}
+
+		err = wr.Close(key)
+
+		if err != nil {
+			writeError(rsp, key, err)
+			return
+		}
+
+		rsp.WriteHeader(http.StatusCreated)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// writeError writes the HTTP status code for 'err', encountered writing 'key', to 'rsp'.
+func writeError(rsp http.ResponseWriter, key string, err error) {
+
+	switch {
+	case errors.Is(err, atomicwrite.ErrPreconditionFailed):
+		http.Error(rsp, "Precondition failed", http.StatusPreconditionFailed)
+	case errors.Is(err, atomicwrite.ErrQuotaExceeded):
+		http.Error(rsp, "Request entity too large", http.StatusRequestEntityTooLarge)
+	default:
+		log.Printf("Failed to write %s, %v", key, err)
+		http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+	}
+}
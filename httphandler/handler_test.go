@@ -0,0 +1,106 @@
+package httphandler
+
+import (
+	"context"
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/fileblob"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	h := NewHandler(bucket_uri)
+
+	do := func(method string, path string, body string, header map[string]string) int {
+
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+
+		rsp := httptest.NewRecorder()
+		h.ServeHTTP(rsp, req)
+
+		return rsp.Code
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		header map[string]string
+		status int
+	}{
+		{http.MethodPut, "/a/atomicwrite.txt", "Hello world", nil, http.StatusCreated},
+		{http.MethodGet, "/a/atomicwrite.txt", "", nil, http.StatusMethodNotAllowed},
+		{http.MethodPut, "/", "Hello world", nil, http.StatusBadRequest},
+		{http.MethodPut, "/a/../../atomicwrite.txt", "Hello world", nil, http.StatusBadRequest},
+		{http.MethodPut, "/a/atomicwrite.txt", "Goodbye world", map[string]string{"If-None-Match": "*"}, http.StatusPreconditionFailed},
+		{http.MethodPut, "/a/atomicwrite.txt", "Goodbye world", map[string]string{"If-Match": "\"nope\""}, http.StatusPreconditionFailed},
+		{http.MethodPut, "/b/atomicwrite.txt", "Hello world", map[string]string{"If-Match": "*"}, http.StatusPreconditionFailed},
+		{http.MethodPut, "/b/atomicwrite.txt", "Hello world", map[string]string{"If-None-Match": "*"}, http.StatusCreated},
+	}
+
+	for _, test := range tests {
+
+		status := do(test.method, test.path, test.body, test.header)
+
+		if status != test.status {
+			t.Fatalf("Unexpected status for %s %s %v, expected %d but got %d", test.method, test.path, test.header, test.status, status)
+		}
+	}
+
+	bucket, err := blob.OpenBucket(ctx, bucket_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to open bucket, %v", err)
+	}
+
+	defer bucket.Close()
+
+	attrs, err := bucket.Attributes(ctx, "a/atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to retrieve attributes, %v", err)
+	}
+
+	status := do(http.MethodPut, "/a/atomicwrite.txt", "Goodbye world", map[string]string{"If-Match": attrs.ETag})
+
+	if status != http.StatusCreated {
+		t.Fatalf("Unexpected status for matching ETag: %d", status)
+	}
+
+	body, err := os.ReadFile(filepath.Join(root, "a", "atomicwrite.txt"))
+
+	if err != nil {
+		t.Fatalf("Failed to read a/atomicwrite.txt, %v", err)
+	}
+
+	if string(body) != "Goodbye world" {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	for _, e := range entries {
+
+		if !e.IsDir() {
+			t.Fatalf("Unexpected file %s", e.Name())
+		}
+	}
+}
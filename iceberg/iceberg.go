@@ -0,0 +1,239 @@
+// package iceberg implements methods for atomically writing Apache Iceberg table metadata files to a gocloud.dev/blob bucket.
+package iceberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sfomuseum/go-atomicwrite"
+	"gocloud.dev/gcerrors"
+	"regexp"
+	"strconv"
+)
+
+// VERSION_HINT_KEY is the key, relative to the root of an Iceberg table, of the file which records the table's current metadata
+// version. It is the pointer used by file system (and object store) tables which do not use an external catalog.
+const VERSION_HINT_KEY string = "metadata/version-hint.text"
+
+// ErrCommitConflict is returned by `WriteMetadata` when another writer committed a new metadata version, or updated the version
+// hint, while metadata was being written.
+var ErrCommitConflict = errors.New("Commit conflict")
+
+// re_metadata_file matches the keys of metadata files, capturing the version.
+var re_metadata_file = regexp.MustCompile(`^metadata/v(\d+)\.metadata\.json$`)
+
+// type IcebergMetadata is the table metadata for an Iceberg table. Nested structures (schemas, partition specs, snapshots and sort
+// orders) are left encoded since this package does not need to inspect them. See https://iceberg.apache.org/spec/#table-metadata
+// for details.
+type IcebergMetadata struct {
+	// The version of the Iceberg format specification, 1 or 2
+	FormatVersion int `json:"format-version"`
+	// The unique identifier of the table
+	TableUUID string `json:"table-uuid"`
+	// The base location of the table
+	Location string `json:"location"`
+	// The highest sequence number assigned to a snapshot (format version 2)
+	LastSequenceNumber int64 `json:"last-sequence-number,omitempty"`
+	// The time the table was last updated, in milliseconds since the Unix epoch
+	LastUpdatedMs int64 `json:"last-updated-ms"`
+	// The highest column ID assigned in the table
+	LastColumnID int `json:"last-column-id"`
+	// The table's schemas
+	Schemas []json.RawMessage `json:"schemas,omitempty"`
+	// The ID of the table's current schema
+	CurrentSchemaID int `json:"current-schema-id"`
+	// The table's partition specs
+	PartitionSpecs []json.RawMessage `json:"partition-specs,omitempty"`
+	// The ID of the table's default partition spec
+	DefaultSpecID int `json:"default-spec-id"`
+	// The highest partition field ID assigned in the table
+	LastPartitionID int `json:"last-partition-id"`
+	// The table's properties
+	Properties map[string]string `json:"properties,omitempty"`
+	// The ID of the table's current snapshot, if any
+	CurrentSnapshotID *int64 `json:"current-snapshot-id,omitempty"`
+	// The table's snapshots
+	Snapshots []json.RawMessage `json:"snapshots,omitempty"`
+	// The history of the table's current snapshot
+	SnapshotLog []json.RawMessage `json:"snapshot-log,omitempty"`
+	// The history of the table's metadata files
+	MetadataLog []json.RawMessage `json:"metadata-log,omitempty"`
+	// The table's sort orders
+	SortOrders []json.RawMessage `json:"sort-orders,omitempty"`
+	// The ID of the table's default sort order
+	DefaultSortOrderID int `json:"default-sort-order-id"`
+}
+
+// WriteMetadata atomically writes 'meta' as the next metadata version of the Iceberg table whose root is defined by 'table_uri'
+// (for example "file:///data/table") and then atomically replaces the table's version hint to point to it. The new metadata file
+// (metadata/v{VERSION}.metadata.json) is only written if it does not already exist and the version hint is only replaced if it has
+// not changed since the latest version was determined. If either check fails an error wrapping ErrCommitConflict is returned; in the
+// second case the new metadata file has been written but is not current, and the caller should reload the table and try again.
+// 'opts' are applied to each write, as with the `atomicwrite.New` constructor, and to reading the latest version and the version
+// hint. As noted in the documentation for the `atomicwrite.WithIfNotExists` and `atomicwrite.WithIfMatch` options those checks
+// happen immediately before data is committed rather than atomically; on a local filesystem pass the `atomicwrite.WithFlock` option
+// to remove that race.
+func WriteMetadata(ctx context.Context, table_uri string, meta *IcebergMetadata, opts ...atomicwrite.Option) error {
+
+	err := validateMetadata(meta)
+
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(meta)
+
+	if err != nil {
+		return fmt.Errorf("Failed to encode metadata, %w", err)
+	}
+
+	hint_etag, err := versionHintETag(ctx, table_uri, opts...)
+
+	if err != nil {
+		return err
+	}
+
+	latest, err := LatestVersion(ctx, table_uri, opts...)
+
+	if err != nil {
+		return err
+	}
+
+	version := latest + 1
+
+	metadata_opts := append(copyOptions(opts), atomicwrite.WithIfNotExists())
+
+	err = writeKey(ctx, table_uri, MetadataKey(version), body, metadata_opts...)
+
+	if errors.Is(err, atomicwrite.ErrPreconditionFailed) {
+		return fmt.Errorf("Metadata version %d already exists, %w", version, ErrCommitConflict)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	hint_opts := copyOptions(opts)
+
+	if hint_etag == "" {
+		hint_opts = append(hint_opts, atomicwrite.WithIfNotExists())
+	} else {
+		hint_opts = append(hint_opts, atomicwrite.WithIfMatch(hint_etag))
+	}
+
+	err = writeKey(ctx, table_uri, VERSION_HINT_KEY, []byte(strconv.FormatInt(version, 10)), hint_opts...)
+
+	if errors.Is(err, atomicwrite.ErrPreconditionFailed) {
+		return fmt.Errorf("Metadata version %d was written but the version hint was updated by another writer, %w", version, ErrCommitConflict)
+	}
+
+	return err
+}
+
+// LatestVersion returns the highest metadata version of the Iceberg table whose root is defined by 'table_uri', or 0 if the table
+// has no metadata files. Metadata versions start at 1. 'opts' are passed to `atomicwrite.ListCommitted` so that metadata files are
+// listed using the same `atomicwrite.WithBucketOpener` and `atomicwrite.WithBucketWrapper` options they are written with.
+func LatestVersion(ctx context.Context, table_uri string, opts ...atomicwrite.Option) (int64, error) {
+
+	objects, err := atomicwrite.ListCommitted(ctx, table_uri, "metadata/", "", opts...)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to list metadata files, %w", err)
+	}
+
+	latest := int64(0)
+
+	for _, obj := range objects {
+
+		m := re_metadata_file.FindStringSubmatch(obj.Key)
+
+		if m == nil {
+			continue
+		}
+
+		v, err := strconv.ParseInt(m[1], 10, 64)
+
+		if err != nil {
+			return 0, fmt.Errorf("Failed to parse version for %s, %w", obj.Key, err)
+		}
+
+		if v > latest {
+			latest = v
+		}
+	}
+
+	return latest, nil
+}
+
+// MetadataKey returns the key, relative to the root of an Iceberg table, of the metadata file for 'version'.
+func MetadataKey(version int64) string {
+	return fmt.Sprintf("metadata/v%d.metadata.json", version)
+}
+
+// versionHintETag returns the ETag of the version hint for the Iceberg table whose root is defined by 'table_uri', or an empty
+// string if it does not exist. 'opts' are passed to `atomicwrite.Attributes`.
+func versionHintETag(ctx context.Context, table_uri string, opts ...atomicwrite.Option) (string, error) {
+
+	attrs, err := atomicwrite.Attributes(ctx, table_uri, VERSION_HINT_KEY, opts...)
+
+	if err != nil {
+
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	if attrs.ETag == "" {
+		return "", fmt.Errorf("Bucket %s does not report ETags for %s", table_uri, VERSION_HINT_KEY)
+	}
+
+	return attrs.ETag, nil
+}
+
+// writeKey atomically writes 'body' to 'key' in the bucket defined by 'table_uri'.
+func writeKey(ctx context.Context, table_uri string, key string, body []byte, opts ...atomicwrite.Option) error {
+
+	wr, err := atomicwrite.NewDeferred(ctx, table_uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create writer, %w", err)
+	}
+
+	_, err = wr.Write(body)
+
+	if err != nil {
+		wr.Abort()
+		return fmt.Errorf("Failed to write %s, %w", key, err)
+	}
+
+	return wr.Close(key)
+}
+
+// copyOptions returns a copy of 'opts' which can be appended to without modifying 'opts'.
+func copyOptions(opts []atomicwrite.Option) []atomicwrite.Option {
+
+	c := make([]atomicwrite.Option, len(opts))
+	copy(c, opts)
+
+	return c
+}
+
+// validateMetadata returns an error if 'meta' is missing any of the properties required by all format versions.
+func validateMetadata(meta *IcebergMetadata) error {
+
+	switch {
+	case meta == nil:
+		return fmt.Errorf("Metadata is nil")
+	case meta.FormatVersion != 1 && meta.FormatVersion != 2:
+		return fmt.Errorf("Unsupported format version %d", meta.FormatVersion)
+	case meta.TableUUID == "":
+		return fmt.Errorf("Missing table UUID")
+	case meta.Location == "":
+		return fmt.Errorf("Missing table location")
+	}
+
+	return nil
+}
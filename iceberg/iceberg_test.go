@@ -0,0 +1,235 @@
+package iceberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/sfomuseum/go-atomicwrite"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func testMetadata() *IcebergMetadata {
+
+	return &IcebergMetadata{
+		FormatVersion:   2,
+		TableUUID:       "9c12d441-03fe-4693-9a96-a0705ddf69c1",
+		Location:        "file:///usr/local/table",
+		LastUpdatedMs:   1602638573590,
+		LastColumnID:    1,
+		Schemas:         []json.RawMessage{json.RawMessage(`{"type":"struct","schema-id":0,"fields":[]}`)},
+		PartitionSpecs:  []json.RawMessage{json.RawMessage(`{"spec-id":0,"fields":[]}`)},
+		SortOrders:      []json.RawMessage{json.RawMessage(`{"order-id":0,"fields":[]}`)},
+		LastPartitionID: 999,
+	}
+}
+
+func TestWriteMetadata(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	table_uri := "file://" + filepath.ToSlash(root)
+
+	latest, err := LatestVersion(ctx, table_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to derive latest version, %v", err)
+	}
+
+	if latest != 0 {
+		t.Fatalf("Expected latest version of empty table to be 0, got %d", latest)
+	}
+
+	for i := 1; i <= 2; i++ {
+
+		err = WriteMetadata(ctx, table_uri, testMetadata())
+
+		if err != nil {
+			t.Fatalf("Failed to write metadata, %v", err)
+		}
+
+		hint_path := filepath.Join(root, filepath.FromSlash(VERSION_HINT_KEY))
+
+		hint, err := os.ReadFile(hint_path)
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", hint_path, err)
+		}
+
+		if string(hint) != strconv.Itoa(i) {
+			t.Fatalf("Expected version hint %d, got '%s'", i, hint)
+		}
+	}
+
+	path := filepath.Join(root, filepath.FromSlash(MetadataKey(2)))
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	var meta IcebergMetadata
+
+	err = json.Unmarshal(v, &meta)
+
+	if err != nil {
+		t.Fatalf("Failed to decode metadata, %v", err)
+	}
+
+	if meta.TableUUID != testMetadata().TableUUID {
+		t.Fatalf("Unexpected table UUID: %s", meta.TableUUID)
+	}
+}
+
+func TestWriteMetadataConflict(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	table_uri := "file://" + filepath.ToSlash(root)
+
+	// Simulate another writer committing version 1, and updating the version hint, while this writer is writing version 1
+
+	conflict := func(asFunc func(interface{}) bool) error {
+
+		for _, key := range []string{MetadataKey(1), VERSION_HINT_KEY} {
+
+			path := filepath.Join(root, filepath.FromSlash(key))
+
+			err := os.MkdirAll(filepath.Dir(path), 0755)
+
+			if err != nil {
+				return err
+			}
+
+			err = os.WriteFile(path, []byte("1"), 0644)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err := WriteMetadata(ctx, table_uri, testMetadata(), atomicwrite.WithBeforeWrite(conflict))
+
+	if !errors.Is(err, ErrCommitConflict) {
+		t.Fatalf("Expected ErrCommitConflict, got %v", err)
+	}
+}
+
+func TestWriteMetadataVersionHintConflict(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	table_uri := "file://" + filepath.ToSlash(root)
+
+	err := WriteMetadata(ctx, table_uri, testMetadata())
+
+	if err != nil {
+		t.Fatalf("Failed to write metadata, %v", err)
+	}
+
+	// Simulate another writer replacing the version hint after this writer has read it
+
+	hint_path := filepath.Join(root, filepath.FromSlash(VERSION_HINT_KEY))
+
+	conflict := func(asFunc func(interface{}) bool) error {
+		return os.WriteFile(hint_path, []byte("10"), 0644)
+	}
+
+	err = WriteMetadata(ctx, table_uri, testMetadata(), atomicwrite.WithBeforeWrite(conflict))
+
+	if !errors.Is(err, ErrCommitConflict) {
+		t.Fatalf("Expected ErrCommitConflict, got %v", err)
+	}
+
+	hint, err := os.ReadFile(hint_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", hint_path, err)
+	}
+
+	if string(hint) != "10" {
+		t.Fatalf("Expected version hint to be left unchanged, got '%s'", hint)
+	}
+}
+
+func TestWriteMetadataInvalid(t *testing.T) {
+
+	ctx := context.Background()
+
+	table_uri := "file://" + filepath.ToSlash(t.TempDir())
+
+	tests := []*IcebergMetadata{
+		nil,
+		{},
+		{FormatVersion: 3, TableUUID: "a", Location: "b"},
+		{FormatVersion: 2, Location: "b"},
+		{FormatVersion: 2, TableUUID: "a"},
+	}
+
+	for _, meta := range tests {
+
+		err := WriteMetadata(ctx, table_uri, meta)
+
+		if err == nil {
+			t.Fatalf("Expected invalid metadata %v to fail", meta)
+		}
+	}
+}
+
+func TestWriteMetadataWithBucketOpener(t *testing.T) {
+
+	ctx := context.Background()
+
+	mem := memblob.OpenBucket(nil)
+	defer mem.Close()
+
+	// Each mem:// URI opens a new, empty, bucket so the latest version and the version hint are
+	// only found if they are read using the same opener that they are written with
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return mem, nil
+	}
+
+	for i := 1; i <= 2; i++ {
+
+		err := WriteMetadata(ctx, "mem://", testMetadata(), atomicwrite.WithBucketOpener(opener))
+
+		if err != nil {
+			t.Fatalf("Failed to write metadata, %v", err)
+		}
+
+		hint, err := mem.ReadAll(ctx, VERSION_HINT_KEY)
+
+		if err != nil {
+			t.Fatalf("Failed to read version hint, %v", err)
+		}
+
+		if string(hint) != strconv.Itoa(i) {
+			t.Fatalf("Expected version hint %d, got '%s'", i, hint)
+		}
+	}
+
+	latest, err := LatestVersion(ctx, "mem://", atomicwrite.WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to derive latest version, %v", err)
+	}
+
+	if latest != 2 {
+		t.Fatalf("Expected latest version 2, got %d", latest)
+	}
+}
@@ -0,0 +1,266 @@
+//go:build integration
+// +build integration
+
+// package integration implements integration tests for the go-atomicwrite package against each of the backends defined in
+// docker-compose.yml. Run them using `make integration` from the root of the repository.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/sfomuseum/go-atomicwrite"
+	"gocloud.dev/blob"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The sizes, in bytes, of the data written for each backend and option
+var sizes = []int{
+	0,
+	1024,
+	1024*1024 + 1,
+}
+
+// The options tested for each backend and size. The go-atomicwrite package does not compress data so there is no compression option.
+var test_options = []string{
+	"none",
+	"encryption",
+	"checksum",
+}
+
+// buckets caches the buckets opened for each bucket URI so that data can be read back from mem:// buckets
+var buckets = new(sync.Map)
+
+func TestMain(m *testing.M) {
+
+	_, err := exec.LookPath("docker")
+
+	if err != nil {
+		log.Println("Docker is not available, skipping integration tests")
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestIntegration(t *testing.T) {
+
+	ctx := context.Background()
+
+	backends := map[string]string{
+		"file":   "file://" + t.TempDir(),
+		"mem":    "mem://",
+		"s3":     os.Getenv("ATOMICWRITE_S3_URI"),
+		"azblob": os.Getenv("ATOMICWRITE_AZBLOB_URI"),
+	}
+
+	for name, bucket_uri := range backends {
+
+		t.Run(name, func(t *testing.T) {
+
+			if bucket_uri == "" {
+				t.Skipf("No URI defined for %s backend", name)
+			}
+
+			u, err := url.Parse(bucket_uri)
+
+			if err != nil {
+				t.Fatalf("Failed to parse %s, %v", bucket_uri, err)
+			}
+
+			if !blob.DefaultURLMux().ValidBucketScheme(u.Scheme) {
+				t.Skipf("No gocloud.dev/blob driver registered for %s", u.Scheme)
+			}
+
+			for _, sz := range sizes {
+
+				for _, opt := range test_options {
+
+					t.Run(fmt.Sprintf("%d-%s", sz, opt), func(t *testing.T) {
+						testIntegration(ctx, t, bucket_uri, sz, opt)
+					})
+				}
+			}
+		})
+	}
+}
+
+// testIntegration writes 'sz' random bytes to a key in 'bucket_uri' using the option 'opt' and verifies the result.
+func testIntegration(ctx context.Context, t *testing.T, bucket_uri string, sz int, opt string) {
+
+	body := make([]byte, sz)
+
+	_, err := rand.Read(body)
+
+	if err != nil {
+		t.Fatalf("Failed to generate data, %v", err)
+	}
+
+	key := fmt.Sprintf("atomicwrite-%d-%s.bin", sz, opt)
+
+	uri, err := keyURI(bucket_uri, key)
+
+	if err != nil {
+		t.Fatalf("Failed to derive URI, %v", err)
+	}
+
+	bucket, err := openBucket(ctx, bucket_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to open bucket, %v", err)
+	}
+
+	opts := []atomicwrite.Option{
+		atomicwrite.WithBucketOpener(openBucket),
+	}
+
+	switch opt {
+	case "encryption":
+
+		enc_key := make([]byte, atomicwrite.AES_GCM_KEY_SIZE)
+
+		_, err := rand.Read(enc_key)
+
+		if err != nil {
+			t.Fatalf("Failed to generate key, %v", err)
+		}
+
+		err = writeBytes(ctx, uri, body, append(opts, atomicwrite.WithAESGCMEncryption(enc_key))...)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", uri, err)
+		}
+
+		r, err := bucket.NewReader(ctx, key, nil)
+
+		if err != nil {
+			t.Fatalf("Failed to open %s, %v", key, err)
+		}
+
+		defer r.Close()
+
+		dr, err := atomicwrite.NewAESGCMDecryptReader(r, enc_key)
+
+		if err != nil {
+			t.Fatalf("Failed to create decrypt reader, %v", err)
+		}
+
+		v, err := io.ReadAll(dr)
+
+		if err != nil {
+			t.Fatalf("Failed to decrypt %s, %v", key, err)
+		}
+
+		if !bytes.Equal(v, body) {
+			t.Fatalf("Unexpected body for %s", key)
+		}
+
+	case "checksum":
+
+		err := atomicwrite.WriteAndHash(ctx, uri, body, "sha256", opts...)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", uri, err)
+		}
+
+		assertBody(ctx, t, bucket, key, body)
+
+		sidecar, err := bucket.ReadAll(ctx, key+".sha256")
+
+		if err != nil {
+			t.Fatalf("Failed to read checksum for %s, %v", key, err)
+		}
+
+		sum := sha256.Sum256(body)
+
+		if !strings.HasPrefix(string(sidecar), hex.EncodeToString(sum[:])) {
+			t.Fatalf("Unexpected checksum for %s: %s", key, string(sidecar))
+		}
+
+	default:
+
+		err := writeBytes(ctx, uri, body, opts...)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", uri, err)
+		}
+
+		assertBody(ctx, t, bucket, key, body)
+	}
+}
+
+// writeBytes atomically writes 'body' to 'uri'.
+func writeBytes(ctx context.Context, uri string, body []byte, opts ...atomicwrite.Option) error {
+
+	wr, err := atomicwrite.New(ctx, uri, opts...)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = wr.Write(body)
+
+	if err != nil {
+		wr.(*atomicwrite.AtomicWriter).Abort()
+		return err
+	}
+
+	return wr.Close()
+}
+
+// assertBody fails 't' if the data stored at 'key' in 'bucket' is not 'body'.
+func assertBody(ctx context.Context, t *testing.T, bucket *blob.Bucket, key string, body []byte) {
+
+	v, err := bucket.ReadAll(ctx, key)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", key, err)
+	}
+
+	if !bytes.Equal(v, body) {
+		t.Fatalf("Unexpected body for %s", key)
+	}
+}
+
+// keyURI returns the URI for 'key' in the bucket defined by 'bucket_uri', preserving any query parameters.
+func keyURI(bucket_uri string, key string) (string, error) {
+
+	u, err := url.Parse(bucket_uri)
+
+	if err != nil {
+		return "", err
+	}
+
+	u.Path = path.Join("/", u.Path, key)
+	return u.String(), nil
+}
+
+// openBucket returns the (cached) bucket for 'bucket_uri'.
+func openBucket(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+
+	v, ok := buckets.Load(bucket_uri)
+
+	if ok {
+		return v.(*blob.Bucket), nil
+	}
+
+	bucket, err := blob.OpenBucket(ctx, bucket_uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	v, _ = buckets.LoadOrStore(bucket_uri, bucket)
+	return v.(*blob.Bucket), nil
+}
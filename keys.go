@@ -0,0 +1,65 @@
+package atomicwrite
+
+import (
+	"strings"
+)
+
+// type KeyNormalizerFunc is a function used to transform the key (relative to the bucket) derived from the URI passed to the
+// `New` constructor before any data is written.
+type KeyNormalizerFunc func(key string) string
+
+// WithKeyNormalizer returns an Option which registers 'fn' to transform the key (relative to the bucket) of the final path.
+// Since the intermediate temporary file is derived from the final path it is transformed as well. Keys for URIs defined by the
+// `WithAdditionalPaths` option are also transformed. This is useful when object storage key naming conventions differ between
+// backends, for example S3 keys are case-sensitive while Azure Blob Storage keys are not. For `file://` URIs the key is the
+// filename. This option may be specified multiple times; normalizers are invoked in the order they were registered. If the
+// transformed key is empty the `New` constructor will return an error.
+func WithKeyNormalizer(fn KeyNormalizerFunc) Option {
+
+	return func(o *options) {
+		o.key_normalizers = append(o.key_normalizers, fn)
+	}
+}
+
+// LowercaseKeyNormalizer is a KeyNormalizerFunc which converts 'key' to lower case.
+func LowercaseKeyNormalizer(key string) string {
+	return strings.ToLower(key)
+}
+
+// ForwardSlashKeyNormalizer is a KeyNormalizerFunc which replaces backslashes in 'key' with forward slashes.
+func ForwardSlashKeyNormalizer(key string) string {
+	return strings.ReplaceAll(key, "\\", "/")
+}
+
+// TrimLeadingSlashKeyNormalizer is a KeyNormalizerFunc which removes any leading forward slashes from 'key'.
+func TrimLeadingSlashKeyNormalizer(key string) string {
+	return strings.TrimLeft(key, "/")
+}
+
+// normalizeKey returns 'key' transformed by each of 'fns' in order.
+func normalizeKey(fns []KeyNormalizerFunc, key string) string {
+
+	for _, fn := range fns {
+		key = fn(key)
+	}
+
+	return key
+}
+
+// IsValidKey returns false if 'key' is empty, ends with "/" or contains ".." segments. It is used by the httphandler, grpc and
+// wshandler packages to reject client-supplied keys which do not name a single object inside the bucket.
+func IsValidKey(key string) bool {
+
+	if key == "" || strings.HasSuffix(key, "/") {
+		return false
+	}
+
+	for _, part := range strings.Split(key, "/") {
+
+		if part == ".." {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,114 @@
+package atomicwrite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyNormalizers(t *testing.T) {
+
+	tests := map[string][]string{
+		"Example/AtomicWrite.TXT": {"example/atomicwrite.txt", "Example/AtomicWrite.TXT", "Example/AtomicWrite.TXT"},
+		"a\\b\\atomicwrite.txt":   {"a\\b\\atomicwrite.txt", "a/b/atomicwrite.txt", "a\\b\\atomicwrite.txt"},
+		"//a/atomicwrite.txt":     {"//a/atomicwrite.txt", "//a/atomicwrite.txt", "a/atomicwrite.txt"},
+	}
+
+	fns := []KeyNormalizerFunc{
+		LowercaseKeyNormalizer,
+		ForwardSlashKeyNormalizer,
+		TrimLeadingSlashKeyNormalizer,
+	}
+
+	for key, expected := range tests {
+
+		for i, fn := range fns {
+
+			v := fn(key)
+
+			if v != expected[i] {
+				t.Fatalf("Unexpected value for %s (normalizer %d): %s", key, i, v)
+			}
+		}
+	}
+
+	v := normalizeKey(fns, "\\\\A\\AtomicWrite.TXT")
+
+	if v != "a/atomicwrite.txt" {
+		t.Fatalf("Unexpected value for chained normalizers: %s", v)
+	}
+}
+
+func TestWithKeyNormalizer(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "AtomicWrite.TXT")
+	latest := filepath.Join(root, "Latest.TXT")
+
+	err := testAtomicWrite(path, WithKeyNormalizer(LowercaseKeyNormalizer), WithAdditionalPaths(latest))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	names := make(map[string]bool)
+
+	for _, e := range entries {
+		names[e.Name()] = true
+	}
+
+	for _, fname := range []string{"atomicwrite.txt", "latest.txt"} {
+
+		if !names[fname] {
+			t.Fatalf("Expected %s to exist, got %v", fname, names)
+		}
+	}
+
+	for _, fname := range []string{"AtomicWrite.TXT", "Latest.TXT"} {
+
+		if names[fname] {
+			t.Fatalf("Expected %s not to exist", fname)
+		}
+	}
+
+	empty := func(key string) string {
+		return ""
+	}
+
+	_, err = New(ctx, path, WithKeyNormalizer(empty))
+
+	if err == nil {
+		t.Fatalf("Expected empty normalized key to fail")
+	}
+}
+
+func TestIsValidKey(t *testing.T) {
+
+	tests := map[string]bool{
+		"atomicwrite.txt":       true,
+		"a/b/atomicwrite.txt":   true,
+		"a/..b/atomicwrite.txt": true,
+		"":                      false,
+		"a/":                    false,
+		"../atomicwrite.txt":    false,
+		"a/../atomicwrite.txt":  false,
+		"a/..":                  false,
+	}
+
+	for key, expected := range tests {
+
+		if IsValidKey(key) != expected {
+			t.Fatalf("Expected IsValidKey(%q) to be %t", key, expected)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package atomicwrite
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"runtime"
+)
+
+// WithLineEnding returns an Option specifying 'ending' as the line ending used by the `WriteLines` and `WriteLinesChan` methods.
+// If unset (or empty) the line ending is "\r\n" on Windows and "\n" on all other platforms.
+func WithLineEnding(ending string) Option {
+
+	return func(o *options) {
+		o.line_ending = ending
+	}
+}
+
+// WriteLines atomically writes 'lines' to 'uri', each followed by a line ending (see the `WithLineEnding` option). Lines are
+// written as-is so they should not contain line endings themselves.
+func WriteLines(ctx context.Context, uri string, lines []string, opts ...Option) error {
+
+	ch := make(chan string, len(lines))
+
+	for _, ln := range lines {
+		ch <- ln
+	}
+
+	close(ch)
+
+	return WriteLinesChan(ctx, uri, ch, opts...)
+}
+
+// WriteLinesChan atomically writes each line received from 'lines' to 'uri', each followed by a line ending (see the `WithLineEnding`
+// option), until 'lines' is closed. Data is only committed once 'lines' is closed. If 'ctx' is cancelled before then the writer is
+// aborted, nothing is written to 'uri' and the context's error is returned.
+func WriteLinesChan(ctx context.Context, uri string, lines <-chan string, opts ...Option) error {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	ending := o.line_ending
+
+	if ending == "" {
+
+		ending = "\n"
+
+		if runtime.GOOS == "windows" {
+			ending = "\r\n"
+		}
+	}
+
+	wr, err := New(ctx, uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create atomic writer, %w", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+	buf := bufio.NewWriter(wr)
+
+	for {
+
+		select {
+		case <-ctx.Done():
+			aw.Abort()
+			return ctx.Err()
+		case ln, ok := <-lines:
+
+			if !ok {
+
+				err = buf.Flush()
+
+				if err != nil {
+					aw.Abort()
+					return fmt.Errorf("Failed to write data, %w", err)
+				}
+
+				err = wr.Close()
+
+				if err != nil {
+					return fmt.Errorf("Failed to close atomic writer, %w", err)
+				}
+
+				return nil
+			}
+
+			_, err = buf.WriteString(ln + ending)
+
+			if err != nil {
+				aw.Abort()
+				return fmt.Errorf("Failed to write data, %w", err)
+			}
+		}
+	}
+}
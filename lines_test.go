@@ -0,0 +1,118 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteLines(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	err := WriteLines(ctx, path, []string{"hello", "world"})
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	expected := "hello\nworld\n"
+
+	if runtime.GOOS == "windows" {
+		expected = "hello\r\nworld\r\n"
+	}
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != expected {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+
+	err = WriteLines(ctx, path, []string{"hello", "world"}, WithLineEnding("\r\n"))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	v, err = os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != "hello\r\nworld\r\n" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+}
+
+func TestWriteLinesChan(t *testing.T) {
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	lines := make(chan string)
+	errs := make(chan error)
+
+	go func() {
+		errs <- WriteLinesChan(ctx, path, lines, WithLineEnding("\n"))
+	}()
+
+	lines <- "hello"
+	cancel()
+
+	err := <-errs
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no files after cancellation, got %d", len(entries))
+	}
+
+	lines = make(chan string)
+
+	go func() {
+		errs <- WriteLinesChan(context.Background(), path, lines, WithLineEnding("\n"))
+	}()
+
+	lines <- "hello"
+	lines <- "world"
+	close(lines)
+
+	err = <-errs
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != "hello\nworld\n" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+}
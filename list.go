@@ -0,0 +1,80 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// re_atomic_path matches the file names of intermediate temporary files derived by the `deriveAtomicPath` method,
+// "{STEM}-{RANDOM_INTEGER}{EXTENSION}". Random integers are almost always at least 10 digits long so shorter
+// integers, which are common in the names of committed files, are not matched.
+var re_atomic_path = regexp.MustCompile(`^.*-\d{10,}(\.[^.]*)?$`)
+
+// ListCommitted returns the objects in the bucket defined by 'bucket_uri' whose keys start with 'prefix', excluding intermediate
+// temporary files (and snapshot files) which are still being written. If 'temp_marker' is not empty then objects whose file
+// names start or end with 'temp_marker' are excluded. Otherwise objects whose file names match the default temporary file naming
+// scheme, "{STEM}-{RANDOM_INTEGER}{EXTENSION}" where the random integer is at least 10 digits long, are excluded. Only the
+// `WithBucketOpener` and `WithBucketWrapper` options are used.
+func ListCommitted(ctx context.Context, bucket_uri string, prefix string, temp_marker string, opts ...Option) ([]*blob.ListObject, error) {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	bucket, err := o.openBucket(ctx, bucket_uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer o.closeBucket(bucket)
+
+	list_opts := &blob.ListOptions{
+		Prefix: prefix,
+	}
+
+	iter := bucket.List(list_opts)
+
+	objects := make([]*blob.ListObject, 0)
+
+	for {
+
+		obj, err := iter.Next(ctx)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list bucket %s, %w", bucket_uri, err)
+		}
+
+		if isAtomicPath(obj.Key, temp_marker) {
+			continue
+		}
+
+		objects = append(objects, obj)
+	}
+
+	return objects, nil
+}
+
+// isAtomicPath returns true if the file name of 'key' starts or ends with 'temp_marker' or, if 'temp_marker' is
+// empty, it matches the default temporary file naming scheme.
+func isAtomicPath(key string, temp_marker string) bool {
+
+	fname := path.Base(key)
+
+	if temp_marker != "" {
+		return strings.HasPrefix(fname, temp_marker) || strings.HasSuffix(fname, temp_marker)
+	}
+
+	return re_atomic_path.MatchString(fname)
+}
@@ -0,0 +1,104 @@
+package atomicwrite
+
+import (
+	"context"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestListCommitted(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	for _, p := range []string{"a", "b"} {
+
+		err := os.Mkdir(filepath.Join(root, p), 0755)
+
+		if err != nil {
+			t.Fatalf("Failed to create %s, %v", p, err)
+		}
+	}
+
+	names := []string{
+		"a/atomicwrite.txt",
+		"a/atomicwrite-5577006791947779410.txt",
+		"a/photo-2024.jpg",
+		"a/atomicwrite.txt.tmp",
+		"b/atomicwrite.txt",
+	}
+
+	for _, n := range names {
+
+		err := os.WriteFile(filepath.Join(root, filepath.FromSlash(n)), []byte(HELLO_WORLD), 0644)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", n, err)
+		}
+	}
+
+	tests := map[string]string{
+		"":     "a/atomicwrite.txt a/atomicwrite.txt.tmp a/photo-2024.jpg",
+		".tmp": "a/atomicwrite-5577006791947779410.txt a/atomicwrite.txt a/photo-2024.jpg",
+	}
+
+	for marker, expected := range tests {
+
+		objects, err := ListCommitted(ctx, "file://"+root, "a/", marker)
+
+		if err != nil {
+			t.Fatalf("Failed to list committed files with '%s' marker, %v", marker, err)
+		}
+
+		keys := make([]string, len(objects))
+
+		for i, obj := range objects {
+			keys[i] = obj.Key
+		}
+
+		sort.Strings(keys)
+
+		if strings.Join(keys, " ") != expected {
+			t.Fatalf("Unexpected committed files with '%s' marker: %v", marker, keys)
+		}
+	}
+}
+
+func TestListCommittedWithBucketOpener(t *testing.T) {
+
+	ctx := context.Background()
+
+	mem := memblob.OpenBucket(nil)
+	defer mem.Close()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return mem, nil
+	}
+
+	err := mem.WriteAll(ctx, "a/atomicwrite.txt", []byte(HELLO_WORLD), nil)
+
+	if err != nil {
+		t.Fatalf("Failed to write a/atomicwrite.txt, %v", err)
+	}
+
+	// The shared bucket must still be open after listing so list it twice
+
+	for i := 0; i < 2; i++ {
+
+		objects, err := ListCommitted(ctx, "mem://", "a/", "", WithBucketOpener(opener))
+
+		if err != nil {
+			t.Fatalf("Failed to list committed files, %v", err)
+		}
+
+		if len(objects) != 1 || objects[0].Key != "a/atomicwrite.txt" {
+			t.Fatalf("Unexpected committed files: %v", objects)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package atomicwrite
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// type lockingWriter implements the io.WriteCloser interface for an AtomicWriter instance which holds a lock until it is closed or aborted.
+type lockingWriter struct {
+	// The underlying AtomicWriter instance
+	writer *AtomicWriter
+	// The lock held by the writer
+	lock sync.Locker
+	// Used to ensure lock is only released once
+	unlock_once sync.Once
+}
+
+// NewLocking returns a new io.WriteCloser instance for 'uri' which acquires 'lock' before the underlying AtomicWriter instance
+// is created and releases it when the `Close` or `Abort` methods are invoked (or if the AtomicWriter instance can not be created).
+// This allows callers to serialize access to a URI across goroutines, or processes, using whatever locking strategy they choose,
+// for example a `sync.Mutex` or a `sync.Locker` backed by a lock file or a Redis lock. NewLocking blocks until 'lock' is acquired.
+// The returned writer also implements an `Abort() error` method.
+func NewLocking(ctx context.Context, uri string, lock sync.Locker, opts ...Option) (io.WriteCloser, error) {
+
+	lock.Lock()
+
+	wr, err := newAtomicWriter(ctx, uri, opts...)
+
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+
+	w := &lockingWriter{
+		writer: wr,
+		lock:   lock,
+	}
+
+	return w, nil
+}
+
+// Write writes 'b' to the underlying AtomicWriter instance.
+func (w *lockingWriter) Write(b []byte) (int, error) {
+	return w.writer.Write(b)
+}
+
+// Close closes (and commits) the underlying AtomicWriter instance and then releases the lock.
+func (w *lockingWriter) Close() error {
+
+	defer w.unlock()
+	return w.writer.Close()
+}
+
+// Abort aborts the underlying AtomicWriter instance and then releases the lock.
+func (w *lockingWriter) Abort() error {
+
+	defer w.unlock()
+	return w.writer.Abort()
+}
+
+// unlock releases the lock the first time it is invoked.
+func (w *lockingWriter) unlock() {
+
+	w.unlock_once.Do(func() {
+		w.lock.Unlock()
+	})
+}
@@ -0,0 +1,112 @@
+package atomicwrite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLocker is a sync.Locker which records the number of times it has been locked and unlocked.
+type testLocker struct {
+	sync.Mutex
+	locked   int
+	unlocked int
+}
+
+func (l *testLocker) Lock() {
+	l.Mutex.Lock()
+	l.locked += 1
+}
+
+func (l *testLocker) Unlock() {
+	l.unlocked += 1
+	l.Mutex.Unlock()
+}
+
+func TestNewLocking(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	l := &testLocker{}
+
+	wr, err := NewLocking(ctx, path, l)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	// A second writer should block until the first writer is closed
+
+	done := make(chan error)
+
+	go func() {
+
+		wr2, err := NewLocking(ctx, path, l)
+
+		if err != nil {
+			done <- err
+			return
+		}
+
+		done <- wr2.(*lockingWriter).Abort()
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("Expected second writer to block")
+	case <-time.After(50 * time.Millisecond):
+		// pass
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	err = <-done
+
+	if err != nil {
+		t.Fatalf("Failed to abort second writer, %v", err)
+	}
+
+	// Subsequent calls must not unlock the lock again
+
+	wr.Close()
+	wr.(*lockingWriter).Abort()
+
+	if l.locked != 2 || l.unlocked != 2 {
+		t.Fatalf("Unexpected lock counts, locked %d unlocked %d", l.locked, l.unlocked)
+	}
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(body) != HELLO_WORLD {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+
+	_, err = NewLocking(ctx, "file:///", l)
+
+	if err == nil {
+		t.Fatalf("Expected invalid URI to fail")
+	}
+
+	if l.locked != 3 || l.unlocked != 3 {
+		t.Fatalf("Expected lock to be released after failure, locked %d unlocked %d", l.locked, l.unlocked)
+	}
+}
@@ -0,0 +1,110 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/gcerrors"
+	"io"
+)
+
+// Merge appends the data stored at 'src_key' to the data stored at 'dst_key' in the bucket defined by 'bucket_uri'. The data
+// stored at 'dst_key' (if it exists) and 'src_key' are copied to an intermediate temporary file, using a DeferredWriter instance,
+// which is then committed to 'dst_key' so readers will only ever see the previous data or the merged data. This is useful for log
+// aggregation and event sourcing, where multiple sources contribute to a single blob. If 'src_key' does not exist an error wrapping
+// ErrNotFound is returned. To avoid losing data written by another writer while the data is being merged the data is only
+// committed if 'dst_key' is unchanged (its ETag still matches, or it still does not exist), otherwise an error wrapping
+// ErrPreconditionFailed is returned and the merge may be retried. Buckets which do not report ETags can not detect concurrent
+// changes. 'src_key' is not removed. 'opts' are the same as the `NewDeferred` constructor.
+func Merge(ctx context.Context, bucket_uri string, src_key string, dst_key string, opts ...Option) error {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	src := normalizeKey(o.key_normalizers, encodeKey(src_key))
+	dst := normalizeKey(o.key_normalizers, encodeKey(dst_key))
+
+	if src == "" || dst == "" {
+		return fmt.Errorf("Key is empty")
+	}
+
+	if src == dst {
+		return fmt.Errorf("Source and destination keys are the same")
+	}
+
+	bucket, err := o.openBucket(ctx, bucket_uri)
+
+	if err != nil {
+		return err
+	}
+
+	defer o.closeBucket(bucket)
+
+	src_r, err := bucket.NewReader(ctx, src, nil)
+
+	if gcerrors.Code(err) == gcerrors.NotFound {
+		return fmt.Errorf("%s does not exist, %w", src, ErrNotFound)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed to open %s, %w", src, err)
+	}
+
+	defer src_r.Close()
+
+	// Retrieve the ETag before reading the data so that any change after this point is detected when the merged data is committed
+
+	var dst_r io.ReadCloser
+
+	attrs, err := bucket.Attributes(ctx, dst)
+
+	switch {
+	case gcerrors.Code(err) == gcerrors.NotFound:
+		opts = append(opts, WithIfNotExists())
+	case err != nil:
+		return fmt.Errorf("Failed to retrieve attributes for %s, %w", dst, err)
+	default:
+
+		if attrs.ETag != "" {
+			opts = append(opts, WithIfMatch(attrs.ETag))
+		}
+
+		r, err := bucket.NewReader(ctx, dst, nil)
+
+		if err != nil {
+			return fmt.Errorf("Failed to open %s, %w", dst, err)
+		}
+
+		defer r.Close()
+		dst_r = r
+	}
+
+	wr, err := NewDeferred(ctx, bucket_uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create atomic writer, %w", err)
+	}
+
+	readers := []io.Reader{src_r}
+
+	if dst_r != nil {
+		readers = []io.Reader{dst_r, src_r}
+	}
+
+	_, err = io.Copy(wr, io.MultiReader(readers...))
+
+	if err != nil {
+		wr.Abort()
+		return fmt.Errorf("Failed to copy data, %w", err)
+	}
+
+	err = wr.Close(dst_key)
+
+	if err != nil {
+		return fmt.Errorf("Failed to commit %s, %w", dst, err)
+	}
+
+	return nil
+}
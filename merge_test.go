@@ -0,0 +1,101 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	for fname, body := range map[string]string{"a.log": "a\n", "b.log": "b\n"} {
+
+		err := os.WriteFile(filepath.Join(root, fname), []byte(body), 0644)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", fname, err)
+		}
+	}
+
+	for _, src := range []string{"a.log", "b.log"} {
+
+		err := Merge(ctx, bucket_uri, src, "all.log")
+
+		if err != nil {
+			t.Fatalf("Failed to merge %s, %v", src, err)
+		}
+	}
+
+	path := filepath.Join(root, "all.log")
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != "a\nb\n" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+
+	err = Merge(ctx, bucket_uri, "c.log", "all.log")
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	for fname, body := range map[string]string{"a.log": "a\n", "all.log": "x\n"} {
+
+		err := os.WriteFile(filepath.Join(root, fname), []byte(body), 0644)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", fname, err)
+		}
+	}
+
+	// Simulate another writer replacing all.log while the data is being merged
+
+	path := filepath.Join(root, "all.log")
+	changed := false
+
+	change := func(asFunc func(interface{}) bool) error {
+
+		if changed {
+			return nil
+		}
+
+		changed = true
+		return os.WriteFile(path, []byte("y\ny\n"), 0644)
+	}
+
+	err := Merge(ctx, bucket_uri, "a.log", "all.log", WithBeforeWrite(change))
+
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Expected ErrPreconditionFailed, got %v", err)
+	}
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != "y\ny\n" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+}
@@ -0,0 +1,131 @@
+// package metrics implements WriterEventListeners which record metrics for the lifecycle events emitted by AtomicWriter
+// instances. The PrometheusExporter records metrics using collectors from the Prometheus client library which it registers
+// itself. The Exporter records metrics using any values implementing the Counter and Observer interfaces. Metrics can be recorded
+// using OpenTelemetry with the otelmetrics package.
+package metrics
+
+import (
+	"github.com/sfomuseum/go-atomicwrite"
+	"sync"
+	"time"
+)
+
+// type Counter is an interface for counters. It is implemented by `prometheus.Counter`.
+type Counter interface {
+	// Add adds 'v', which must be positive, to the counter.
+	Add(v float64)
+}
+
+// type Observer is an interface for recording observations, like latencies. It is implemented by `prometheus.Histogram`
+// and `prometheus.Summary`.
+type Observer interface {
+	// Observe records 'v'.
+	Observe(v float64)
+}
+
+// type Metrics defines the metrics recorded by an Exporter instance. Any of them may be nil in which case they are not recorded.
+type Metrics struct {
+	// The number of writers whose data has been committed
+	Commits Counter
+	// The number of writers which have been aborted
+	Aborts Counter
+	// The number of bytes written
+	BytesWritten Counter
+	// The number of errors encountered committing or discarding data
+	Errors Counter
+	// The time, in seconds, between a writer being opened and its data being committed
+	CommitLatency Observer
+}
+
+// type Exporter implements the `atomicwrite.WriterEventListener` interface recording metrics for each event.
+type Exporter struct {
+	// The metrics to record
+	metrics *Metrics
+	// A mutex guarding opened
+	mu sync.Mutex
+	// The time each writer was opened, keyed by its writer ID
+	opened map[string]time.Time
+}
+
+var _ atomicwrite.WriterEventListener = (*Exporter)(nil)
+
+// NewExporter returns a new Exporter instance which records 'm'. The same Exporter instance may be registered with multiple
+// AtomicWriter instances, using the `atomicwrite.WithEventListener` option, and is safe for concurrent use.
+func NewExporter(m *Metrics) *Exporter {
+
+	e := &Exporter{
+		metrics: m,
+		opened:  make(map[string]time.Time),
+	}
+
+	return e
+}
+
+// OnEvent records the metrics for 'e'.
+func (ex *Exporter) OnEvent(e atomicwrite.WriterEvent) {
+
+	switch e.Kind {
+	case atomicwrite.EventOpened:
+
+		if ex.metrics.CommitLatency != nil {
+			ex.mu.Lock()
+			ex.opened[e.WriterID] = e.Time
+			ex.mu.Unlock()
+		}
+
+	case atomicwrite.EventWritten:
+
+		if ex.metrics.BytesWritten != nil && e.Bytes > 0 {
+			ex.metrics.BytesWritten.Add(float64(e.Bytes))
+		}
+
+	case atomicwrite.EventCommitted:
+
+		if ex.metrics.Commits != nil {
+			ex.metrics.Commits.Add(1)
+		}
+
+		if ex.metrics.CommitLatency != nil {
+
+			t, ok := ex.popOpened(e.WriterID)
+
+			if ok {
+				ex.metrics.CommitLatency.Observe(e.Time.Sub(t).Seconds())
+			}
+		}
+
+	case atomicwrite.EventAborted:
+
+		if ex.metrics.Aborts != nil {
+			ex.metrics.Aborts.Add(1)
+		}
+
+		ex.popOpened(e.WriterID)
+
+	case atomicwrite.EventStagingDeleted:
+
+		// Writers whose data could not be committed are never timed
+		ex.popOpened(e.WriterID)
+
+	case atomicwrite.EventError:
+
+		if ex.metrics.Errors != nil {
+			ex.metrics.Errors.Add(1)
+		}
+	}
+}
+
+// popOpened returns and removes the time the writer with ID 'id' was opened.
+func (ex *Exporter) popOpened(id string) (time.Time, bool) {
+
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	t, ok := ex.opened[id]
+
+	if ok {
+		delete(ex.opened, id)
+	}
+
+	return t, ok
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"context"
+	"github.com/sfomuseum/go-atomicwrite"
+	"testing"
+)
+
+type testCounter struct {
+	value float64
+}
+
+func (c *testCounter) Add(v float64) {
+	c.value += v
+}
+
+type testObserver struct {
+	values []float64
+}
+
+func (o *testObserver) Observe(v float64) {
+	o.values = append(o.values, v)
+}
+
+func TestExporter(t *testing.T) {
+
+	ctx := context.Background()
+
+	commits := &testCounter{}
+	aborts := &testCounter{}
+	bytes_written := &testCounter{}
+	latency := &testObserver{}
+
+	m := &Metrics{
+		Commits:       commits,
+		Aborts:        aborts,
+		BytesWritten:  bytes_written,
+		CommitLatency: latency,
+	}
+
+	ex := NewExporter(m)
+
+	for _, label := range []string{"close", "abort"} {
+
+		wr, err := atomicwrite.New(ctx, "mem://atomicwrite.txt", atomicwrite.WithEventListener(ex))
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		_, err = wr.Write([]byte("Hello world"))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		switch label {
+		case "abort":
+			err = wr.(*atomicwrite.AtomicWriter).Abort()
+		default:
+			err = wr.Close()
+		}
+
+		if err != nil {
+			t.Fatalf("Failed to %s writer, %v", label, err)
+		}
+	}
+
+	if commits.value != 1 {
+		t.Fatalf("Unexpected commits: %f", commits.value)
+	}
+
+	if aborts.value != 1 {
+		t.Fatalf("Unexpected aborts: %f", aborts.value)
+	}
+
+	if bytes_written.value != 22 {
+		t.Fatalf("Unexpected bytes written: %f", bytes_written.value)
+	}
+
+	if len(latency.values) != 1 || latency.values[0] < 0 {
+		t.Fatalf("Unexpected commit latencies: %v", latency.values)
+	}
+
+	if len(ex.opened) != 0 {
+		t.Fatalf("Expected no open writers, got %d", len(ex.opened))
+	}
+}
+
+func TestExporterFlush(t *testing.T) {
+
+	ctx := context.Background()
+
+	latency := &testObserver{}
+
+	m := &Metrics{
+		CommitLatency: latency,
+	}
+
+	ex := NewExporter(m)
+
+	for _, label := range []string{"close", "abort"} {
+
+		wr, err := atomicwrite.New(ctx, "mem://atomicwrite.txt", atomicwrite.WithEventListener(ex))
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		aw := wr.(*atomicwrite.AtomicWriter)
+
+		_, err = aw.Write([]byte("Hello world"))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		// Flushing changes the temporary path of the writer
+
+		err = aw.Flush()
+
+		if err != nil {
+			t.Fatalf("Failed to flush writer, %v", err)
+		}
+
+		switch label {
+		case "abort":
+			err = aw.Abort()
+		default:
+			err = aw.Close()
+		}
+
+		if err != nil {
+			t.Fatalf("Failed to %s writer, %v", label, err)
+		}
+	}
+
+	if len(latency.values) != 1 {
+		t.Fatalf("Unexpected commit latencies: %v", latency.values)
+	}
+
+	if len(ex.opened) != 0 {
+		t.Fatalf("Expected no open writers, got %d", len(ex.opened))
+	}
+}
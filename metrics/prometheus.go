@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sfomuseum/go-atomicwrite"
+)
+
+// type PrometheusExporter implements the `atomicwrite.WriterEventListener` interface recording metrics for each event using
+// collectors from the Prometheus client library.
+type PrometheusExporter struct {
+	// The number of writers whose data has been committed
+	commits prometheus.Counter
+	// The number of writers which have been aborted
+	aborts prometheus.Counter
+	// The number of bytes written
+	bytes_written prometheus.Counter
+	// The number of errors encountered committing or discarding data
+	errors prometheus.Counter
+	// The time, in seconds, between a writer being opened and its data being committed
+	commit_latency prometheus.Histogram
+	// The Exporter instance which records events using the collectors above
+	exporter *Exporter
+}
+
+var _ atomicwrite.WriterEventListener = (*PrometheusExporter)(nil)
+
+// NewPrometheusExporter returns a new PrometheusExporter instance whose collectors are registered with 'registry'. The
+// collectors are named "{PREFIX}_commits_total", "{PREFIX}_aborts_total", "{PREFIX}_bytes_written_total", "{PREFIX}_errors_total"
+// and "{PREFIX}_commit_latency_seconds" (a histogram) where {PREFIX} is 'prefix'. If 'prefix' is empty the names are not prefixed.
+// If 'registry' is nil the collectors are created but not registered. As with the `prometheus.MustRegister` function this
+// panics if the collectors can not be registered, for example because collectors with the same names are already registered.
+// The same PrometheusExporter instance may be registered with multiple AtomicWriter instances, using the
+// `atomicwrite.WithEventListener` option, and is safe for concurrent use.
+func NewPrometheusExporter(registry prometheus.Registerer, prefix string) *PrometheusExporter {
+
+	factory := promauto.With(registry)
+
+	ex := &PrometheusExporter{
+		commits: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "commits_total",
+			Help:      "The number of writers whose data has been committed.",
+		}),
+		aborts: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "aborts_total",
+			Help:      "The number of writers which have been aborted.",
+		}),
+		bytes_written: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "bytes_written_total",
+			Help:      "The number of bytes written.",
+		}),
+		errors: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "errors_total",
+			Help:      "The number of errors encountered committing or discarding data.",
+		}),
+		commit_latency: factory.NewHistogram(prometheus.HistogramOpts{
+			Namespace: prefix,
+			Name:      "commit_latency_seconds",
+			Help:      "The time between a writer being opened and its data being committed.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+
+	m := &Metrics{
+		Commits:       ex.commits,
+		Aborts:        ex.aborts,
+		BytesWritten:  ex.bytes_written,
+		Errors:        ex.errors,
+		CommitLatency: ex.commit_latency,
+	}
+
+	ex.exporter = NewExporter(m)
+
+	return ex
+}
+
+// OnEvent records the metrics for 'e'.
+func (ex *PrometheusExporter) OnEvent(e atomicwrite.WriterEvent) {
+	ex.exporter.OnEvent(e)
+}
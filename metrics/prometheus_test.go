@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sfomuseum/go-atomicwrite"
+	"testing"
+)
+
+func TestPrometheusExporter(t *testing.T) {
+
+	ctx := context.Background()
+
+	registry := prometheus.NewRegistry()
+
+	ex := NewPrometheusExporter(registry, "atomicwrite")
+
+	for _, label := range []string{"close", "abort"} {
+
+		wr, err := atomicwrite.New(ctx, "mem://atomicwrite.txt", atomicwrite.WithEventListener(ex))
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		_, err = wr.Write([]byte("Hello world"))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		switch label {
+		case "abort":
+			err = wr.(*atomicwrite.AtomicWriter).Abort()
+		default:
+			err = wr.Close()
+		}
+
+		if err != nil {
+			t.Fatalf("Failed to %s writer, %v", label, err)
+		}
+	}
+
+	if v := testutil.ToFloat64(ex.commits); v != 1 {
+		t.Fatalf("Unexpected commits: %f", v)
+	}
+
+	if v := testutil.ToFloat64(ex.aborts); v != 1 {
+		t.Fatalf("Unexpected aborts: %f", v)
+	}
+
+	if v := testutil.ToFloat64(ex.bytes_written); v != 22 {
+		t.Fatalf("Unexpected bytes written: %f", v)
+	}
+
+	if v := testutil.ToFloat64(ex.errors); v != 0 {
+		t.Fatalf("Unexpected errors: %f", v)
+	}
+
+	families, err := registry.Gather()
+
+	if err != nil {
+		t.Fatalf("Failed to gather metrics, %v", err)
+	}
+
+	names := make(map[string]bool)
+
+	for _, f := range families {
+
+		names[f.GetName()] = true
+
+		if f.GetName() == "atomicwrite_commit_latency_seconds" {
+
+			count := f.GetMetric()[0].GetHistogram().GetSampleCount()
+
+			if count != 1 {
+				t.Fatalf("Unexpected commit latency sample count: %d", count)
+			}
+		}
+	}
+
+	for _, name := range []string{"atomicwrite_commits_total", "atomicwrite_aborts_total", "atomicwrite_bytes_written_total", "atomicwrite_errors_total", "atomicwrite_commit_latency_seconds"} {
+
+		if !names[name] {
+			t.Fatalf("Expected %s to be registered", name)
+		}
+	}
+}
+
+func TestPrometheusExporterDuplicate(t *testing.T) {
+
+	registry := prometheus.NewRegistry()
+
+	NewPrometheusExporter(registry, "atomicwrite")
+
+	defer func() {
+
+		if recover() == nil {
+			t.Fatalf("Expected registering duplicate collectors to panic")
+		}
+	}()
+
+	NewPrometheusExporter(registry, "atomicwrite")
+}
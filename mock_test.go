@@ -0,0 +1,254 @@
+package atomicwrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/driver"
+	"gocloud.dev/gcerrors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mockBucket is a minimal in-memory gocloud.dev/blob driver used to test driver-specific behaviour. Its
+// `BeforeWrite` As function recognizes *http.Header, standing in for the request headers (or request
+// input structs) that drivers like s3blob and gcsblob expose, and records the headers for each key.
+type mockBucket struct {
+	mu      sync.Mutex
+	blobs   map[string][]byte
+	times   map[string]time.Time
+	headers map[string]http.Header
+}
+
+type mockWriter struct {
+	ctx    context.Context
+	bucket *mockBucket
+	key    string
+	header http.Header
+	buf    bytes.Buffer
+}
+
+type mockReader struct {
+	io.Reader
+	attrs *driver.ReaderAttributes
+}
+
+var errMockNotFound = errors.New("not found")
+
+func newMockBucket() (*blob.Bucket, *mockBucket) {
+
+	b := &mockBucket{
+		blobs:   make(map[string][]byte),
+		times:   make(map[string]time.Time),
+		headers: make(map[string]http.Header),
+	}
+
+	return blob.NewBucket(b), b
+}
+
+func (b *mockBucket) ErrorCode(err error) gcerrors.ErrorCode {
+
+	if errors.Is(err, errMockNotFound) {
+		return gcerrors.NotFound
+	}
+
+	return gcerrors.Unknown
+}
+
+func (b *mockBucket) As(i interface{}) bool {
+	return false
+}
+
+func (b *mockBucket) ErrorAs(err error, i interface{}) bool {
+	return false
+}
+
+func (b *mockBucket) Attributes(ctx context.Context, key string) (*driver.Attributes, error) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	body, ok := b.blobs[key]
+
+	if !ok {
+		return nil, errMockNotFound
+	}
+
+	attrs := &driver.Attributes{
+		Size:    int64(len(body)),
+		ModTime: b.times[key],
+	}
+
+	return attrs, nil
+}
+
+func (b *mockBucket) ListPaged(ctx context.Context, opts *driver.ListOptions) (*driver.ListPage, error) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	keys := make([]string, 0)
+
+	for k := range b.blobs {
+
+		if strings.HasPrefix(k, opts.Prefix) {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	page := &driver.ListPage{}
+
+	for _, k := range keys {
+
+		obj := &driver.ListObject{
+			Key:     k,
+			Size:    int64(len(b.blobs[k])),
+			ModTime: b.times[k],
+		}
+
+		page.Objects = append(page.Objects, obj)
+	}
+
+	return page, nil
+}
+
+func (b *mockBucket) NewRangeReader(ctx context.Context, key string, offset, length int64, opts *driver.ReaderOptions) (driver.Reader, error) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	body, ok := b.blobs[key]
+
+	if !ok {
+		return nil, errMockNotFound
+	}
+
+	end := int64(len(body))
+
+	if length >= 0 && offset+length < end {
+		end = offset + length
+	}
+
+	r := &mockReader{
+		Reader: bytes.NewReader(body[offset:end]),
+		attrs: &driver.ReaderAttributes{
+			Size:    int64(len(body)),
+			ModTime: b.times[key],
+		},
+	}
+
+	return r, nil
+}
+
+func (b *mockBucket) NewTypedWriter(ctx context.Context, key, contentType string, opts *driver.WriterOptions) (driver.Writer, error) {
+
+	w := &mockWriter{
+		ctx:    ctx,
+		bucket: b,
+		key:    key,
+		header: make(http.Header),
+	}
+
+	if opts.BeforeWrite != nil {
+
+		asFunc := func(i interface{}) bool {
+
+			p, ok := i.(*http.Header)
+
+			if !ok {
+				return false
+			}
+
+			*p = w.header
+			return true
+		}
+
+		err := opts.BeforeWrite(asFunc)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+func (b *mockBucket) Copy(ctx context.Context, dstKey, srcKey string, opts *driver.CopyOptions) error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	body, ok := b.blobs[srcKey]
+
+	if !ok {
+		return errMockNotFound
+	}
+
+	b.blobs[dstKey] = body
+	b.times[dstKey] = time.Now()
+	return nil
+}
+
+func (b *mockBucket) Delete(ctx context.Context, key string) error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.blobs[key]
+
+	if !ok {
+		return errMockNotFound
+	}
+
+	delete(b.blobs, key)
+	delete(b.times, key)
+	return nil
+}
+
+func (b *mockBucket) SignedURL(ctx context.Context, key string, opts *driver.SignedURLOptions) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (b *mockBucket) Close() error {
+	return nil
+}
+
+func (w *mockWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *mockWriter) Close() error {
+
+	err := w.ctx.Err()
+
+	if err != nil {
+		return err
+	}
+
+	w.bucket.mu.Lock()
+	defer w.bucket.mu.Unlock()
+
+	w.bucket.blobs[w.key] = w.buf.Bytes()
+	w.bucket.times[w.key] = time.Now()
+	w.bucket.headers[w.key] = w.header
+	return nil
+}
+
+func (r *mockReader) Close() error {
+	return nil
+}
+
+func (r *mockReader) Attributes() *driver.ReaderAttributes {
+	return r.attrs
+}
+
+func (r *mockReader) As(i interface{}) bool {
+	return false
+}
@@ -0,0 +1,171 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gocloud.dev/blob"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrNotFound is returned by the `Move` and `Clone` methods when the source key does not exist.
+var ErrNotFound = errors.New("Not found")
+
+// Move moves 'src_key' to 'dst_key' in the bucket defined by 'bucket_uri', replacing 'dst_key' if it already exists. If 'src_key'
+// does not exist an error wrapping ErrNotFound is returned. If the `WithIfNotExists` option is defined and 'dst_key' already exists
+// an error wrapping ErrAlreadyExists is returned. Only the `WithBucketOpener`, `WithBucketWrapper`, `WithFlock`, `WithIfNotExists`
+// and `WithKeyNormalizer` options are used.
+//
+// For local filesystem paths (and `file://` URIs) the file is renamed so readers of 'dst_key' will only ever see the previous data
+// or the new data. With the `WithIfNotExists` option the file is hard-linked to 'dst_key', which fails if 'dst_key' exists, and
+// then removed. Attributes written by the gocloud.dev/blob/fileblob package are moved afterwards. For all other buckets, and for
+// local paths when the `WithBucketOpener` or `WithBucketWrapper` options are defined, 'src_key' is copied to 'dst_key' (using the
+// bucket's native copy operation, which replaces 'dst_key' in a single step) and then deleted. If the copy fails nothing is
+// changed. If deleting 'src_key' fails an error is returned but 'dst_key' is kept, so data is never lost. As with `PutIfAbsent`
+// the `WithIfNotExists` option is checked immediately before copying for these buckets.
+func Move(ctx context.Context, bucket_uri string, src_key string, dst_key string, opts ...Option) error {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	src_key = normalizeKey(o.key_normalizers, src_key)
+	dst_key = normalizeKey(o.key_normalizers, dst_key)
+
+	if src_key == "" || dst_key == "" {
+		return fmt.Errorf("Key is empty")
+	}
+
+	if src_key == dst_key {
+		return fmt.Errorf("Source and destination keys are the same")
+	}
+
+	// Always acquire locks in the same order so that concurrent moves in opposite directions do not deadlock
+
+	keys := []string{src_key, dst_key}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+
+		unlock, err := o.lockKey(bucket_uri, k)
+
+		if err != nil {
+			return err
+		}
+
+		defer unlock()
+	}
+
+	// Only bypass the bucket when the caller has not asked for it to be opened or wrapped in some other way
+
+	if !o.custom_bucket_opener && len(o.bucket_wrappers) == 0 {
+
+		root, err := localBucketPath(bucket_uri)
+
+		if err == nil {
+
+			src_path, src_ok := localKeyPath(root, src_key)
+			dst_path, dst_ok := localKeyPath(root, dst_key)
+
+			if src_ok && dst_ok {
+				return moveLocal(src_path, dst_path, o.if_not_exists)
+			}
+		}
+	}
+
+	bucket, err := o.openBucket(ctx, bucket_uri)
+
+	if err != nil {
+		return err
+	}
+
+	defer o.closeBucket(bucket)
+
+	return moveBucket(ctx, bucket, src_key, dst_key, o.if_not_exists)
+}
+
+// moveLocal renames 'src_path' to 'dst_path', along with any attributes written by the gocloud.dev/blob/fileblob package. If
+// 'create_only' is true an error wrapping ErrAlreadyExists is returned if 'dst_path' exists.
+func moveLocal(src_path string, dst_path string, create_only bool) error {
+
+	info, err := os.Stat(src_path)
+
+	if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return fmt.Errorf("%s does not exist, %w", src_path, ErrNotFound)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed to stat %s, %w", src_path, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(dst_path), 0777)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create parent directory for %s, %w", dst_path, err)
+	}
+
+	if create_only {
+
+		err = os.Link(src_path, dst_path)
+
+		if os.IsExist(err) {
+			return fmt.Errorf("%s already exists, %w", dst_path, ErrAlreadyExists)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Failed to link %s to %s, %w", src_path, dst_path, err)
+		}
+
+		err = os.Remove(src_path)
+
+		if err != nil {
+			return fmt.Errorf("Failed to remove %s after linking it to %s, %w", src_path, dst_path, err)
+		}
+
+	} else {
+
+		err = os.Rename(src_path, dst_path)
+
+		if err != nil {
+			return fmt.Errorf("Failed to rename %s to %s, %w", src_path, dst_path, err)
+		}
+	}
+
+	src_attrs := src_path + fileblob_attrs_ext
+	dst_attrs := dst_path + fileblob_attrs_ext
+
+	err = os.Rename(src_attrs, dst_attrs)
+
+	if os.IsNotExist(err) {
+		err = os.Remove(dst_attrs)
+	}
+
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Failed to move attributes for %s, %w", src_path, err)
+	}
+
+	return nil
+}
+
+// moveBucket copies 'src_key' to 'dst_key' in 'bucket' and then deletes 'src_key'. If 'create_only' is true an error wrapping
+// ErrAlreadyExists is returned if 'dst_key' exists.
+func moveBucket(ctx context.Context, bucket *blob.Bucket, src_key string, dst_key string, create_only bool) error {
+
+	err := copyKey(ctx, bucket, src_key, dst_key, create_only)
+
+	if err != nil {
+		return err
+	}
+
+	err = bucket.Delete(ctx, src_key)
+
+	if err != nil {
+		return fmt.Errorf("Failed to delete %s after copying it to %s, %w", src_key, dst_key, err)
+	}
+
+	return nil
+}
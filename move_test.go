@@ -0,0 +1,201 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMove(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	for _, fname := range []string{"a.txt", "b.txt"} {
+
+		err := writeBytes(ctx, filepath.Join(root, fname), []byte(fname))
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", fname, err)
+		}
+	}
+
+	err := Move(ctx, bucket_uri, "a.txt", "b.txt", WithIfNotExists())
+
+	if !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+	}
+
+	err = Move(ctx, bucket_uri, "a.txt", "c/a.txt", WithIfNotExists())
+
+	if err != nil {
+		t.Fatalf("Failed to move a.txt, %v", err)
+	}
+
+	err = Move(ctx, bucket_uri, "c/a.txt", "b.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to move c/a.txt, %v", err)
+	}
+
+	err = Move(ctx, bucket_uri, "a.txt", "d.txt")
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	bucket, err := blob.OpenBucket(ctx, bucket_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to open bucket, %v", err)
+	}
+
+	defer bucket.Close()
+
+	v, err := bucket.ReadAll(ctx, "b.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to read b.txt, %v", err)
+	}
+
+	if string(v) != "a.txt" {
+		t.Fatalf("Unexpected body: %s", string(v))
+	}
+
+	for _, fname := range []string{"a.txt", "a.txt.attrs", filepath.Join("c", "a.txt"), filepath.Join("c", "a.txt.attrs")} {
+
+		_, err := os.Stat(filepath.Join(root, fname))
+
+		if !os.IsNotExist(err) {
+			t.Fatalf("Expected %s not to exist, %v", fname, err)
+		}
+	}
+}
+
+func TestMoveBucket(t *testing.T) {
+
+	ctx := context.Background()
+
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+
+	for _, key := range []string{"a.txt", "b.txt"} {
+
+		err := bucket.WriteAll(ctx, key, []byte(key), nil)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", key, err)
+		}
+	}
+
+	err := moveBucket(ctx, bucket, "a.txt", "b.txt", true)
+
+	if !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+	}
+
+	err = moveBucket(ctx, bucket, "a.txt", "b.txt", false)
+
+	if err != nil {
+		t.Fatalf("Failed to move a.txt, %v", err)
+	}
+
+	err = moveBucket(ctx, bucket, "a.txt", "b.txt", false)
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	v, err := bucket.ReadAll(ctx, "b.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to read b.txt, %v", err)
+	}
+
+	if string(v) != "a.txt" {
+		t.Fatalf("Unexpected body: %s", string(v))
+	}
+}
+
+func TestMoveWithBucketWrapper(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	wrapper := func(bucket *blob.Bucket) *blob.Bucket {
+		return blob.PrefixedBucket(bucket, "wrapped/")
+	}
+
+	err := writeBytes(ctx, filepath.Join(root, "a.txt"), []byte("a.txt"), WithBucketWrapper(wrapper))
+
+	if err != nil {
+		t.Fatalf("Failed to write a.txt, %v", err)
+	}
+
+	err = Move(ctx, bucket_uri, "a.txt", "b.txt", WithBucketWrapper(wrapper))
+
+	if err != nil {
+		t.Fatalf("Failed to move a.txt, %v", err)
+	}
+
+	v, err := os.ReadFile(filepath.Join(root, "wrapped", "b.txt"))
+
+	if err != nil {
+		t.Fatalf("Failed to read wrapped b.txt, %v", err)
+	}
+
+	if string(v) != "a.txt" {
+		t.Fatalf("Unexpected body: %s", string(v))
+	}
+
+	_, err = os.Stat(filepath.Join(root, "wrapped", "a.txt"))
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected wrapped a.txt not to exist, %v", err)
+	}
+}
+
+func TestMoveWithBucketOpener(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	bucket_uri := "file://" + root
+
+	mem := memblob.OpenBucket(nil)
+	defer mem.Close()
+
+	opener := func(ctx context.Context, uri string) (*blob.Bucket, error) {
+		return mem, nil
+	}
+
+	err := mem.WriteAll(ctx, "a.txt", []byte("a.txt"), nil)
+
+	if err != nil {
+		t.Fatalf("Failed to write a.txt, %v", err)
+	}
+
+	err = Move(ctx, bucket_uri, "a.txt", "b.txt", WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to move a.txt, %v", err)
+	}
+
+	v, err := mem.ReadAll(ctx, "b.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to read b.txt, %v", err)
+	}
+
+	if string(v) != "a.txt" {
+		t.Fatalf("Unexpected body: %s", string(v))
+	}
+}
@@ -0,0 +1,99 @@
+package atomicwrite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// type Format is an interface for encoding values in a particular file format.
+type Format interface {
+	// Extension returns the file extension, including the leading ".", for the format. For example ".json".
+	Extension() string
+	// Encode encodes 'v' in the format.
+	Encode(v interface{}) ([]byte, error)
+}
+
+// type JSONFormat implements the Format interface for JSON-encoded data.
+type JSONFormat struct {
+	Format
+}
+
+// Extension returns ".json".
+func (f *JSONFormat) Extension() string {
+	return ".json"
+}
+
+// Encode encodes 'v' using the `json.Marshal` method.
+func (f *JSONFormat) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// WriteMultiFormat encodes 'v' using each of 'formats' and atomically writes the results to URIs derived by appending each
+// format's extension to 'base_uri' (preserving any query parameters). For example "file:///data/example" and a JSONFormat
+// instance would write to "file:///data/example.json". 'v' is encoded and written to an intermediate temporary file for every
+// format before anything is committed: if any of these steps fail all the writers are aborted and nothing is written. Writers
+// are then committed sequentially; this is best-effort so if committing one format fails the remaining formats are still
+// committed (and formats which have already been committed are not rolled back) and an error listing each failure is returned.
+// Other formats, like Parquet, are supported by implementing the Format interface.
+func WriteMultiFormat(ctx context.Context, base_uri string, v interface{}, formats []Format, opts ...Option) error {
+
+	writers := make([]*AtomicWriter, 0, len(formats))
+
+	abort := func() {
+
+		for _, wr := range writers {
+			wr.Abort()
+		}
+	}
+
+	for _, f := range formats {
+
+		body, err := f.Encode(v)
+
+		if err != nil {
+			abort()
+			return fmt.Errorf("Failed to encode value for %s, %w", f.Extension(), err)
+		}
+
+		uri, err := sidecarURI(base_uri, f.Extension())
+
+		if err != nil {
+			abort()
+			return fmt.Errorf("Failed to derive URI for %s, %w", f.Extension(), err)
+		}
+
+		wr, err := newAtomicWriter(ctx, uri, opts...)
+
+		if err != nil {
+			abort()
+			return fmt.Errorf("Failed to create atomic writer for %s, %w", uri, err)
+		}
+
+		writers = append(writers, wr)
+
+		_, err = wr.Write(body)
+
+		if err != nil {
+			abort()
+			return fmt.Errorf("Failed to write data for %s, %w", uri, err)
+		}
+	}
+
+	errs := make(multiError, 0)
+
+	for _, wr := range writers {
+
+		err := wr.Close()
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed to commit %s, %w", wr.final_path, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
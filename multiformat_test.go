@@ -0,0 +1,98 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testTextFormat struct {
+	Format
+	err error
+}
+
+func (f *testTextFormat) Extension() string {
+	return ".txt"
+}
+
+func (f *testTextFormat) Encode(v interface{}) ([]byte, error) {
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return []byte(fmt.Sprintf("%v", v)), nil
+}
+
+func TestWriteMultiFormat(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	v := map[string]string{
+		"message": HELLO_WORLD,
+	}
+
+	formats := []Format{
+		&JSONFormat{},
+		&testTextFormat{},
+	}
+
+	err := WriteMultiFormat(ctx, filepath.Join(root, "atomicwrite"), v, formats)
+
+	if err != nil {
+		t.Fatalf("Failed to write formats, %v", err)
+	}
+
+	expected := map[string]string{
+		"atomicwrite.json": `{"message":"Hello world"}`,
+		"atomicwrite.txt":  "map[message:Hello world]",
+	}
+
+	for fname, body := range expected {
+
+		path := filepath.Join(root, fname)
+
+		v, err := os.ReadFile(path)
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", path, err)
+		}
+
+		if string(v) != body {
+			t.Fatalf("Unexpected body for %s: %s", fname, string(v))
+		}
+	}
+}
+
+func TestWriteMultiFormatError(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	formats := []Format{
+		&JSONFormat{},
+		&testTextFormat{err: errors.New("Failed")},
+	}
+
+	err := WriteMultiFormat(ctx, filepath.Join(root, "atomicwrite"), HELLO_WORLD, formats)
+
+	if err == nil {
+		t.Fatalf("Expected write to fail")
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no files, got %d", len(entries))
+	}
+}
@@ -0,0 +1,58 @@
+package atomicwrite
+
+import (
+	"gocloud.dev/blob"
+	"strings"
+)
+
+// DEFAULT_MULTIPART_PART_SIZE is the default size, in bytes, of the buffer used by `blob.Writer` instances for remote buckets.
+// For drivers which upload data in parts (for example S3) this is the size of each part; 8MB balances memory usage against
+// the number of API calls.
+const DEFAULT_MULTIPART_PART_SIZE int64 = 8 * 1024 * 1024
+
+// WithMultipartPartSize returns an Option specifying the size, in bytes, of the buffer (`blob.WriterOptions.BufferSize`) used
+// by the `blob.Writer` instances that write the intermediate temporary file and the final path. For StreamingAtomicWriter
+// instances which write directly to the final path this determines the boundaries of each part of a multipart upload. Drivers
+// may impose their own minimum; for S3 it is 5MB. If unset (or less than or equal to zero) the default is the value of any
+// `BufferSize` assigned using the `WithWriterOptions` option, or DEFAULT_MULTIPART_PART_SIZE for remote buckets. Local
+// (`file://` and `mem://`) buckets ignore this option.
+func WithMultipartPartSize(n int64) Option {
+
+	return func(o *options) {
+		o.multipart_part_size = n
+	}
+}
+
+// withPartSize returns a copy of 'writer_opts' whose `BufferSize` is the part size for the bucket defined by 'bucket_uri'. If
+// there is no part size to assign 'writer_opts' is returned as-is.
+func (o *options) withPartSize(writer_opts *blob.WriterOptions, bucket_uri string) *blob.WriterOptions {
+
+	part_size := o.multipart_part_size
+
+	if part_size <= 0 && o.writer_opts != nil && o.writer_opts.BufferSize > 0 {
+		part_size = int64(o.writer_opts.BufferSize)
+	}
+
+	if part_size <= 0 {
+
+		if isLocalBucketURI(bucket_uri) {
+			return writer_opts
+		}
+
+		part_size = DEFAULT_MULTIPART_PART_SIZE
+	}
+
+	sized_opts := &blob.WriterOptions{}
+
+	if writer_opts != nil {
+		*sized_opts = *writer_opts
+	}
+
+	sized_opts.BufferSize = int(part_size)
+	return sized_opts
+}
+
+// isLocalBucketURI returns true if 'bucket_uri' is a local (`file://` or `mem://`) bucket URI.
+func isLocalBucketURI(bucket_uri string) bool {
+	return strings.HasPrefix(bucket_uri, "file://") || strings.HasPrefix(bucket_uri, "mem://")
+}
@@ -0,0 +1,95 @@
+package atomicwrite
+
+import (
+	"context"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"testing"
+)
+
+func TestWithMultipartPartSize(t *testing.T) {
+
+	ctx := context.Background()
+
+	mem := memblob.OpenBucket(nil)
+	defer mem.Close()
+
+	// Pretend that the in-memory bucket is an S3 bucket
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return mem, nil
+	}
+
+	tests := map[int64][]Option{
+		DEFAULT_MULTIPART_PART_SIZE: {WithBucketOpener(opener)},
+		16 * 1024 * 1024:            {WithBucketOpener(opener), WithMultipartPartSize(16 * 1024 * 1024)},
+		6 * 1024 * 1024:             {WithBucketOpener(opener), WithWriterOptions(&blob.WriterOptions{BufferSize: 6 * 1024 * 1024})},
+	}
+
+	for expected, opts := range tests {
+
+		aw, err := newAtomicWriter(ctx, "s3://example/test.txt", opts...)
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		if aw.staging_opts == nil || int64(aw.staging_opts.BufferSize) != expected {
+			t.Fatalf("Expected staging buffer size %d, got %v", expected, aw.staging_opts)
+		}
+
+		if aw.final_opts == nil || int64(aw.final_opts.BufferSize) != expected {
+			t.Fatalf("Expected final buffer size %d, got %v", expected, aw.final_opts)
+		}
+
+		err = aw.Abort()
+
+		if err != nil {
+			t.Fatalf("Failed to abort writer, %v", err)
+		}
+	}
+
+	sw, err := NewStreaming(ctx, "s3://example/test.txt", WithBucketOpener(opener), WithMultipartPartSize(5*1024*1024))
+
+	if err != nil {
+		t.Fatalf("Failed to create streaming writer, %v", err)
+	}
+
+	if !sw.Streaming() {
+		t.Fatalf("Expected part size not to require an intermediate temporary file")
+	}
+
+	_, err = sw.Write([]byte("hello world"))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	err = sw.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close streaming writer, %v", err)
+	}
+}
+
+func TestWithMultipartPartSizeLocal(t *testing.T) {
+
+	o := defaultOptions()
+
+	if o.withPartSize(nil, "file:///tmp") != nil {
+		t.Fatalf("Expected default part size not to be assigned to local buckets")
+	}
+
+	WithMultipartPartSize(1024)(o)
+
+	writer_opts := &blob.WriterOptions{ContentType: "text/plain"}
+	sized_opts := o.withPartSize(writer_opts, "file:///tmp")
+
+	if sized_opts.BufferSize != 1024 || sized_opts.ContentType != "text/plain" {
+		t.Fatalf("Unexpected writer options: %v", sized_opts)
+	}
+
+	if writer_opts.BufferSize != 0 {
+		t.Fatalf("Expected original writer options not to be modified")
+	}
+}
@@ -0,0 +1,63 @@
+package atomicwrite
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// WriteNDJSON atomically writes 'records' to 'uri' as newline-delimited JSON, with each record encoded as a separate JSON
+// value followed by a newline. If any record fails to encode the writer is aborted and nothing is written to 'uri'.
+func WriteNDJSON(ctx context.Context, uri string, records []interface{}, opts ...Option) error {
+
+	fn := func(enc func(v interface{}) error) error {
+
+		for idx, r := range records {
+
+			err := enc(r)
+
+			if err != nil {
+				return fmt.Errorf("Failed to encode record at offset %d, %w", idx, err)
+			}
+		}
+
+		return nil
+	}
+
+	return EncodeNDJSON(ctx, uri, fn, opts...)
+}
+
+// EncodeNDJSON atomically writes newline-delimited JSON to 'uri' by invoking 'fn' with an 'enc' function which encodes a
+// single record (followed by a newline) each time it is called. Data is committed once 'fn' returns. If 'fn' returns an
+// error the writer is aborted and nothing is written to 'uri'.
+func EncodeNDJSON(ctx context.Context, uri string, fn func(enc func(v interface{}) error) error, opts ...Option) error {
+
+	wr, err := New(ctx, uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create atomic writer, %w", err)
+	}
+
+	buf := bufio.NewWriter(wr)
+	enc := json.NewEncoder(buf)
+
+	err = fn(enc.Encode)
+
+	if err == nil {
+		err = buf.Flush()
+	}
+
+	if err != nil {
+		wr.(*AtomicWriter).Abort()
+		return fmt.Errorf("Failed to write NDJSON, %w", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close atomic writer, %w", err)
+	}
+
+	return nil
+}
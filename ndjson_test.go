@@ -0,0 +1,112 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteNDJSON(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.ndjson")
+
+	records := []interface{}{
+		map[string]interface{}{"id": 1},
+		map[string]interface{}{"id": 2, "name": "sfo"},
+	}
+
+	err := WriteNDJSON(ctx, path, records)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	expected := "{\"id\":1}\n{\"id\":2,\"name\":\"sfo\"}\n"
+
+	if string(v) != expected {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+
+	err = WriteNDJSON(ctx, path, []interface{}{map[string]interface{}{"id": 3}, make(chan int)})
+
+	if err == nil {
+		t.Fatalf("Expected unencodable record to fail")
+	}
+
+	v, err = os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != expected {
+		t.Fatalf("Failed write modified %s: %q", path, string(v))
+	}
+}
+
+func TestEncodeNDJSON(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.ndjson")
+
+	fn := func(enc func(v interface{}) error) error {
+
+		for i := 0; i < 3; i++ {
+
+			err := enc(map[string]int{"id": i})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err := EncodeNDJSON(ctx, path, fn)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != "{\"id\":0}\n{\"id\":1}\n{\"id\":2}\n" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+
+	other := filepath.Join(root, "other.ndjson")
+
+	err = EncodeNDJSON(ctx, other, func(enc func(v interface{}) error) error {
+		return fmt.Errorf("Nope")
+	})
+
+	if err == nil {
+		t.Fatalf("Expected callback error to fail write")
+	}
+
+	_, err = os.Stat(other)
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected %s not to exist, %v", other, err)
+	}
+}
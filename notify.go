@@ -0,0 +1,45 @@
+package atomicwrite
+
+// type bytesWrittenNotifier defines a channel to send the running total of bytes written to each time it crosses a multiple of threshold.
+type bytesWrittenNotifier struct {
+	ch        chan<- int64
+	threshold int64
+}
+
+// WithBytesWrittenNotify returns an Option which sends the running total of bytes written to 'ch' each time it crosses a
+// multiple of 'threshold'. Sends are non-blocking: if 'ch' is full the notification is dropped so that writes are never
+// blocked. This is useful for driving progress bars or logging without polling. 'threshold' must be greater than zero
+// otherwise the option is ignored. This option may be specified multiple times.
+func WithBytesWrittenNotify(ch chan<- int64, threshold int64) Option {
+
+	return func(o *options) {
+
+		if threshold <= 0 {
+			return
+		}
+
+		n := &bytesWrittenNotifier{
+			ch:        ch,
+			threshold: threshold,
+		}
+
+		o.notifiers = append(o.notifiers, n)
+	}
+}
+
+// notifyBytesWritten sends 'total' to each notifier whose threshold was crossed going from 'previous' to 'total' bytes written.
+func (aw *AtomicWriter) notifyBytesWritten(previous int64, total int64) {
+
+	for _, n := range aw.notifiers {
+
+		if previous/n.threshold == total/n.threshold {
+			continue
+		}
+
+		select {
+		case n.ch <- total:
+		default:
+			// pass
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package atomicwrite
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithBytesWrittenNotify(t *testing.T) {
+
+	ctx := context.Background()
+
+	ch := make(chan int64, 10)
+	full := make(chan int64)
+
+	wr, err := New(ctx, "mem://atomicwrite.txt", WithBytesWrittenNotify(ch, 10), WithBytesWrittenNotify(full, 1))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	defer wr.Close()
+
+	for _, s := range []string{"12345", "67890", "abc", "defghijklmnopqrstuvwxyz", "!"} {
+
+		_, err := wr.Write([]byte(s))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+	}
+
+	close(ch)
+
+	expected := []int64{10, 36}
+	i := 0
+
+	for total := range ch {
+
+		if i >= len(expected) || total != expected[i] {
+			t.Fatalf("Unexpected notification at position %d: %d", i, total)
+		}
+
+		i += 1
+	}
+
+	if i != len(expected) {
+		t.Fatalf("Expected %d notifications, got %d", len(expected), i)
+	}
+}
@@ -0,0 +1,349 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+	"log"
+	"time"
+)
+
+// type BucketOpenerFunc is a function used to open the `blob.Bucket` instance that AtomicWriter instances write data to.
+// 'bucket_uri' is the bucket URI derived from the URI passed to the `New` constructor.
+type BucketOpenerFunc func(ctx context.Context, bucket_uri string) (*blob.Bucket, error)
+
+// type BucketWrapperFunc is a function used to transform a `blob.Bucket` instance after it has been opened, for example to apply
+// custom rate-limiting or request signing to bucket operations.
+type BucketWrapperFunc func(bucket *blob.Bucket) *blob.Bucket
+
+// type BeforeWriteFunc is a callback function invoked before any data is written by an underlying `blob.Writer` instance.
+// 'asFunc' converts its argument to driver-specific types. See the documentation for `blob.WriterOptions.BeforeWrite` and
+// https://gocloud.dev/concepts/as/ for details.
+type BeforeWriteFunc func(asFunc func(interface{}) bool) error
+
+// type Option is a function used to configure AtomicWriter instances created by the `New` constructor.
+type Option func(*options)
+
+// type options defines the configuration details for an AtomicWriter instance.
+type options struct {
+	// The custom options used to create the underlying `blob.Writer` instance
+	writer_opts *blob.WriterOptions
+	// The function used to open the underlying `blob.Bucket` instance
+	bucket_opener BucketOpenerFunc
+	// A boolean flag indicating whether bucket_opener was defined by the `WithBucketOpener` option, in which case the buckets it returns are owned by the caller
+	custom_bucket_opener bool
+	// Zero or more listeners to receive lifecycle events
+	listeners []WriterEventListener
+	// Zero or more callback functions invoked before data is written to both the temporary and final paths
+	before_write []BeforeWriteFunc
+	// Zero or more callback functions invoked before data is written to the final path
+	final_before_write []BeforeWriteFunc
+	// The size of the buffer used to copy data from the temporary file to the final path
+	copy_buffer_size int
+	// The URI of the bucket whose total size is limited by quota_max
+	quota_uri string
+	// The maximum total size, in bytes, of all the objects in the bucket defined by quota_uri
+	quota_max int64
+	// The expected size, in bytes, of the data being written
+	size_hint int64
+	// The logger used for debugging messages
+	logger *log.Logger
+	// Zero or more context keys whose values are merged in to the metadata for the final path
+	metadata_keys []interface{}
+	// Zero or more additional URIs that data is copied to after it has been copied to the final path
+	additional_uris []string
+	// The interval at which RotatingWriter instances rotate writers
+	rotation_interval time.Duration
+	// The interval at which the `Flush` method is invoked in the background
+	flush_interval time.Duration
+	// Zero or more channels to notify as the running total of bytes written crosses a threshold
+	notifiers []*bytesWrittenNotifier
+	// Zero or more standard HTTP headers to assign to the final path
+	final_headers map[string]string
+	// A boolean flag indicating whether to acquire an exclusive lock before copying data to the final path
+	flock bool
+	// Zero or more functions used to transform the key of the final path
+	key_normalizers []KeyNormalizerFunc
+	// The HTTP request that data is streamed to, if any
+	tee_http *teeHTTPConfig
+	// The key used to encrypt data with AES-GCM, if any
+	encryption_key []byte
+	// The function used to create an encrypting writer, if any
+	encrypt_writer EncryptWriterFunc
+	// The maximum size, in bytes, of each shard if data is split in to shards
+	shard_max_size int64
+	// The template used to derive the URI for each shard, if data is split in to shards
+	shard_template string
+	// A boolean flag indicating whether to only commit data if the final path does not exist
+	if_not_exists bool
+	// The ETag the final path must have for data to be committed, if any
+	if_match string
+	// The line ending used by the `WriteLines` and `WriteLinesChan` methods, if not the default
+	line_ending string
+	// A boolean flag indicating whether to validate that data is a well-formed Parquet file before it is committed
+	parquet_validation bool
+	// The time after which the final path may be deleted by the `DeleteExpired` method, if any
+	expires_at time.Time
+	// Zero or more functions used to validate URIs before any buckets are opened
+	uri_validators []URIValidatorFunc
+	// Zero or more functions used to transform buckets after they have been opened by bucket_opener
+	bucket_wrappers []BucketWrapperFunc
+	// The size, in bytes, of the buffer used by the underlying `blob.Writer` instances, if not the default
+	multipart_part_size int64
+}
+
+// defaultOptions returns an options instance with default values.
+func defaultOptions() *options {
+
+	o := &options{
+		bucket_opener: blob.OpenBucket,
+	}
+
+	return o
+}
+
+// WithWriterOptions returns an Option specifying 'writer_opts' as the custom options used to create the
+// underlying `blob.Writer` instance. The content headers (ContentType, CacheControl and so on) and Metadata
+// are also applied to the final path; other properties, like BeforeWrite, only apply to the temporary file.
+func WithWriterOptions(writer_opts *blob.WriterOptions) Option {
+
+	return func(o *options) {
+		o.writer_opts = writer_opts
+	}
+}
+
+// WithBucketOpener returns an Option specifying 'fn' as the function used to open the underlying `blob.Bucket`
+// instance rather than the default `blob.OpenBucket` function. This is useful when a bucket needs to be opened
+// with details that can not be encoded in a URI, for example an S3 bucket using an explicit `aws.Config` instance
+// with a pre-configured credentials provider (as is the case for EKS workloads using IAM Roles for Service Accounts).
+// Buckets returned by 'fn' are owned by the caller and are never closed, so 'fn' may return the same (shared or cached)
+// instance on every call; the caller is responsible for closing it once all writers are done.
+func WithBucketOpener(fn BucketOpenerFunc) Option {
+
+	return func(o *options) {
+		o.bucket_opener = fn
+		o.custom_bucket_opener = true
+	}
+}
+
+// WithBucketWrapper returns an Option which registers 'fn' to transform each `blob.Bucket` instance after it has been opened
+// (using the function defined by the `WithBucketOpener` option) and before it is used. Since `blob.Bucket` is a concrete type this
+// is the way to apply custom rate-limiting, credential rotation or request signing to bucket operations, for example by returning
+// `blob.NewBucket` with a wrapped driver. Wrappers are applied to every bucket opened on behalf of an AtomicWriter instance,
+// including those for the `WithAdditionalPaths` and `WithQuota` options. This option may be specified multiple times; wrappers
+// are applied in the order they were registered.
+func WithBucketWrapper(fn BucketWrapperFunc) Option {
+
+	return func(o *options) {
+		o.bucket_wrappers = append(o.bucket_wrappers, fn)
+	}
+}
+
+// wrappedBucketOpener returns a BucketOpenerFunc which opens buckets using the function defined by the `WithBucketOpener` option
+// and then transforms them using the functions registered by the `WithBucketWrapper` option.
+func (o *options) wrappedBucketOpener() BucketOpenerFunc {
+
+	if len(o.bucket_wrappers) == 0 {
+		return o.bucket_opener
+	}
+
+	opener := o.bucket_opener
+	wrappers := o.bucket_wrappers
+
+	return func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+
+		bucket, err := opener(ctx, bucket_uri)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fn := range wrappers {
+
+			bucket = fn(bucket)
+
+			if bucket == nil {
+				return nil, fmt.Errorf("Bucket wrapper returned nil bucket")
+			}
+		}
+
+		return bucket, nil
+	}
+}
+
+// WithBeforeWrite returns an Option which registers 'fn' to be invoked before data is written to both the intermediate
+// temporary file and the final path. This is how driver-specific settings, which need to be consistent for both writes,
+// are applied. For example, to write to a GCS bucket using a customer-managed encryption key:
+//
+//	fn := func(asFunc func(interface{}) bool) error {
+//		var w *storage.Writer
+//		if asFunc(&w) {
+//			w.KMSKeyName = "projects/P/locations/L/keyRings/R/cryptoKeys/K"
+//		}
+//		return nil
+//	}
+//
+// Callback functions should ignore driver-specific types they do not recognize (that is, when 'asFunc' returns false) so that
+// they are silently ignored by other drivers. This option may be specified multiple times; callbacks are invoked in the order
+// they were registered and after any `BeforeWrite` callback defined by the `WithWriterOptions` option (for the temporary file).
+func WithBeforeWrite(fn BeforeWriteFunc) Option {
+
+	return func(o *options) {
+		o.before_write = append(o.before_write, fn)
+	}
+}
+
+// WithFinalBeforeWrite returns an Option which registers 'fn' to be invoked before data is written to the final path
+// but not the intermediate temporary file. This is how driver-specific settings that only apply to the final object, for
+// example S3 object tags or access controls, are applied. Callbacks are invoked after any callbacks registered using the
+// `WithBeforeWrite` option. This option may be specified multiple times.
+func WithFinalBeforeWrite(fn BeforeWriteFunc) Option {
+
+	return func(o *options) {
+		o.final_before_write = append(o.final_before_write, fn)
+	}
+}
+
+// WithCopyBufferSize returns an Option specifying the size, in bytes, of the buffer used to copy data from the intermediate
+// temporary file to the final path when the `Close` method is invoked. If unset (or less than or equal to zero) the default
+// is 32KB for local (`file://` and `mem://`) buckets and 1MB for all other buckets.
+func WithCopyBufferSize(n int) Option {
+
+	return func(o *options) {
+		o.copy_buffer_size = n
+	}
+}
+
+// WithLogger returns an Option specifying 'logger' as the logger used for debugging messages, for example each time a candidate
+// path for the intermediate temporary file already exists and a new path is tried. By default debugging messages are not logged.
+func WithLogger(logger *log.Logger) Option {
+
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithMetadataKey returns an Option which registers 'key' as a context key. If the context passed to the `New` constructor
+// contains a `map[string]string` value for 'key' it is merged in to the metadata (`blob.WriterOptions.Metadata`) for the final
+// path. This allows middleware, for example HTTP handlers, to add per-request metadata like request or user IDs to the objects
+// being written. Values which are not a `map[string]string` are ignored. This option may be specified multiple times; if the
+// same metadata key is defined by multiple context values the value for the last registered context key wins.
+func WithMetadataKey(key interface{}) Option {
+
+	return func(o *options) {
+		o.metadata_keys = append(o.metadata_keys, key)
+	}
+}
+
+// stagingWriterOptions returns the `blob.WriterOptions` used to create the `blob.Writer` instance for the intermediate temporary file.
+func (o *options) stagingWriterOptions() *blob.WriterOptions {
+
+	if len(o.before_write) == 0 {
+		return o.writer_opts
+	}
+
+	writer_opts := &blob.WriterOptions{}
+
+	if o.writer_opts != nil {
+		*writer_opts = *o.writer_opts
+	}
+
+	fns := make([]BeforeWriteFunc, 0)
+
+	if writer_opts.BeforeWrite != nil {
+		fns = append(fns, writer_opts.BeforeWrite)
+	}
+
+	fns = append(fns, o.before_write...)
+
+	writer_opts.BeforeWrite = chainBeforeWrite(fns)
+	return writer_opts
+}
+
+// finalWriterOptions returns the `blob.WriterOptions` used to create the `blob.Writer` instance for the final path. The content
+// headers and metadata defined by the `WithWriterOptions` option are carried over. Metadata is read from 'ctx' for any keys
+// registered using the `WithMetadataKey` option.
+func (o *options) finalWriterOptions(ctx context.Context) (*blob.WriterOptions, error) {
+
+	fns := make([]BeforeWriteFunc, 0)
+	fns = append(fns, o.before_write...)
+	fns = append(fns, o.final_before_write...)
+
+	metadata := make(map[string]string)
+
+	if o.writer_opts != nil {
+
+		for k, v := range o.writer_opts.Metadata {
+			metadata[k] = v
+		}
+	}
+
+	for _, k := range o.metadata_keys {
+
+		v, ok := ctx.Value(k).(map[string]string)
+
+		if !ok {
+			continue
+		}
+
+		for mk, mv := range v {
+			metadata[mk] = mv
+		}
+	}
+
+	if !o.expires_at.IsZero() {
+		metadata[EXPIRES_AT_METADATA_KEY] = o.expires_at.UTC().Format(time.RFC3339)
+	}
+
+	if len(fns) == 0 && len(metadata) == 0 && len(o.final_headers) == 0 && o.writer_opts == nil {
+		return nil, nil
+	}
+
+	writer_opts := &blob.WriterOptions{}
+
+	// Attributes describing the data are carried over from the options defined by the `WithWriterOptions` option. Other
+	// properties, like BeforeWrite callbacks, only apply to the intermediate temporary file.
+
+	if o.writer_opts != nil {
+		writer_opts.CacheControl = o.writer_opts.CacheControl
+		writer_opts.ContentDisposition = o.writer_opts.ContentDisposition
+		writer_opts.ContentEncoding = o.writer_opts.ContentEncoding
+		writer_opts.ContentLanguage = o.writer_opts.ContentLanguage
+		writer_opts.ContentType = o.writer_opts.ContentType
+	}
+
+	if len(fns) > 0 {
+		writer_opts.BeforeWrite = chainBeforeWrite(fns)
+	}
+
+	if len(metadata) > 0 {
+		writer_opts.Metadata = metadata
+	}
+
+	err := applyHeaders(writer_opts, o.final_headers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return writer_opts, nil
+}
+
+// chainBeforeWrite returns a single `blob.WriterOptions.BeforeWrite` callback which invokes each of 'fns' in order,
+// stopping at the first error.
+func chainBeforeWrite(fns []BeforeWriteFunc) func(asFunc func(interface{}) bool) error {
+
+	return func(asFunc func(interface{}) bool) error {
+
+		for _, fn := range fns {
+
+			err := fn(asFunc)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,315 @@
+package atomicwrite
+
+import (
+	"context"
+	"gocloud.dev/blob"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithBeforeWrite(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	count := 0
+
+	fn := func(asFunc func(interface{}) bool) error {
+
+		var f *os.File
+
+		if !asFunc(&f) {
+			t.Fatalf("Expected fileblob writer to be convertible to *os.File")
+		}
+
+		count += 1
+		return nil
+	}
+
+	wr, err := New(ctx, path, WithBeforeWrite(fn))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	// Once for the temporary file and once for the final path
+
+	if count != 2 {
+		t.Fatalf("Expected callback to be invoked twice, got %d", count)
+	}
+}
+
+func TestWithBeforeWriteHeaders(t *testing.T) {
+
+	ctx := context.Background()
+
+	bucket, mock := newMockBucket()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return bucket, nil
+	}
+
+	// This is the equivalent of setting the ServerSideEncryption and SSEKMSKeyId
+	// properties on the s3manager.UploadInput instance used by s3blob writers
+
+	sse := func(asFunc func(interface{}) bool) error {
+
+		var h http.Header
+
+		if asFunc(&h) {
+			h.Set("x-amz-server-side-encryption", "aws:kms")
+			h.Set("x-amz-server-side-encryption-aws-kms-key-id", "example-key")
+		}
+
+		return nil
+	}
+
+	wr, err := New(ctx, "mock://bucket/atomicwrite.txt", WithBucketOpener(opener), WithBeforeWrite(sse))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	atomic_path := wr.(*AtomicWriter).atomic_path
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	// Both the temporary file and the final path must use the same encryption settings
+
+	for _, key := range []string{atomic_path, "atomicwrite.txt"} {
+
+		h, ok := mock.headers[key]
+
+		if !ok {
+			t.Fatalf("No headers recorded for %s", key)
+		}
+
+		if h.Get("x-amz-server-side-encryption") != "aws:kms" {
+			t.Fatalf("Unexpected server-side encryption header for %s: %s", key, h.Get("x-amz-server-side-encryption"))
+		}
+
+		if h.Get("x-amz-server-side-encryption-aws-kms-key-id") != "example-key" {
+			t.Fatalf("Unexpected KMS key ID header for %s: %s", key, h.Get("x-amz-server-side-encryption-aws-kms-key-id"))
+		}
+	}
+}
+
+func TestWithFinalBeforeWrite(t *testing.T) {
+
+	ctx := context.Background()
+
+	bucket, mock := newMockBucket()
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return bucket, nil
+	}
+
+	tags, err := EncodeS3Tags(map[string]string{"project": "example"})
+
+	if err != nil {
+		t.Fatalf("Failed to encode tags, %v", err)
+	}
+
+	fn := func(asFunc func(interface{}) bool) error {
+
+		var h http.Header
+
+		if asFunc(&h) {
+			h.Set("x-amz-tagging", tags)
+		}
+
+		return nil
+	}
+
+	wr, err := New(ctx, "mock://bucket/atomicwrite.txt", WithBucketOpener(opener), WithFinalBeforeWrite(fn))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	atomic_path := wr.(*AtomicWriter).atomic_path
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	if mock.headers[atomic_path].Get("x-amz-tagging") != "" {
+		t.Fatalf("Expected temporary file not to be tagged")
+	}
+
+	if mock.headers["atomicwrite.txt"].Get("x-amz-tagging") != tags {
+		t.Fatalf("Unexpected tags for final path: %s", mock.headers["atomicwrite.txt"].Get("x-amz-tagging"))
+	}
+}
+
+type testMetadataKey string
+
+func TestWithMetadataKey(t *testing.T) {
+
+	key := testMetadataKey("metadata")
+
+	ctx := context.WithValue(context.Background(), key, map[string]string{"request-id": "1234"})
+
+	root := t.TempDir()
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	wr, err := New(ctx, path, WithMetadataKey(key))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	bucket, err := blob.OpenBucket(ctx, "file://"+root)
+
+	if err != nil {
+		t.Fatalf("Failed to open bucket, %v", err)
+	}
+
+	defer bucket.Close()
+
+	attrs, err := bucket.Attributes(ctx, "atomicwrite.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to derive attributes, %v", err)
+	}
+
+	if attrs.Metadata["request-id"] != "1234" {
+		t.Fatalf("Unexpected metadata: %v", attrs.Metadata)
+	}
+}
+
+func TestWithBucketWrapper(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	count := 0
+
+	wrapper := func(bucket *blob.Bucket) *blob.Bucket {
+		count += 1
+		return blob.PrefixedBucket(bucket, "wrapped/")
+	}
+
+	err := writeBytes(ctx, path, []byte("hello world"), WithBucketWrapper(wrapper))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	if count != 1 {
+		t.Fatalf("Expected wrapper to be invoked once, got %d", count)
+	}
+
+	v, err := os.ReadFile(filepath.Join(root, "wrapped", "atomicwrite.txt"))
+
+	if err != nil {
+		t.Fatalf("Failed to read wrapped path, %v", err)
+	}
+
+	if string(v) != "hello world" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+
+	_, err = os.Stat(path)
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected %s not to exist, %v", path, err)
+	}
+
+	nil_wrapper := func(bucket *blob.Bucket) *blob.Bucket {
+		return nil
+	}
+
+	_, err = New(ctx, path, WithBucketWrapper(nil_wrapper))
+
+	if err == nil {
+		t.Fatalf("Expected nil bucket to fail")
+	}
+}
+
+func TestWithWriterOptionsFinalPath(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.msgpack")
+
+	writer_opts := &blob.WriterOptions{
+		ContentType:  "application/msgpack",
+		CacheControl: "max-age=3600",
+		Metadata:     map[string]string{"source": "test"},
+	}
+
+	err := writeBytes(ctx, path, []byte(HELLO_WORLD), WithWriterOptions(writer_opts))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	bucket, err := blob.OpenBucket(ctx, "file://"+root)
+
+	if err != nil {
+		t.Fatalf("Failed to open bucket, %v", err)
+	}
+
+	defer bucket.Close()
+
+	attrs, err := bucket.Attributes(ctx, "atomicwrite.msgpack")
+
+	if err != nil {
+		t.Fatalf("Failed to retrieve attributes, %v", err)
+	}
+
+	if attrs.ContentType != "application/msgpack" || attrs.CacheControl != "max-age=3600" || attrs.Metadata["source"] != "test" {
+		t.Fatalf("Unexpected attributes for final path: %v", attrs)
+	}
+}
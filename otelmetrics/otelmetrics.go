@@ -0,0 +1,145 @@
+// package otelmetrics implements a WriterEventListener which records metrics for the lifecycle events emitted by AtomicWriter
+// instances using the OpenTelemetry metrics API. It records the same metrics as the PrometheusExporter in the metrics package
+// but does not depend on the Prometheus client library.
+package otelmetrics
+
+import (
+	"context"
+	"github.com/sfomuseum/go-atomicwrite"
+	"go.opentelemetry.io/otel"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/metric/nonrecording"
+	"go.opentelemetry.io/otel/metric/unit"
+	"sync"
+	"time"
+)
+
+// type OTelExporter implements the `atomicwrite.WriterEventListener` interface recording metrics for each event using
+// OpenTelemetry instruments.
+type OTelExporter struct {
+	// The number of writers whose data has been committed
+	commits syncint64.Counter
+	// The number of writers which have been aborted
+	aborts syncint64.Counter
+	// The number of bytes written
+	bytes_written syncint64.Counter
+	// The number of errors encountered committing or discarding data
+	errors syncint64.Counter
+	// The time, in seconds, between a writer being opened and its data being committed
+	commit_latency syncfloat64.Histogram
+	// A mutex guarding opened
+	mu sync.Mutex
+	// The time each writer was opened, keyed by its writer ID
+	opened map[string]time.Time
+}
+
+var _ atomicwrite.WriterEventListener = (*OTelExporter)(nil)
+
+// NewOTelExporter returns a new OTelExporter instance whose instruments are created by 'meter'. The instruments are named
+// "atomicwrite.commits", "atomicwrite.aborts", "atomicwrite.bytes_written", "atomicwrite.errors" and "atomicwrite.commit.latency"
+// (a histogram, in seconds). Errors creating instruments are reported to the global OpenTelemetry error handler, using the
+// `otel.Handle` function, and the instruments in question are not recorded. The same OTelExporter instance may be registered with
+// multiple AtomicWriter instances, using the `atomicwrite.WithEventListener` option, and is safe for concurrent use.
+func NewOTelExporter(meter otelmetric.Meter) *OTelExporter {
+
+	ex := &OTelExporter{
+		commits:        newCounter(meter, "atomicwrite.commits", "The number of writers whose data has been committed.", unit.Dimensionless),
+		aborts:         newCounter(meter, "atomicwrite.aborts", "The number of writers which have been aborted.", unit.Dimensionless),
+		bytes_written:  newCounter(meter, "atomicwrite.bytes_written", "The number of bytes written.", unit.Bytes),
+		errors:         newCounter(meter, "atomicwrite.errors", "The number of errors encountered committing or discarding data.", unit.Dimensionless),
+		commit_latency: newHistogram(meter, "atomicwrite.commit.latency", "The time between a writer being opened and its data being committed.", unit.Unit("s")),
+		opened:         make(map[string]time.Time),
+	}
+
+	return ex
+}
+
+// OnEvent records the metrics for 'e'.
+func (ex *OTelExporter) OnEvent(e atomicwrite.WriterEvent) {
+
+	ctx := context.Background()
+
+	switch e.Kind {
+	case atomicwrite.EventOpened:
+
+		ex.mu.Lock()
+		ex.opened[e.WriterID] = e.Time
+		ex.mu.Unlock()
+
+	case atomicwrite.EventWritten:
+
+		if e.Bytes > 0 {
+			ex.bytes_written.Add(ctx, int64(e.Bytes))
+		}
+
+	case atomicwrite.EventCommitted:
+
+		ex.commits.Add(ctx, 1)
+
+		t, ok := ex.popOpened(e.WriterID)
+
+		if ok {
+			ex.commit_latency.Record(ctx, e.Time.Sub(t).Seconds())
+		}
+
+	case atomicwrite.EventAborted:
+
+		ex.aborts.Add(ctx, 1)
+		ex.popOpened(e.WriterID)
+
+	case atomicwrite.EventStagingDeleted:
+
+		// Writers whose data could not be committed are never timed
+		ex.popOpened(e.WriterID)
+
+	case atomicwrite.EventError:
+
+		ex.errors.Add(ctx, 1)
+	}
+}
+
+// popOpened returns and removes the time the writer with ID 'id' was opened.
+func (ex *OTelExporter) popOpened(id string) (time.Time, bool) {
+
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	t, ok := ex.opened[id]
+
+	if ok {
+		delete(ex.opened, id)
+	}
+
+	return t, ok
+}
+
+// newCounter returns a new integer counter named 'name' created by 'meter'. If the counter can not be created the error is
+// reported using the `otel.Handle` function and a counter which records nothing is returned.
+func newCounter(meter otelmetric.Meter, name string, description string, u unit.Unit) syncint64.Counter {
+
+	c, err := meter.SyncInt64().Counter(name, instrument.WithDescription(description), instrument.WithUnit(u))
+
+	if err != nil {
+		otel.Handle(err)
+		c, _ = nonrecording.NewNoopMeter().SyncInt64().Counter(name)
+	}
+
+	return c
+}
+
+// newHistogram returns a new float histogram named 'name' created by 'meter'. If the histogram can not be created the error is
+// reported using the `otel.Handle` function and a histogram which records nothing is returned.
+func newHistogram(meter otelmetric.Meter, name string, description string, u unit.Unit) syncfloat64.Histogram {
+
+	h, err := meter.SyncFloat64().Histogram(name, instrument.WithDescription(description), instrument.WithUnit(u))
+
+	if err != nil {
+		otel.Handle(err)
+		h, _ = nonrecording.NewNoopMeter().SyncFloat64().Histogram(name)
+	}
+
+	return h
+}
@@ -0,0 +1,217 @@
+package otelmetrics
+
+import (
+	"context"
+	"fmt"
+	"github.com/sfomuseum/go-atomicwrite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/metric/nonrecording"
+	"sync"
+	"testing"
+)
+
+// testMeter is a otelmetric.Meter which records the values passed to the synchronous instruments it creates, keyed by name.
+type testMeter struct {
+	otelmetric.Meter
+	mu     sync.Mutex
+	values map[string][]float64
+	fail   string
+}
+
+func newTestMeter() *testMeter {
+
+	m := &testMeter{
+		Meter:  nonrecording.NewNoopMeter(),
+		values: make(map[string][]float64),
+	}
+
+	return m
+}
+
+func (m *testMeter) record(name string, v float64) {
+	m.mu.Lock()
+	m.values[name] = append(m.values[name], v)
+	m.mu.Unlock()
+}
+
+func (m *testMeter) SyncInt64() syncint64.InstrumentProvider {
+	return &testInt64Provider{InstrumentProvider: m.Meter.SyncInt64(), meter: m}
+}
+
+func (m *testMeter) SyncFloat64() syncfloat64.InstrumentProvider {
+	return &testFloat64Provider{InstrumentProvider: m.Meter.SyncFloat64(), meter: m}
+}
+
+type testInt64Provider struct {
+	syncint64.InstrumentProvider
+	meter *testMeter
+}
+
+func (p *testInt64Provider) Counter(name string, opts ...instrument.Option) (syncint64.Counter, error) {
+
+	if name == p.meter.fail {
+		return nil, fmt.Errorf("Invalid instrument %s", name)
+	}
+
+	c, _ := p.InstrumentProvider.Counter(name)
+	return &testInt64Counter{Counter: c, name: name, meter: p.meter}, nil
+}
+
+type testInt64Counter struct {
+	syncint64.Counter
+	name  string
+	meter *testMeter
+}
+
+func (c *testInt64Counter) Add(ctx context.Context, v int64, attrs ...attribute.KeyValue) {
+	c.meter.record(c.name, float64(v))
+}
+
+type testFloat64Provider struct {
+	syncfloat64.InstrumentProvider
+	meter *testMeter
+}
+
+func (p *testFloat64Provider) Histogram(name string, opts ...instrument.Option) (syncfloat64.Histogram, error) {
+	h, _ := p.InstrumentProvider.Histogram(name)
+	return &testFloat64Histogram{Histogram: h, name: name, meter: p.meter}, nil
+}
+
+type testFloat64Histogram struct {
+	syncfloat64.Histogram
+	name  string
+	meter *testMeter
+}
+
+func (h *testFloat64Histogram) Record(ctx context.Context, v float64, attrs ...attribute.KeyValue) {
+	h.meter.record(h.name, v)
+}
+
+func sum(values []float64) float64 {
+
+	total := 0.0
+
+	for _, v := range values {
+		total += v
+	}
+
+	return total
+}
+
+func TestOTelExporter(t *testing.T) {
+
+	ctx := context.Background()
+
+	meter := newTestMeter()
+
+	ex := NewOTelExporter(meter)
+
+	for _, label := range []string{"close", "abort", "flush"} {
+
+		wr, err := atomicwrite.New(ctx, "mem://atomicwrite.txt", atomicwrite.WithEventListener(ex))
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		aw := wr.(*atomicwrite.AtomicWriter)
+
+		_, err = aw.Write([]byte("Hello world"))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		switch label {
+		case "abort":
+			err = aw.Abort()
+		case "flush":
+
+			err = aw.Flush()
+
+			if err != nil {
+				t.Fatalf("Failed to flush writer, %v", err)
+			}
+
+			err = aw.Close()
+
+		default:
+			err = aw.Close()
+		}
+
+		if err != nil {
+			t.Fatalf("Failed to %s writer, %v", label, err)
+		}
+	}
+
+	if v := sum(meter.values["atomicwrite.commits"]); v != 2 {
+		t.Fatalf("Unexpected commits: %f", v)
+	}
+
+	if v := sum(meter.values["atomicwrite.aborts"]); v != 1 {
+		t.Fatalf("Unexpected aborts: %f", v)
+	}
+
+	if v := sum(meter.values["atomicwrite.bytes_written"]); v != 33 {
+		t.Fatalf("Unexpected bytes written: %f", v)
+	}
+
+	if v := sum(meter.values["atomicwrite.errors"]); v != 0 {
+		t.Fatalf("Unexpected errors: %f", v)
+	}
+
+	latency := meter.values["atomicwrite.commit.latency"]
+
+	if len(latency) != 2 || latency[0] < 0 || latency[1] < 0 {
+		t.Fatalf("Unexpected commit latencies: %v", latency)
+	}
+
+	if len(ex.opened) != 0 {
+		t.Fatalf("Expected no open writers, got %d", len(ex.opened))
+	}
+}
+
+func TestOTelExporterInstrumentError(t *testing.T) {
+
+	ctx := context.Background()
+
+	var handled error
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		handled = err
+	}))
+
+	meter := newTestMeter()
+	meter.fail = "atomicwrite.commits"
+
+	ex := NewOTelExporter(meter)
+
+	if handled == nil {
+		t.Fatalf("Expected instrument error to be reported")
+	}
+
+	wr, err := atomicwrite.New(ctx, "mem://atomicwrite.txt", atomicwrite.WithEventListener(ex))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	if len(meter.values["atomicwrite.commits"]) != 0 {
+		t.Fatalf("Expected commits not to be recorded")
+	}
+
+	if len(meter.values["atomicwrite.commit.latency"]) != 1 {
+		t.Fatalf("Expected commit latency to be recorded")
+	}
+}
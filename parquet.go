@@ -0,0 +1,93 @@
+package atomicwrite
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrInvalidParquet is returned by the `Close` method when the `WithParquetValidation` option is enabled and the data written
+// is not a well-formed Parquet file.
+var ErrInvalidParquet = errors.New("Invalid Parquet file")
+
+// parquet_magic is the magic number found at the start and end of every Parquet file.
+var parquet_magic = []byte("PAR1")
+
+// WithParquetValidation returns an Option which causes the `Close` method to check that the data written to the intermediate
+// temporary file is a well-formed Parquet file before it is copied to the final path. If it is not, nothing is written to the
+// final path, the temporary file is removed and an error wrapping ErrInvalidParquet is returned. Validation checks the file
+// structure defined by the Parquet specification: the leading and trailing "PAR1" magic numbers and that the footer length
+// recorded at the end of the file fits between them. It does not decode the (Thrift-encoded) file metadata itself. This option
+// can not be combined with the `WithAESGCMEncryption` or `WithEncryptWriter` options.
+func WithParquetValidation() Option {
+
+	return func(o *options) {
+		o.parquet_validation = true
+	}
+}
+
+// validateParquet returns an error wrapping ErrInvalidParquet if the 'size' bytes written to the intermediate temporary file are
+// not a well-formed Parquet file.
+func (aw *AtomicWriter) validateParquet(ctx context.Context, size int64) error {
+
+	// magic number + footer + footer length (4 bytes, little endian) + magic number
+
+	min_size := int64(len(parquet_magic)*2 + 4)
+
+	if size < min_size {
+		return fmt.Errorf("File is too small (%d bytes), %w", size, ErrInvalidParquet)
+	}
+
+	head, err := aw.readRange(ctx, 0, int64(len(parquet_magic)))
+
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(head, parquet_magic) {
+		return fmt.Errorf("Missing leading magic number, %w", ErrInvalidParquet)
+	}
+
+	tail, err := aw.readRange(ctx, size-8, 8)
+
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(tail[4:], parquet_magic) {
+		return fmt.Errorf("Missing trailing magic number, %w", ErrInvalidParquet)
+	}
+
+	footer_len := int64(binary.LittleEndian.Uint32(tail[:4]))
+
+	if footer_len == 0 || footer_len > size-min_size {
+		return fmt.Errorf("Invalid footer length (%d bytes), %w", footer_len, ErrInvalidParquet)
+	}
+
+	return nil
+}
+
+// readRange reads 'length' bytes starting at 'offset' from the intermediate temporary file.
+func (aw *AtomicWriter) readRange(ctx context.Context, offset int64, length int64) ([]byte, error) {
+
+	r, err := aw.bucket.NewRangeReader(ctx, aw.atomic_path, offset, length, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open range reader for %s, %w", aw.atomic_path, err)
+	}
+
+	defer r.Close()
+
+	buf := make([]byte, length)
+
+	_, err = io.ReadFull(r, buf)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s, %w", aw.atomic_path, err)
+	}
+
+	return buf, nil
+}
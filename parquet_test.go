@@ -0,0 +1,84 @@
+package atomicwrite
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithParquetValidation(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.parquet")
+
+	footer := []byte("footer")
+
+	var buf bytes.Buffer
+	buf.WriteString("PAR1")
+	buf.WriteString("column chunks")
+	buf.Write(footer)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(footer)))
+	buf.WriteString("PAR1")
+
+	err := writeBytes(ctx, path, buf.Bytes(), WithParquetValidation())
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	_, err = os.Stat(path)
+
+	if err != nil {
+		t.Fatalf("Failed to stat %s, %v", path, err)
+	}
+
+	tests := map[string][]byte{
+		"empty":    []byte{},
+		"text":     []byte("hello world, this is not parquet"),
+		"trailing": append([]byte("PAR1"), []byte("footer\x06\x00\x00\x00PAR2")...),
+		"length":   append([]byte("PAR1"), []byte("footer\xff\x00\x00\x00PAR1")...),
+	}
+
+	for label, body := range tests {
+
+		path := filepath.Join(root, label+".parquet")
+
+		err := writeBytes(ctx, path, body, WithParquetValidation())
+
+		if !errors.Is(err, ErrInvalidParquet) {
+			t.Fatalf("Expected ErrInvalidParquet for %s, got %v", label, err)
+		}
+
+		_, err = os.Stat(path)
+
+		if !os.IsNotExist(err) {
+			t.Fatalf("Expected %s not to exist, %v", path, err)
+		}
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	for _, e := range entries {
+
+		if e.Name() != "atomicwrite.parquet" && e.Name() != "atomicwrite.parquet.attrs" {
+			t.Fatalf("Unexpected file %s", e.Name())
+		}
+	}
+
+	_, err = New(ctx, path, WithParquetValidation(), WithAESGCMEncryption(make([]byte, 32)))
+
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported, got %v", err)
+	}
+}
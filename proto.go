@@ -0,0 +1,37 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// WriteProto marshals 'm' using the `proto.Marshal` method and atomically writes the result to 'uri'.
+func WriteProto(ctx context.Context, uri string, m proto.Message, opts ...Option) error {
+
+	body, err := proto.Marshal(m)
+
+	if err != nil {
+		return fmt.Errorf("Failed to marshal message, %w", err)
+	}
+
+	return writeBytes(ctx, uri, body, opts...)
+}
+
+// ReadProto reads the data stored at 'uri' and unmarshals it in to 'm' using the `proto.Unmarshal` method.
+func ReadProto(ctx context.Context, uri string, m proto.Message) error {
+
+	body, err := readBytes(ctx, uri)
+
+	if err != nil {
+		return err
+	}
+
+	err = proto.Unmarshal(body, m)
+
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal message, %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package atomicwrite
+
+import (
+	"context"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteProto(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.pb")
+
+	m := timestamppb.Now()
+
+	err := WriteProto(ctx, path, m)
+
+	if err != nil {
+		t.Fatalf("Failed to write message, %v", err)
+	}
+
+	m2 := &timestamppb.Timestamp{}
+
+	err = ReadProto(ctx, path, m2)
+
+	if err != nil {
+		t.Fatalf("Failed to read message, %v", err)
+	}
+
+	if !proto.Equal(m, m2) {
+		t.Fatalf("Message read (%v) does not match message written (%v)", m2, m)
+	}
+}
@@ -0,0 +1,171 @@
+package atomicwrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/fileblob"
+	"gocloud.dev/blob/memblob"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// The maximum size, in bytes, of the payloads generated by the `quickPayload` type
+const quick_max_payload int = 10 * 1024 * 1024
+
+// type quickPayload is a random payload, between 0 and quick_max_payload bytes long, generated by the testing/quick package.
+// Sizes are distributed logarithmically so that small (including empty) payloads are as likely to be tested as large ones.
+type quickPayload []byte
+
+// Generate implements the quick.Generator interface.
+func (p quickPayload) Generate(r *rand.Rand, size int) reflect.Value {
+
+	n := int(math.Pow(float64(quick_max_payload+1), r.Float64())) - 1
+
+	b := make([]byte, n)
+	r.Read(b)
+
+	return reflect.ValueOf(quickPayload(b))
+}
+
+func TestAtomicWriteQuick(t *testing.T) {
+
+	ctx := context.Background()
+
+	file_bucket, err := fileblob.OpenBucket(t.TempDir(), nil)
+
+	if err != nil {
+		t.Fatalf("Failed to open file bucket, %v", err)
+	}
+
+	defer file_bucket.Close()
+
+	mem_bucket := memblob.OpenBucket(nil)
+	defer mem_bucket.Close()
+
+	buckets := map[string]*blob.Bucket{
+		"file": file_bucket,
+		"mem":  mem_bucket,
+	}
+
+	cfg := &quick.Config{
+		MaxCount: 20,
+	}
+
+	if testing.Short() {
+		cfg.MaxCount = 5
+	}
+
+	for scheme, bucket := range buckets {
+
+		opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+			return bucket, nil
+		}
+
+		i := 0
+
+		fn := func(p quickPayload) bool {
+
+			i += 1
+			key := fmt.Sprintf("payload-%d.bin", i)
+
+			err := checkAtomicWrite(ctx, bucket, fmt.Sprintf("%s:///%s", scheme, key), key, p, WithBucketOpener(opener))
+
+			if err != nil {
+				t.Logf("Failed to write %d bytes to %s bucket, %v", len(p), scheme, err)
+				return false
+			}
+
+			return true
+		}
+
+		err := quick.Check(fn, cfg)
+
+		if err != nil {
+			t.Fatalf("Property failed for %s bucket, %v", scheme, err)
+		}
+	}
+}
+
+// checkAtomicWrite writes 'body' to 'uri' and returns an error if 'key' exists before the writer is closed, if 'key' does not
+// contain exactly 'body' once the writer is closed or if any other keys, notably the intermediate temporary file, remain in 'bucket'.
+func checkAtomicWrite(ctx context.Context, bucket *blob.Bucket, uri string, key string, body []byte, opts ...Option) error {
+
+	wr, err := New(ctx, uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create writer, %w", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	defer aw.Abort()
+
+	_, err = io.Copy(wr, bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("Failed to write data, %w", err)
+	}
+
+	exists, err := bucket.Exists(ctx, key)
+
+	if err != nil {
+		return fmt.Errorf("Failed to determine whether %s exists, %w", key, err)
+	}
+
+	if exists {
+		return fmt.Errorf("%s exists before Close", key)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close writer, %w", err)
+	}
+
+	v, err := bucket.ReadAll(ctx, key)
+
+	if err != nil {
+		return fmt.Errorf("Failed to read %s, %w", key, err)
+	}
+
+	if !bytes.Equal(v, body) {
+		return fmt.Errorf("Committed data does not match (%d bytes written, %d bytes committed)", len(body), len(v))
+	}
+
+	exists, err = bucket.Exists(ctx, aw.AtomicPath())
+
+	if err != nil {
+		return fmt.Errorf("Failed to determine whether %s exists, %w", aw.AtomicPath(), err)
+	}
+
+	if exists {
+		return fmt.Errorf("Temporary file %s exists after Close", aw.AtomicPath())
+	}
+
+	iter := bucket.List(nil)
+
+	for {
+
+		obj, err := iter.Next(ctx)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("Failed to list bucket, %w", err)
+		}
+
+		if obj.Key != key {
+			return fmt.Errorf("Unexpected key %s", obj.Key)
+		}
+	}
+
+	return bucket.Delete(ctx, key)
+}
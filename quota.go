@@ -0,0 +1,98 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gocloud.dev/blob"
+	"io"
+)
+
+// ErrQuotaExceeded is returned when writing data would exceed the quota defined by the `WithQuota` option.
+var ErrQuotaExceeded = errors.New("Quota exceeded")
+
+// WithQuota returns an Option which limits the total size of all the objects in the bucket defined by 'bucket_uri' to
+// 'max_total_bytes'. Before the intermediate temporary file is created all the objects in the bucket are listed and their
+// sizes summed. If the sum plus the expected size of the data being written (defined by the `WithSizeHint` option) exceeds
+// 'max_total_bytes' then ErrQuotaExceeded is returned. Subsequent calls to the `Write` method will also return ErrQuotaExceeded
+// if they would exceed the remaining quota. For `file://` URIs the bucket is a directory so this is a per-directory quota. Note
+// that there is no locking so concurrent writers may, together, exceed the quota.
+func WithQuota(bucket_uri string, max_total_bytes int64) Option {
+
+	return func(o *options) {
+		o.quota_uri = bucket_uri
+		o.quota_max = max_total_bytes
+	}
+}
+
+// WithSizeHint returns an Option which defines the expected size, in bytes, of the data being written. This is used by the
+// `WithQuota` option to determine whether a write should be allowed before any data is written.
+func WithSizeHint(size int64) Option {
+
+	return func(o *options) {
+		o.size_hint = size
+	}
+}
+
+// remainingQuota returns the number of bytes that may still be written under the quota defined by 'o', or -1 if there is
+// no quota. ErrQuotaExceeded is returned if the size hint defined by 'o' exceeds the remaining quota. 'bucket' is the bucket,
+// already opened for 'bucket_uri', that data is being written to; it is reused if the quota is for the same bucket.
+func (o *options) remainingQuota(ctx context.Context, bucket_uri string, bucket *blob.Bucket) (int64, error) {
+
+	if o.quota_uri == "" {
+		return -1, nil
+	}
+
+	quota_bucket := bucket
+
+	if o.quota_uri != bucket_uri {
+
+		b, err := o.openBucket(ctx, o.quota_uri)
+
+		if err != nil {
+			return -1, err
+		}
+
+		defer o.closeBucket(b)
+		quota_bucket = b
+	}
+
+	used, err := bucketUsage(ctx, quota_bucket)
+
+	if err != nil {
+		return -1, fmt.Errorf("Failed to determine usage for %s, %w", o.quota_uri, err)
+	}
+
+	remaining := o.quota_max - used
+
+	if remaining < 0 || o.size_hint > remaining {
+		return -1, fmt.Errorf("Writing %d bytes would exceed quota of %d bytes (%d bytes used), %w", o.size_hint, o.quota_max, used, ErrQuotaExceeded)
+	}
+
+	return remaining, nil
+}
+
+// bucketUsage returns the sum of the sizes of all the objects in 'bucket'.
+func bucketUsage(ctx context.Context, bucket *blob.Bucket) (int64, error) {
+
+	var used int64
+
+	iter := bucket.List(nil)
+
+	for {
+
+		obj, err := iter.Next(ctx)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("Failed to list bucket, %w", err)
+		}
+
+		used += obj.Size
+	}
+
+	return used, nil
+}
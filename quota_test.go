@@ -0,0 +1,127 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithQuota(t *testing.T) {
+
+	ctx := context.Background()
+
+	tmpdir := t.TempDir()
+	bucket_uri := fmt.Sprintf("file://%s?metadata=skip", tmpdir)
+
+	max := int64(len(HELLO_WORLD) * 2)
+
+	// Write to (but don't exceed) the quota
+
+	for i := 0; i < 2; i++ {
+
+		path := filepath.Join(tmpdir, fmt.Sprintf("atomicwrite-%d.txt", i))
+		uri := fmt.Sprintf("file://%s?metadata=skip", path)
+
+		err := testAtomicWrite(uri, WithQuota(bucket_uri, max), WithSizeHint(int64(len(HELLO_WORLD))))
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", path, err)
+		}
+	}
+
+	// Size hints exceeding the quota fail immediately
+
+	path := filepath.Join(tmpdir, "atomicwrite-2.txt")
+
+	_, err := New(ctx, path, WithQuota(bucket_uri, max), WithSizeHint(1))
+
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected size hint to exceed quota, got %v", err)
+	}
+
+	// Writes exceeding the quota fail
+
+	wr, err := New(ctx, path, WithQuota(bucket_uri, max+5))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected write to exceed quota, got %v", err)
+	}
+
+	err = wr.(*AtomicWriter).Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort writer, %v", err)
+	}
+}
+
+func TestWithQuotaSharedBucketOpener(t *testing.T) {
+
+	ctx := context.Background()
+
+	mem := memblob.OpenBucket(nil)
+	defer mem.Close()
+
+	// Every call returns the same instance, as a caching opener would
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return mem, nil
+	}
+
+	max := int64(len(HELLO_WORLD) * 2)
+
+	// The quota URI matches the writer's bucket URI so the writer's bucket is reused; for
+	// "mem://other" the bucket is opened separately but must not be closed
+
+	for i, quota_uri := range []string{"mem://", "mem://other"} {
+
+		uri := fmt.Sprintf("mem://atomicwrite-%d.txt", i)
+
+		err := testAtomicWrite(uri, WithBucketOpener(opener), WithQuota(quota_uri, max), WithSizeHint(int64(len(HELLO_WORLD))))
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", uri, err)
+		}
+	}
+
+	// The bucket now contains max bytes so any further write exceeds the quota
+
+	_, err := New(ctx, "mem://atomicwrite-2.txt", WithBucketOpener(opener), WithQuota("mem://", max), WithSizeHint(1))
+
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected size hint to exceed quota, got %v", err)
+	}
+
+	wr, err := New(ctx, "mem://atomicwrite-2.txt", WithBucketOpener(opener), WithQuota("mem://", max+5))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected write to exceed quota, got %v", err)
+	}
+
+	err = wr.(*AtomicWriter).Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort writer, %v", err)
+	}
+
+	_, err = mem.Attributes(ctx, "atomicwrite-0.txt")
+
+	if err != nil {
+		t.Fatalf("Expected shared bucket to remain open, %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package atomicwrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"gocloud.dev/gcerrors"
+	"io"
+)
+
+// type ReaderWriter provides an io.ReadCloser instance for the existing data at a given URI and an AtomicWriter
+// instance for replacing that data. This is useful for updating data "in place".
+type ReaderWriter struct {
+	// Reader is an io.ReadCloser instance for reading the data that existed when `OpenReaderWriter` was invoked.
+	Reader io.ReadCloser
+	// Writer is an io.WriteCloser instance for writing the data that will replace the existing data when `Close` is invoked.
+	Writer io.WriteCloser
+	// The underlying AtomicWriter instance
+	aw *AtomicWriter
+}
+
+// OpenReaderWriter returns a new ReaderWriter instance for 'uri'. The existing data for 'uri' is copied to an intermediate
+// snapshot file which is what the `ReaderWriter.Reader` instance reads from. This ensures that the data being read is not
+// modified, by this or other processes, while it is being read. If there is no existing data for 'uri' then `ReaderWriter.Reader`
+// will be an empty reader. The `ReaderWriter.Writer` instance is an AtomicWriter instance for 'uri' which is committed when the
+// `ReaderWriter.Close` method is invoked. Only the write side is persisted; the snapshot file is removed on `Close` or `Abort`.
+func OpenReaderWriter(ctx context.Context, uri string, opts ...Option) (*ReaderWriter, error) {
+
+	aw, err := newAtomicWriter(ctx, uri, opts...)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create atomic writer, %w", err)
+	}
+
+	rw := &ReaderWriter{
+		Writer: aw,
+		aw:     aw,
+	}
+
+	r, err := openSnapshot(ctx, aw.bucket, aw.FinalPath())
+
+	switch {
+	case gcerrors.Code(err) == gcerrors.NotFound:
+		rw.Reader = io.NopCloser(bytes.NewReader(nil))
+	case err != nil:
+		aw.Abort()
+		return nil, err
+	default:
+		rw.Reader = r
+	}
+
+	return rw, nil
+}
+
+// Close closes the `ReaderWriter.Reader` instance, removes the snapshot file and commits the data written to the
+// `ReaderWriter.Writer` instance.
+func (rw *ReaderWriter) Close() error {
+
+	err := rw.closeReader()
+
+	if err != nil {
+		rw.aw.Abort()
+		return err
+	}
+
+	return rw.aw.Close()
+}
+
+// Abort closes the `ReaderWriter.Reader` instance, removes the snapshot file and discards the data written to the
+// `ReaderWriter.Writer` instance.
+func (rw *ReaderWriter) Abort() error {
+
+	err := rw.closeReader()
+
+	if err != nil {
+		rw.aw.Abort()
+		return err
+	}
+
+	return rw.aw.Abort()
+}
+
+// closeReader closes the `ReaderWriter.Reader` instance which, in turn, removes the snapshot file.
+func (rw *ReaderWriter) closeReader() error {
+	return rw.Reader.Close()
+}
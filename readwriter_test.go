@@ -0,0 +1,83 @@
+package atomicwrite
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenReaderWriter(t *testing.T) {
+
+	ctx := context.Background()
+
+	tmpdir := t.TempDir()
+	path := filepath.Join(tmpdir, "atomicwrite.txt")
+
+	for i := 0; i < 2; i++ {
+
+		rw, err := OpenReaderWriter(ctx, path)
+
+		if err != nil {
+			t.Fatalf("Failed to open reader writer, %v", err)
+		}
+
+		body, err := io.ReadAll(rw.Reader)
+
+		if err != nil {
+			t.Fatalf("Failed to read existing data, %v", err)
+		}
+
+		_, err = rw.Writer.Write([]byte(strings.ToUpper(HELLO_WORLD)))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		// Writing should not affect the data being read
+
+		expected := ""
+
+		if i > 0 {
+			expected = strings.ToUpper(HELLO_WORLD)
+		}
+
+		if string(body) != expected {
+			t.Fatalf("Unexpected existing data: '%s'", string(body))
+		}
+
+		err = rw.Close()
+
+		if err != nil {
+			t.Fatalf("Failed to close reader writer, %v", err)
+		}
+	}
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if !bytes.Equal(body, []byte(strings.ToUpper(HELLO_WORLD))) {
+		t.Fatalf("Invalid data (%s) written to %s", string(body), path)
+	}
+
+	entries, err := os.ReadDir(tmpdir)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", tmpdir, err)
+	}
+
+	for _, e := range entries {
+
+		// fileblob stores attributes in ".attrs" sidecar files
+
+		if e.Name() != "atomicwrite.txt" && e.Name() != "atomicwrite.txt.attrs" {
+			t.Fatalf("Expected snapshot and temporary files to be removed, found %s", e.Name())
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package atomicwrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// type RotatingTemplateVars defines the variables available to the URI template passed to the `NewRotating` constructor.
+type RotatingTemplateVars struct {
+	// The zero-based index of the current writer
+	Index int
+	// The Unix timestamp at which the current writer was opened
+	Timestamp int64
+	// The time at which the current writer was opened
+	Time time.Time
+}
+
+// type RotatingWriter implements the io.WriteCloser interface writing data to a sequence of AtomicWriter instances,
+// opening a new instance each time the current instance reaches a maximum size or a time boundary.
+type RotatingWriter struct {
+	// The context.Context instance used to create each AtomicWriter instance
+	ctx context.Context
+	// The template used to derive the URI for each AtomicWriter instance
+	template *template.Template
+	// The maximum number of bytes to write to each AtomicWriter instance, or 0 if there is no maximum
+	max_size int64
+	// The options used to create each AtomicWriter instance
+	opts []Option
+	// A mutex guarding writer, index, written and err
+	mu sync.Mutex
+	// The current AtomicWriter instance, or nil if the RotatingWriter has been closed
+	writer *AtomicWriter
+	// The zero-based index of the current AtomicWriter instance
+	index int
+	// The number of bytes written to the current AtomicWriter instance
+	written int64
+	// The error (if any) encountered rotating writers in the background
+	err error
+	// A channel that is closed to stop the background goroutine which rotates writers on time boundaries
+	done chan struct{}
+	// Used to ensure done is only closed once
+	done_once sync.Once
+	// Used to wait for the background goroutine which rotates writers on time boundaries to exit
+	wg sync.WaitGroup
+}
+
+// WithTimeRotation returns an Option which causes RotatingWriter instances to rotate writers at each multiple of 'd', for
+// example hourly or daily. Time boundaries are computed relative to the zero time (in UTC). As with the `RotatingWriter.Close`
+// method writers which have not had any data written to them when a time boundary is reached are aborted rather than committed.
+// This option has no effect on AtomicWriter instances.
+func WithTimeRotation(d time.Duration) Option {
+
+	return func(o *options) {
+		o.rotation_interval = d
+	}
+}
+
+// NewRotating returns a new RotatingWriter instance. 'uri_template' is a `text/template` string which is rendered, using a
+// RotatingTemplateVars instance, to derive the URI for each AtomicWriter instance. For example "file:///logs/app-{{.Index}}.log"
+// or "file:///logs/app-{{.Time.Format "2006-01-02T15"}}.log". Before data is written to the current AtomicWriter instance if the
+// write would cause it to exceed 'max_size' bytes then that instance is closed (committing its data) and a new instance is opened
+// for the next URI. A single write larger than 'max_size' is written in its entirety to an empty writer. If 'max_size' is 0 then
+// writers are not rotated by size, in which case the `WithTimeRotation` option must be specified. If `WithTimeRotation` is specified
+// writers are also rotated, in a background goroutine, at each time boundary. The template should include the {{.Index}} variable
+// if writers may be rotated more than once per second. 'opts' are passed to each AtomicWriter instance. RotatingWriter instances are
+// safe for concurrent use.
+func NewRotating(ctx context.Context, uri_template string, max_size int64, opts ...Option) (*RotatingWriter, error) {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	switch {
+	case max_size < 0:
+		return nil, fmt.Errorf("Maximum size must not be negative")
+	case max_size == 0 && o.rotation_interval <= 0:
+		return nil, fmt.Errorf("Maximum size must be greater than zero if time rotation is not enabled")
+	}
+
+	t, err := template.New("uri").Parse(uri_template)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI template, %w", err)
+	}
+
+	rw := &RotatingWriter{
+		ctx:      ctx,
+		template: t,
+		max_size: max_size,
+		opts:     opts,
+		done:     make(chan struct{}),
+	}
+
+	err = rw.open()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if o.rotation_interval > 0 {
+		rw.wg.Add(1)
+		go rw.rotateOnInterval(o.rotation_interval)
+	}
+
+	return rw, nil
+}
+
+// Write writes 'b' to the current AtomicWriter instance, first rotating it if necessary.
+func (rw *RotatingWriter) Write(b []byte) (int, error) {
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.err != nil {
+		return 0, rw.err
+	}
+
+	if rw.writer == nil {
+		return 0, fmt.Errorf("Rotating writer has been closed")
+	}
+
+	if rw.max_size > 0 && rw.written > 0 && rw.written+int64(len(b)) > rw.max_size {
+
+		err := rw.rotate(true)
+
+		if err != nil {
+			rw.err = err
+			return 0, err
+		}
+	}
+
+	n, err := rw.writer.Write(b)
+	rw.written += int64(n)
+
+	return n, err
+}
+
+// Close stops rotating writers on time boundaries and closes (and commits) the current AtomicWriter instance. If no data has
+// been written to the current instance it is aborted instead. If an error was encountered rotating writers in the background
+// it is returned. Subsequent calls to Close are no-ops which return nil (or the background error).
+func (rw *RotatingWriter) Close() error {
+
+	rw.done_once.Do(func() {
+		close(rw.done)
+	})
+
+	rw.wg.Wait()
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.writer == nil {
+		return rw.err
+	}
+
+	var err error
+
+	if rw.written > 0 {
+		err = rw.writer.Close()
+	} else {
+		err = rw.writer.Abort()
+	}
+
+	rw.writer = nil
+
+	if rw.err != nil {
+		return rw.err
+	}
+
+	return err
+}
+
+// rotateOnInterval rotates writers at each multiple of 'd' until the `Close` method is invoked. Rather than using a
+// ticker, which may drift, a new timer is created for the next time boundary after each rotation.
+func (rw *RotatingWriter) rotateOnInterval(d time.Duration) {
+
+	defer rw.wg.Done()
+
+	for {
+
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(d).Add(d).Sub(now))
+
+		select {
+		case <-rw.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+			// pass
+		}
+
+		rw.mu.Lock()
+
+		if rw.writer != nil && rw.err == nil {
+			rw.err = rw.rotate(rw.written > 0)
+		}
+
+		rw.mu.Unlock()
+	}
+}
+
+// rotate closes, or aborts if 'commit' is false, the current AtomicWriter instance and opens a new one.
+func (rw *RotatingWriter) rotate(commit bool) error {
+
+	var err error
+
+	if commit {
+		err = rw.writer.Close()
+	} else {
+		err = rw.writer.Abort()
+	}
+
+	rw.writer = nil
+
+	if err != nil {
+		return fmt.Errorf("Failed to close writer %d, %w", rw.index, err)
+	}
+
+	rw.index += 1
+	return rw.open()
+}
+
+// open opens a new AtomicWriter instance for the current index.
+func (rw *RotatingWriter) open() error {
+
+	now := time.Now()
+
+	vars := RotatingTemplateVars{
+		Index:     rw.index,
+		Timestamp: now.Unix(),
+		Time:      now,
+	}
+
+	var buf bytes.Buffer
+
+	err := rw.template.Execute(&buf, vars)
+
+	if err != nil {
+		return fmt.Errorf("Failed to render URI template, %w", err)
+	}
+
+	uri := buf.String()
+
+	wr, err := newAtomicWriter(rw.ctx, uri, rw.opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create writer for %s, %w", uri, err)
+	}
+
+	rw.writer = wr
+	rw.written = 0
+
+	return nil
+}
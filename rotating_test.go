@@ -0,0 +1,179 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNewRotating(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	uri_template := filepath.Join(root, "atomicwrite-{{.Index}}.txt")
+
+	wr, err := NewRotating(ctx, uri_template, 10)
+
+	if err != nil {
+		t.Fatalf("Failed to create rotating writer, %v", err)
+	}
+
+	for _, s := range []string{"12345", "67890", "abcde", "fghijklmnopqrstuvwxyz", "!"} {
+
+		_, err := wr.Write([]byte(s))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close rotating writer, %v", err)
+	}
+
+	expected := []string{"1234567890", "abcde", "fghijklmnopqrstuvwxyz", "!"}
+
+	for i, e := range expected {
+
+		path := filepath.Join(root, fmt.Sprintf("atomicwrite-%d.txt", i))
+
+		body, err := os.ReadFile(path)
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", path, err)
+		}
+
+		if string(body) != e {
+			t.Fatalf("Unexpected body for %s, expected '%s' but got '%s'", path, e, string(body))
+		}
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err == nil {
+		t.Fatalf("Expected write to closed rotating writer to fail")
+	}
+}
+
+func TestNewRotatingWithTimeRotation(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	uri_template := "file://" + root + "/atomicwrite-{{.Index}}-{{.Time.Format \"150405\"}}.txt?metadata=skip"
+
+	d := 100 * time.Millisecond
+
+	wr, err := NewRotating(ctx, uri_template, 0, WithTimeRotation(d))
+
+	if err != nil {
+		t.Fatalf("Failed to create rotating writer, %v", err)
+	}
+
+	for _, s := range []string{"a", "b"} {
+
+		_, err := wr.Write([]byte(s))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		time.Sleep(d * 2)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close rotating writer, %v", err)
+	}
+
+	// Writers which have no data when a time boundary is reached are aborted so the indices
+	// of the files written depend on timing but there should only be two of them
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	bodies := make(map[int]string)
+	indices := make([]int, 0)
+
+	for _, e := range entries {
+
+		var i int
+
+		_, err := fmt.Sscanf(e.Name(), "atomicwrite-%d-", &i)
+
+		if err != nil {
+			t.Fatalf("Unexpected file %s, %v", e.Name(), err)
+		}
+
+		body, err := os.ReadFile(filepath.Join(root, e.Name()))
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", e.Name(), err)
+		}
+
+		bodies[i] = string(body)
+		indices = append(indices, i)
+	}
+
+	sort.Ints(indices)
+
+	if len(indices) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(indices))
+	}
+
+	if bodies[indices[0]] != "a" || bodies[indices[1]] != "b" {
+		t.Fatalf("Unexpected bodies: %v", bodies)
+	}
+}
+
+func TestNewRotatingRotateError(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	// The template fails to render for every writer after the first
+
+	uri_template := filepath.Join(root, "atomicwrite-{{if gt .Index 0}}{{.Missing}}{{end}}{{.Index}}.txt")
+
+	wr, err := NewRotating(ctx, uri_template, 10)
+
+	if err != nil {
+		t.Fatalf("Failed to create rotating writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte("12345"))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	_, err = wr.Write([]byte("67890abcde"))
+
+	if err == nil {
+		t.Fatalf("Expected write which rotates writer to fail")
+	}
+
+	_, err = wr.Write([]byte("!"))
+
+	if err == nil {
+		t.Fatalf("Expected write after failed rotation to fail")
+	}
+
+	err = wr.Close()
+
+	if err == nil {
+		t.Fatalf("Expected close after failed rotation to return the rotation error")
+	}
+}
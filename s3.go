@@ -0,0 +1,108 @@
+package atomicwrite
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// S3_MAX_TAGS is the maximum number of tags that can be assigned to an S3 object.
+const S3_MAX_TAGS int = 10
+
+// S3_MAX_TAG_KEY_LENGTH is the maximum length, in Unicode characters, of an S3 object tag key.
+const S3_MAX_TAG_KEY_LENGTH int = 128
+
+// S3_MAX_TAG_VALUE_LENGTH is the maximum length, in Unicode characters, of an S3 object tag value.
+const S3_MAX_TAG_VALUE_LENGTH int = 256
+
+// Storage classes for objects in S3 buckets. Objects in the GLACIER and DEEP_ARCHIVE storage classes are not immediately
+// readable; they must be restored before their contents can be read.
+const (
+	S3_STORAGE_CLASS_STANDARD            string = "STANDARD"
+	S3_STORAGE_CLASS_REDUCED_REDUNDANCY  string = "REDUCED_REDUNDANCY"
+	S3_STORAGE_CLASS_STANDARD_IA         string = "STANDARD_IA"
+	S3_STORAGE_CLASS_ONEZONE_IA          string = "ONEZONE_IA"
+	S3_STORAGE_CLASS_INTELLIGENT_TIERING string = "INTELLIGENT_TIERING"
+	S3_STORAGE_CLASS_GLACIER             string = "GLACIER"
+	S3_STORAGE_CLASS_GLACIER_IR          string = "GLACIER_IR"
+	S3_STORAGE_CLASS_DEEP_ARCHIVE        string = "DEEP_ARCHIVE"
+)
+
+// S3StorageClass returns the canonical (upper-case) name for the S3 storage class 'class', suitable for assigning to the
+// `StorageClass` property of the `s3manager.UploadInput` instance used by gocloud.dev/blob/s3blob writers (which is sent
+// as the "x-amz-storage-class" header). An error is returned if 'class' is not a known storage class.
+func S3StorageClass(class string) (string, error) {
+
+	class = strings.ToUpper(class)
+
+	switch class {
+	case S3_STORAGE_CLASS_STANDARD, S3_STORAGE_CLASS_REDUCED_REDUNDANCY, S3_STORAGE_CLASS_STANDARD_IA, S3_STORAGE_CLASS_ONEZONE_IA,
+		S3_STORAGE_CLASS_INTELLIGENT_TIERING, S3_STORAGE_CLASS_GLACIER, S3_STORAGE_CLASS_GLACIER_IR, S3_STORAGE_CLASS_DEEP_ARCHIVE:
+		return class, nil
+	default:
+		return "", fmt.Errorf("Unsupported storage class '%s'", class)
+	}
+}
+
+// Object Lock retention modes for objects in S3 buckets.
+const (
+	S3_OBJECT_LOCK_MODE_GOVERNANCE string = "GOVERNANCE"
+	S3_OBJECT_LOCK_MODE_COMPLIANCE string = "COMPLIANCE"
+)
+
+// S3ObjectLockMode validates the S3 Object Lock mode 'mode' and retention period ending at 'retain_until' and returns the
+// canonical (upper-case) name for 'mode', suitable for assigning to the `ObjectLockMode` property of the `s3manager.UploadInput`
+// instance used by gocloud.dev/blob/s3blob writers (which is sent as the "x-amz-object-lock-mode" header). An error is returned
+// if 'mode' is not a known mode or 'retain_until' is not in the future.
+func S3ObjectLockMode(mode string, retain_until time.Time) (string, error) {
+
+	mode = strings.ToUpper(mode)
+
+	switch mode {
+	case S3_OBJECT_LOCK_MODE_GOVERNANCE, S3_OBJECT_LOCK_MODE_COMPLIANCE:
+		// pass
+	default:
+		return "", fmt.Errorf("Unsupported Object Lock mode '%s'", mode)
+	}
+
+	if !retain_until.After(time.Now()) {
+		return "", fmt.Errorf("Retention period must end in the future")
+	}
+
+	return mode, nil
+}
+
+// EncodeS3Tags validates 'tags' and encodes them as a URL query string suitable for assigning to the `Tagging` property
+// of the `s3manager.UploadInput` instance used by gocloud.dev/blob/s3blob writers (which is sent as the "x-amz-tagging"
+// header). S3 objects may have at most 10 tags; keys may be at most 128 characters and values at most 256 characters.
+// Tags should be applied using the `WithFinalBeforeWrite` option so that they are assigned to the final object and not
+// the intermediate temporary file.
+func EncodeS3Tags(tags map[string]string) (string, error) {
+
+	if len(tags) > S3_MAX_TAGS {
+		return "", fmt.Errorf("Too many tags (%d), S3 objects may have at most %d tags", len(tags), S3_MAX_TAGS)
+	}
+
+	q := url.Values{}
+
+	for k, v := range tags {
+
+		if k == "" {
+			return "", fmt.Errorf("Tag keys can not be empty")
+		}
+
+		if utf8.RuneCountInString(k) > S3_MAX_TAG_KEY_LENGTH {
+			return "", fmt.Errorf("Tag key '%s' exceeds maximum length of %d characters", k, S3_MAX_TAG_KEY_LENGTH)
+		}
+
+		if utf8.RuneCountInString(v) > S3_MAX_TAG_VALUE_LENGTH {
+			return "", fmt.Errorf("Value for tag key '%s' exceeds maximum length of %d characters", k, S3_MAX_TAG_VALUE_LENGTH)
+		}
+
+		q.Set(k, v)
+	}
+
+	return q.Encode(), nil
+}
@@ -0,0 +1,102 @@
+package atomicwrite
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeS3Tags(t *testing.T) {
+
+	tags := map[string]string{
+		"project": "example",
+		"owner":   "sfo museum",
+	}
+
+	enc, err := EncodeS3Tags(tags)
+
+	if err != nil {
+		t.Fatalf("Failed to encode tags, %v", err)
+	}
+
+	if enc != "owner=sfo+museum&project=example" {
+		t.Fatalf("Unexpected encoding: %s", enc)
+	}
+
+	too_many := make(map[string]string)
+
+	for i := 0; i <= S3_MAX_TAGS; i++ {
+		too_many[fmt.Sprintf("tag%d", i)] = "value"
+	}
+
+	invalid := []map[string]string{
+		too_many,
+		{"": "value"},
+		{strings.Repeat("k", S3_MAX_TAG_KEY_LENGTH+1): "value"},
+		{"key": strings.Repeat("v", S3_MAX_TAG_VALUE_LENGTH+1)},
+	}
+
+	for i, tags := range invalid {
+
+		_, err := EncodeS3Tags(tags)
+
+		if err == nil {
+			t.Fatalf("Expected invalid tags at offset %d to fail", i)
+		}
+	}
+}
+
+func TestS3StorageClass(t *testing.T) {
+
+	tests := map[string]string{
+		"standard_ia": S3_STORAGE_CLASS_STANDARD_IA,
+		"GLACIER":     S3_STORAGE_CLASS_GLACIER,
+	}
+
+	for class, expected := range tests {
+
+		v, err := S3StorageClass(class)
+
+		if err != nil {
+			t.Fatalf("Failed to derive storage class for %s, %v", class, err)
+		}
+
+		if v != expected {
+			t.Fatalf("Unexpected storage class for %s: %s", class, v)
+		}
+	}
+
+	_, err := S3StorageClass("NEARLINE")
+
+	if err == nil {
+		t.Fatalf("Expected unsupported storage class to fail")
+	}
+}
+
+func TestS3ObjectLockMode(t *testing.T) {
+
+	retain_until := time.Now().Add(time.Hour)
+
+	mode, err := S3ObjectLockMode("governance", retain_until)
+
+	if err != nil {
+		t.Fatalf("Failed to derive Object Lock mode, %v", err)
+	}
+
+	if mode != S3_OBJECT_LOCK_MODE_GOVERNANCE {
+		t.Fatalf("Unexpected Object Lock mode: %s", mode)
+	}
+
+	_, err = S3ObjectLockMode("LOCKED", retain_until)
+
+	if err == nil {
+		t.Fatalf("Expected unsupported Object Lock mode to fail")
+	}
+
+	_, err = S3ObjectLockMode(S3_OBJECT_LOCK_MODE_COMPLIANCE, time.Now().Add(-time.Hour))
+
+	if err == nil {
+		t.Fatalf("Expected retention period in the past to fail")
+	}
+}
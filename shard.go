@@ -0,0 +1,256 @@
+package atomicwrite
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"text/template"
+)
+
+// SHARD_MANIFEST_TYPE is the value of the "type" property of the manifests written by the `WithShard` option.
+const SHARD_MANIFEST_TYPE string = "atomicwrite#shards"
+
+// type ShardTemplateVars defines the variables available to the URI template passed to the `WithShard` option.
+type ShardTemplateVars struct {
+	// The zero-based index of the shard
+	Index int
+	// A random value which is unique to each write, so that shards written by one write never replace those of another
+	WriteID string
+}
+
+// type ShardManifest defines the manifest written to the final path by the `WithShard` option.
+type ShardManifest struct {
+	// The manifest type, which is always SHARD_MANIFEST_TYPE
+	Type string `json:"type"`
+	// The total size, in bytes, of all the shards
+	Size int64 `json:"size"`
+	// The shards, in order
+	Shards []*ShardManifestEntry `json:"shards"`
+}
+
+// type ShardManifestEntry defines an individual shard listed in a ShardManifest.
+type ShardManifestEntry struct {
+	// The URI of the shard
+	URI string `json:"uri"`
+	// The size, in bytes, of the shard
+	Size int64 `json:"size"`
+}
+
+// type shardConfig defines the configuration for splitting data in to shards.
+type shardConfig struct {
+	// The maximum size, in bytes, of each shard
+	max_size int64
+	// The template used to derive the URI for each shard
+	template *template.Template
+}
+
+// WithShard returns an Option which splits the data written to an AtomicWriter instance in to shards of at most 'max_shard_size'
+// bytes if, when the `Close` method is invoked, the intermediate temporary file is larger than 'max_shard_size'. 'uri_template'
+// is a `text/template` string which is rendered, using a ShardTemplateVars instance, to derive the URI for each shard. For example
+// "file:///data/example.txt.{{.WriteID}}.part.{{.Index}}". It should be an absolute URI and it must include the {{.WriteID}}
+// variable, otherwise the `New` constructor returns an error, so that each write stores its shards under new URIs rather than
+// replacing the shards listed by the current manifest. Each shard is written directly to its URI and then a JSON-encoded
+// ShardManifest listing each shard's URI and size is written to the final path, so readers of the final path only ever see the
+// previous data or the complete new manifest. Shards listed by previous manifests, and shards already written by a write which
+// fails, are not removed. If the intermediate temporary file is not larger than 'max_shard_size' data is written to the final path
+// as usual. Use the `ReadSharded` method to read data written using this option. This option can not be combined with the
+// `WithAdditionalPaths` option.
+func WithShard(max_shard_size int64, uri_template string) Option {
+
+	return func(o *options) {
+		o.shard_max_size = max_shard_size
+		o.shard_template = uri_template
+	}
+}
+
+// newShardConfig returns a new shardConfig instance for 'max_size' and 'uri_template'.
+func newShardConfig(max_size int64, uri_template string) (*shardConfig, error) {
+
+	if max_size <= 0 {
+		return nil, fmt.Errorf("Maximum shard size must be greater than zero")
+	}
+
+	t, err := template.New("shard").Parse(uri_template)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse shard URI template, %w", err)
+	}
+
+	// Ensure that the template varies per write by rendering the first shard's URI for two different writes
+
+	uris := make([]string, 2)
+
+	for i, write_id := range []string{"a", "b"} {
+
+		uri, err := renderShardURI(t, ShardTemplateVars{Index: 0, WriteID: write_id})
+
+		if err != nil {
+			return nil, err
+		}
+
+		uris[i] = uri
+	}
+
+	if uris[0] == uris[1] {
+		return nil, fmt.Errorf("Shard URI template must include the {{.WriteID}} variable")
+	}
+
+	c := &shardConfig{
+		max_size: max_size,
+		template: t,
+	}
+
+	return c, nil
+}
+
+// commitShards copies the data in the intermediate temporary file, which is 'size' bytes, to one or more shards and then
+// writes a manifest listing those shards to the final path. The caller must hold aw.mu.
+func (aw *AtomicWriter) commitShards(ctx context.Context, size int64) error {
+
+	write_id, err := newWriteID()
+
+	if err != nil {
+		return err
+	}
+
+	m := &ShardManifest{
+		Type:   SHARD_MANIFEST_TYPE,
+		Size:   size,
+		Shards: make([]*ShardManifestEntry, 0),
+	}
+
+	for offset := int64(0); offset < size; offset += aw.shard.max_size {
+
+		length := aw.shard.max_size
+
+		if offset+length > size {
+			length = size - offset
+		}
+
+		vars := ShardTemplateVars{
+			Index:   len(m.Shards),
+			WriteID: write_id,
+		}
+
+		uri, err := renderShardURI(aw.shard.template, vars)
+
+		if err != nil {
+			return err
+		}
+
+		err = aw.copyToURI(ctx, uri, offset, length)
+
+		if err != nil {
+			return fmt.Errorf("Failed to write shard %s, %w", uri, err)
+		}
+
+		e := &ShardManifestEntry{
+			URI:  uri,
+			Size: length,
+		}
+
+		m.Shards = append(m.Shards, e)
+	}
+
+	body, err := json.Marshal(m)
+
+	if err != nil {
+		return fmt.Errorf("Failed to marshal manifest, %w", err)
+	}
+
+	wr, err := aw.bucket.NewWriter(ctx, aw.final_path, aw.final_opts)
+
+	if err != nil {
+		return fmt.Errorf("Failed to open %s for writing, %w", aw.final_path, err)
+	}
+
+	_, err = wr.Write(body)
+
+	if err != nil {
+		wr.Close()
+		return fmt.Errorf("Failed to write manifest %s, %w", aw.final_path, err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close %s, %w", aw.final_path, err)
+	}
+
+	return nil
+}
+
+// renderShardURI renders 't' using 'vars' to derive the URI for a shard.
+func renderShardURI(t *template.Template, vars ShardTemplateVars) (string, error) {
+
+	var buf bytes.Buffer
+
+	err := t.Execute(&buf, vars)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to render shard URI template, %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// newWriteID returns a new random, hex-encoded, value used to identify a single write. It is used for the WriteID property of
+// ShardTemplateVars and the WriterID property of WriterEvent.
+func newWriteID() (string, error) {
+
+	b := make([]byte, 8)
+
+	_, err := rand.Read(b)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to generate write ID, %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// ReadSharded reads the data stored at 'uri'. If that data is a manifest written by the `WithShard` option then the data in each
+// of the shards it lists is read, verified against the size recorded in the manifest, and returned in order. Otherwise the data
+// stored at 'uri' is returned as-is.
+func ReadSharded(ctx context.Context, uri string) ([]byte, error) {
+
+	body, err := readBytes(ctx, uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var m ShardManifest
+
+	err = json.Unmarshal(body, &m)
+
+	if err != nil || m.Type != SHARD_MANIFEST_TYPE {
+		return body, nil
+	}
+
+	var buf bytes.Buffer
+
+	for _, e := range m.Shards {
+
+		shard, err := readBytes(ctx, e.URI)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read shard %s, %w", e.URI, err)
+		}
+
+		if int64(len(shard)) != e.Size {
+			return nil, fmt.Errorf("Unexpected size for shard %s, expected %d bytes but got %d", e.URI, e.Size, len(shard))
+		}
+
+		buf.Write(shard)
+	}
+
+	if int64(buf.Len()) != m.Size {
+		return nil, fmt.Errorf("Unexpected size for %s, expected %d bytes but got %d", uri, m.Size, buf.Len())
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,208 @@
+package atomicwrite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithShard(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+	uri_template := "file://" + filepath.Join(root, "atomicwrite.txt.{{.WriteID}}.part.{{.Index}}")
+
+	body := strings.Repeat(HELLO_WORLD, 3)
+
+	err := writeBytes(ctx, path, []byte(body), WithShard(10, uri_template))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	manifest, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	var m ShardManifest
+
+	err = json.Unmarshal(manifest, &m)
+
+	if err != nil {
+		t.Fatalf("Failed to unmarshal manifest, %v", err)
+	}
+
+	if m.Type != SHARD_MANIFEST_TYPE || m.Size != int64(len(body)) || len(m.Shards) != 4 {
+		t.Fatalf("Unexpected manifest: %s", string(manifest))
+	}
+
+	for i, e := range m.Shards {
+
+		expected := int64(10)
+
+		if i == 3 {
+			expected = 3
+		}
+
+		if e.Size != expected {
+			t.Fatalf("Unexpected size for shard %d: %d", i, e.Size)
+		}
+	}
+
+	v, err := ReadSharded(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to read sharded %s, %v", path, err)
+	}
+
+	if string(v) != body {
+		t.Fatalf("Unexpected body: %s", string(v))
+	}
+
+	err = os.Remove(strings.TrimPrefix(m.Shards[2].URI, "file://"))
+
+	if err != nil {
+		t.Fatalf("Failed to remove shard, %v", err)
+	}
+
+	_, err = ReadSharded(ctx, path)
+
+	if err == nil {
+		t.Fatalf("Expected missing shard to fail")
+	}
+}
+
+func TestWithShardSmall(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+	uri_template := "file://" + filepath.Join(root, "atomicwrite.txt.{{.WriteID}}.part.{{.Index}}")
+
+	err := writeBytes(ctx, path, []byte(HELLO_WORLD), WithShard(int64(len(HELLO_WORLD)), uri_template))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	for _, e := range entries {
+
+		if e.Name() != "atomicwrite.txt" && e.Name() != "atomicwrite.txt.attrs" {
+			t.Fatalf("Unexpected file %s", e.Name())
+		}
+	}
+
+	v, err := ReadSharded(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to read sharded %s, %v", path, err)
+	}
+
+	if string(v) != HELLO_WORLD {
+		t.Fatalf("Unexpected body: %s", string(v))
+	}
+}
+
+func TestWithShardErrors(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	_, err := New(ctx, path, WithShard(0, "atomicwrite.txt.{{.WriteID}}.part.{{.Index}}"))
+
+	if err == nil {
+		t.Fatalf("Expected invalid shard size to fail")
+	}
+
+	_, err = New(ctx, path, WithShard(10, "atomicwrite.txt.part.{{.Index"))
+
+	if err == nil {
+		t.Fatalf("Expected invalid template to fail")
+	}
+
+	_, err = New(ctx, path, WithShard(10, "atomicwrite.txt.{{.WriteID}}.part.{{.Index}}"), WithAdditionalPaths(filepath.Join(root, "latest.txt")))
+
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported, got %v", err)
+	}
+
+	_, err = New(ctx, path, WithShard(10, "atomicwrite.txt.part.{{.Index}}"))
+
+	if err == nil {
+		t.Fatalf("Expected template without WriteID to fail")
+	}
+}
+
+func TestWithShardRewrite(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+	uri_template := "file://" + filepath.Join(root, "atomicwrite.txt.{{.WriteID}}.part.{{.Index}}")
+
+	first := strings.Repeat(HELLO_WORLD, 3)
+
+	err := writeBytes(ctx, path, []byte(first), WithShard(10, uri_template))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	manifest, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	// A second write must not replace the shards listed by the first manifest, which readers may still be using
+
+	second := strings.Repeat("goodbye world", 3)
+
+	err = writeBytes(ctx, path, []byte(second), WithShard(10, uri_template))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	old_path := filepath.Join(root, "atomicwrite-previous.txt")
+
+	err = os.WriteFile(old_path, manifest, 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", old_path, err)
+	}
+
+	for p, expected := range map[string]string{old_path: first, path: second} {
+
+		v, err := ReadSharded(ctx, p)
+
+		if err != nil {
+			t.Fatalf("Failed to read sharded %s, %v", p, err)
+		}
+
+		if string(v) != expected {
+			t.Fatalf("Unexpected body for %s: %s", p, string(v))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package atomicwrite
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+)
+
+// SIGNATURE_EXTENSION is the extension appended to the URI of the data written by the `WriteAndSign` method to
+// derive the URI of its detached signature.
+const SIGNATURE_EXTENSION string = ".sig"
+
+// SIGNATURE_PEM_TYPE is the PEM block type used to encode detached signatures written by the `WriteAndSign` method.
+const SIGNATURE_PEM_TYPE string = "SIGNATURE"
+
+// WriteAndSign atomically writes 'data' to 'uri' and then atomically writes a detached signature for 'data', created
+// using 'signer', to 'uri' + ".sig". The signature is PEM-encoded. For Ed25519 signers 'data' is signed directly; for all
+// other signers (RSA, ECDSA) the SHA-256 digest of 'data' is signed. The two writes are not a single transaction: If the
+// signature can not be written the data written to 'uri' is not rolled back, but the error returned makes this explicit.
+func WriteAndSign(ctx context.Context, uri string, data []byte, signer crypto.Signer, opts ...Option) error {
+
+	sig_uri, err := sidecarURI(uri, SIGNATURE_EXTENSION)
+
+	if err != nil {
+		return fmt.Errorf("Failed to derive signature URI, %w", err)
+	}
+
+	sig, err := signData(signer, data)
+
+	if err != nil {
+		return err
+	}
+
+	enc := pem.EncodeToMemory(&pem.Block{
+		Type:  SIGNATURE_PEM_TYPE,
+		Bytes: sig,
+	})
+
+	err = writeBytes(ctx, uri, data, opts...)
+
+	if err != nil {
+		return err
+	}
+
+	err = writeBytes(ctx, sig_uri, enc, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Data was written to %s but failed to write signature, %w", uri, err)
+	}
+
+	return nil
+}
+
+// signData signs 'data' using 'signer'.
+func signData(signer crypto.Signer, data []byte) ([]byte, error) {
+
+	var digest []byte
+	var hash crypto.Hash
+
+	switch signer.Public().(type) {
+	case ed25519.PublicKey:
+		digest = data
+		hash = crypto.Hash(0)
+	default:
+		sum := sha256.Sum256(data)
+		digest = sum[:]
+		hash = crypto.SHA256
+	}
+
+	sig, err := signer.Sign(rand.Reader, digest, hash)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to sign data, %w", err)
+	}
+
+	return sig, nil
+}
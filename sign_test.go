@@ -0,0 +1,83 @@
+package atomicwrite
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAndSign(t *testing.T) {
+
+	ctx := context.Background()
+
+	tmpdir := t.TempDir()
+
+	ecdsa_key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key, %v", err)
+	}
+
+	_, ed25519_key, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key, %v", err)
+	}
+
+	// ECDSA
+
+	path := filepath.Join(tmpdir, "ecdsa.txt")
+
+	err = WriteAndSign(ctx, path, []byte(HELLO_WORLD), ecdsa_key)
+
+	if err != nil {
+		t.Fatalf("Failed to write and sign data, %v", err)
+	}
+
+	sig := readTestSignature(t, path+SIGNATURE_EXTENSION)
+	digest := sha256.Sum256([]byte(HELLO_WORLD))
+
+	if !ecdsa.VerifyASN1(&ecdsa_key.PublicKey, digest[:], sig) {
+		t.Fatalf("Failed to verify ECDSA signature")
+	}
+
+	// Ed25519
+
+	path = filepath.Join(tmpdir, "ed25519.txt")
+
+	err = WriteAndSign(ctx, path, []byte(HELLO_WORLD), ed25519_key)
+
+	if err != nil {
+		t.Fatalf("Failed to write and sign data, %v", err)
+	}
+
+	sig = readTestSignature(t, path+SIGNATURE_EXTENSION)
+
+	if !ed25519.Verify(ed25519_key.Public().(ed25519.PublicKey), []byte(HELLO_WORLD), sig) {
+		t.Fatalf("Failed to verify Ed25519 signature")
+	}
+}
+
+func readTestSignature(t *testing.T, path string) []byte {
+
+	enc, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	block, _ := pem.Decode(enc)
+
+	if block == nil || block.Type != SIGNATURE_PEM_TYPE {
+		t.Fatalf("Failed to decode signature in %s", path)
+	}
+
+	return block.Bytes
+}
@@ -0,0 +1,183 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+	"io"
+	"sync/atomic"
+)
+
+// type snapshotReader implements the io.ReadCloser interface for reading a snapshot copy of a blob. The snapshot
+// copy is removed when the `Close` method is invoked.
+type snapshotReader struct {
+	*blob.Reader
+	// The underlying blob.Bucket instance where the snapshot copy is stored
+	bucket *blob.Bucket
+	// The path (relative to bucket) of the snapshot copy
+	snapshot_path string
+	// The context.Context instance used to remove the snapshot copy, which carries the values of the context used to create
+	// it but is never cancelled
+	cleanup_ctx context.Context
+}
+
+// openSnapshot copies 'key' to a new intermediate path in 'bucket' and returns a snapshotReader instance for reading
+// that copy. This ensures that the data being read is not modified, by this or other processes, while it is being read.
+// If 'key' does not exist the error returned by the `blob.Bucket.Copy` method is returned.
+func openSnapshot(ctx context.Context, bucket *blob.Bucket, key string) (*snapshotReader, error) {
+
+	snapshot_path, err := deriveAtomicPath(ctx, bucket, key, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = bucket.Copy(ctx, snapshot_path, key, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create snapshot of %s, %w", key, err)
+	}
+
+	r, err := bucket.NewReader(ctx, snapshot_path, nil)
+
+	if err != nil {
+		bucket.Delete(ctx, snapshot_path)
+		return nil, fmt.Errorf("Failed to open snapshot of %s, %w", key, err)
+	}
+
+	sr := &snapshotReader{
+		Reader:        r,
+		bucket:        bucket,
+		snapshot_path: snapshot_path,
+		cleanup_ctx:   detachContext(ctx),
+	}
+
+	return sr, nil
+}
+
+// Close closes the underlying reader and removes the snapshot copy.
+func (sr *snapshotReader) Close() error {
+
+	sr.Reader.Close()
+
+	err := sr.bucket.Delete(sr.cleanup_ctx, sr.snapshot_path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to delete snapshot %s, %w", sr.snapshot_path, err)
+	}
+
+	return nil
+}
+
+// Snapshot returns an io.ReadCloser instance for reading the data written so far without committing it. Data written by a
+// `blob.Writer` instance is not visible until the writer is closed so the current intermediate temporary file is closed, its
+// contents are copied to a new intermediate temporary file which subsequent writes are appended to, and the old temporary file
+// is returned as the snapshot. The snapshot is removed when the returned reader's `Close` method is invoked. Each call to Snapshot
+// copies all the data written so far. If anything fails after the current temporary file has been closed the writer is aborted.
+func (aw *AtomicWriter) Snapshot() (io.ReadCloser, error) {
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	switch atomic.LoadInt32(&aw.state) {
+	case state_aborted:
+		return nil, ErrAborted
+	case state_closed:
+		return nil, fmt.Errorf("Atomic writer has been closed")
+	}
+
+	snapshot_path, err := aw.roll()
+
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := aw.bucket.NewReader(aw.ctx, snapshot_path, nil)
+
+	if err != nil {
+		aw.bucket.Delete(aw.cleanup_ctx, snapshot_path)
+		return nil, fmt.Errorf("Failed to open snapshot %s, %w", snapshot_path, err)
+	}
+
+	sr := &snapshotReader{
+		Reader:        r,
+		bucket:        aw.bucket,
+		snapshot_path: snapshot_path,
+		cleanup_ctx:   aw.cleanup_ctx,
+	}
+
+	return sr, nil
+}
+
+// roll closes the current intermediate temporary file, which persists the data written so far, and copies its contents to
+// a new intermediate temporary file which subsequent writes are appended to. It returns the path of the closed temporary file
+// which the caller is responsible for removing. If anything fails after the current temporary file has been closed the writer
+// is aborted, since it can no longer be written to. The caller must hold aw.mu.
+func (aw *AtomicWriter) roll() (string, error) {
+
+	// Closing an encrypted writer writes the last chunk so it can not be appended to
+
+	if aw.encrypted {
+		return "", fmt.Errorf("Rolling encrypted writers is not supported, %w", ErrNotSupported)
+	}
+
+	err := aw.writer.Close()
+
+	if err != nil {
+		aw.abortLocked()
+		return "", fmt.Errorf("Failed to close atomic writer, %w", err)
+	}
+
+	// From here on aw.atomic_path refers to a closed blob so aborting the writer
+	// will remove it
+
+	closed_path := aw.atomic_path
+
+	// The new temporary file is derived from the final path, as it is in the `New` constructor, rather than from
+	// closed_path otherwise its key would grow with each roll until the underlying storage rejects it
+
+	atomic_path, err := deriveAtomicPath(aw.ctx, aw.bucket, aw.final_path, nil)
+
+	if err != nil {
+		aw.abortLocked()
+		return "", err
+	}
+
+	wr, err := aw.bucket.NewWriter(aw.ctx, atomic_path, aw.staging_opts)
+
+	if err != nil {
+		aw.abortLocked()
+		return "", fmt.Errorf("Failed to open %s, %w", atomic_path, err)
+	}
+
+	// Aborting the writer cancels aw.ctx so closing wr will discard any data written to it
+
+	discard := func() {
+		aw.abortLocked()
+		wr.Close()
+		aw.bucket.Delete(aw.cleanup_ctx, atomic_path)
+	}
+
+	r, err := aw.bucket.NewReader(aw.ctx, closed_path, nil)
+
+	if err != nil {
+		discard()
+		return "", fmt.Errorf("Failed to open %s, %w", closed_path, err)
+	}
+
+	buf := make([]byte, aw.copy_buffer_size)
+
+	_, err = io.CopyBuffer(struct{ io.Writer }{wr}, struct{ io.Reader }{r}, buf)
+
+	r.Close()
+
+	if err != nil {
+		discard()
+		return "", fmt.Errorf("Failed to copy %s, %w", closed_path, err)
+	}
+
+	aw.writer = wr
+	aw.atomic_path = atomic_path
+
+	return closed_path, nil
+}
@@ -0,0 +1,92 @@
+package atomicwrite
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	uri := "file://" + filepath.Join(root, "atomicwrite.txt") + "?metadata=skip"
+
+	wr, err := New(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	aw := wr.(*AtomicWriter)
+
+	expected := ""
+
+	for _, s := range []string{"Hello", " ", "world"} {
+
+		_, err := aw.Write([]byte(s))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+
+		expected = expected + s
+
+		r, err := aw.Snapshot()
+
+		if err != nil {
+			t.Fatalf("Failed to create snapshot, %v", err)
+		}
+
+		body, err := io.ReadAll(r)
+
+		if err != nil {
+			t.Fatalf("Failed to read snapshot, %v", err)
+		}
+
+		err = r.Close()
+
+		if err != nil {
+			t.Fatalf("Failed to close snapshot, %v", err)
+		}
+
+		if string(body) != expected {
+			t.Fatalf("Unexpected snapshot, expected '%s' but got '%s'", expected, string(body))
+		}
+	}
+
+	err = aw.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	body, err := os.ReadFile(filepath.Join(root, "atomicwrite.txt"))
+
+	if err != nil {
+		t.Fatalf("Failed to read final path, %v", err)
+	}
+
+	if string(body) != expected {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry in %s, got %d", root, len(entries))
+	}
+
+	_, err = aw.Snapshot()
+
+	if err == nil {
+		t.Fatalf("Expected snapshot of closed writer to fail")
+	}
+}
@@ -0,0 +1,96 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+)
+
+// type splitWriter implements the io.WriteCloser interface mirroring writes to two AtomicWriter instances.
+type splitWriter struct {
+	// The AtomicWriter instance for the primary URI
+	primary *AtomicWriter
+	// The AtomicWriter instance for the secondary URI
+	secondary *AtomicWriter
+}
+
+// NewSplit returns a new io.WriteCloser instance which mirrors all writes to AtomicWriter instances for both 'primary_uri' and
+// 'secondary_uri', for example a durable S3 path and a `mem://` cache. When the `Close` method is invoked the primary writer is
+// committed first and then the secondary writer. This is best-effort: the two commits are not atomic with respect to each other.
+// If the secondary commit fails the error is logged but only the error (if any) from the primary commit is returned. If a write
+// to either writer fails both writers are aborted. 'opts' are applied to both writers.
+func NewSplit(ctx context.Context, primary_uri string, secondary_uri string, opts ...Option) (io.WriteCloser, error) {
+
+	primary, err := newAtomicWriter(ctx, primary_uri, opts...)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create primary writer, %w", err)
+	}
+
+	secondary, err := newAtomicWriter(ctx, secondary_uri, opts...)
+
+	if err != nil {
+		primary.Abort()
+		return nil, fmt.Errorf("Failed to create secondary writer, %w", err)
+	}
+
+	w := &splitWriter{
+		primary:   primary,
+		secondary: secondary,
+	}
+
+	return w, nil
+}
+
+// Write writes 'b' to both the primary and secondary writers.
+func (w *splitWriter) Write(b []byte) (int, error) {
+
+	n, err := w.primary.Write(b)
+
+	if err != nil {
+		w.Abort()
+		return n, fmt.Errorf("Failed to write to primary writer, %w", err)
+	}
+
+	_, err = w.secondary.Write(b)
+
+	if err != nil {
+		w.Abort()
+		return n, fmt.Errorf("Failed to write to secondary writer, %w", err)
+	}
+
+	return n, nil
+}
+
+// Close commits the primary writer and then the secondary writer. Errors committing the secondary writer are logged but not returned.
+func (w *splitWriter) Close() error {
+
+	err := w.primary.Close()
+
+	if err != nil {
+		w.secondary.Abort()
+		return err
+	}
+
+	err = w.secondary.Close()
+
+	if err != nil {
+		log.Printf("Failed to commit secondary writer for %s, %v", w.secondary.final_path, err)
+	}
+
+	return nil
+}
+
+// Abort aborts both the primary and secondary writers.
+func (w *splitWriter) Abort() error {
+
+	err := w.primary.Abort()
+
+	if err != nil {
+		w.secondary.Abort()
+		return err
+	}
+
+	return w.secondary.Abort()
+}
@@ -0,0 +1,97 @@
+package atomicwrite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewSplit(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	primary_path := filepath.Join(root, "primary.txt")
+	secondary_path := filepath.Join(root, "secondary.txt")
+
+	wr, err := NewSplit(ctx, primary_path, secondary_path)
+
+	if err != nil {
+		t.Fatalf("Failed to create split writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close split writer, %v", err)
+	}
+
+	for _, p := range []string{primary_path, secondary_path} {
+
+		body, err := os.ReadFile(p)
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", p, err)
+		}
+
+		if string(body) != HELLO_WORLD {
+			t.Fatalf("Unexpected body for %s: %s", p, string(body))
+		}
+	}
+}
+
+func TestNewSplitSecondaryError(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	primary_path := filepath.Join(root, "primary.txt")
+
+	// The secondary bucket is removed before Close is invoked so committing it fails
+
+	secondary_root := filepath.Join(root, "secondary")
+
+	err := os.Mkdir(secondary_root, 0755)
+
+	if err != nil {
+		t.Fatalf("Failed to create %s, %v", secondary_root, err)
+	}
+
+	wr, err := NewSplit(ctx, primary_path, filepath.Join(secondary_root, "secondary.txt"))
+
+	if err != nil {
+		t.Fatalf("Failed to create split writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = os.RemoveAll(secondary_root)
+
+	if err != nil {
+		t.Fatalf("Failed to remove %s, %v", secondary_root, err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Expected secondary error to be ignored, %v", err)
+	}
+
+	_, err = os.Stat(primary_path)
+
+	if err != nil {
+		t.Fatalf("Expected primary path to be committed, %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+	"io"
+	"net/url"
+	"sync"
+)
+
+// streaming_schemes are the gocloud.dev/blob URI schemes whose writers upload data in parts (S3 multipart uploads, GCS resumable
+// uploads and Azure block blobs) and only publish the object once all the parts have been uploaded and the writer is closed.
+var streaming_schemes = map[string]bool{
+	"s3":     true,
+	"gs":     true,
+	"azblob": true,
+}
+
+// type StreamingAtomicWriter implements an atomic io.WriteCloser instance which, for backends that natively support multipart
+// uploads, writes data directly to the final path without an intermediate temporary file. For example, gocloud.dev/blob/s3blob
+// writers upload data in parts and call `CompleteMultipartUpload`, which is itself atomic, when the writer is closed; until then
+// the object does not exist. This avoids writing (and paying for) the data twice. For all other backends, or when an option
+// which requires an intermediate temporary file is specified, StreamingAtomicWriter falls back to an AtomicWriter instance.
+type StreamingAtomicWriter struct {
+	// The underlying AtomicWriter instance, if data is written to an intermediate temporary file
+	aw *AtomicWriter
+	// The underlying blob.Writer instance, if data is written directly to final_path
+	writer *blob.Writer
+	// The final path (relative to bucket) that data will be written to
+	final_path string
+	// The function used to cancel the context used to create writer
+	cancel context.CancelFunc
+	// The state of the writer (open, closed or aborted)
+	state int32
+	// A mutex guarding writer and state
+	mu sync.Mutex
+}
+
+// NewStreaming returns a new StreamingAtomicWriter instance for 'uri'. 'uri' and 'opts' are the same as the `New` constructor.
+// Data is written directly to the final path if the scheme of 'uri' is "s3", "gs" or "azblob" and 'opts' only contain options
+// which configure the bucket or the final object (`WithBucketOpener`, `WithBeforeWrite`, `WithFinalBeforeWrite`, `WithMetadataKey`,
+// `WithAzureHeaders`, `WithKeyNormalizer` and `WithLogger`). Otherwise data is written using an AtomicWriter instance.
+func NewStreaming(ctx context.Context, uri string, opts ...Option) (*StreamingAtomicWriter, error) {
+
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	err := o.validateURIs(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(uri)
+
+	if err != nil || !streaming_schemes[u.Scheme] || o.requiresStaging() {
+
+		aw, err := newAtomicWriter(ctx, uri, opts...)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sw := &StreamingAtomicWriter{
+			aw:         aw,
+			final_path: aw.FinalPath(),
+		}
+
+		return sw, nil
+	}
+
+	bucket_uri, final_path, err := parseURI(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	final_path = normalizeKey(o.key_normalizers, final_path)
+
+	if final_path == "" {
+		return nil, fmt.Errorf("Failed to derive key from URI, normalized key is empty")
+	}
+
+	final_opts, err := o.finalWriterOptions(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	final_opts = o.withPartSize(final_opts, bucket_uri)
+
+	bucket, err := o.wrappedBucketOpener()(ctx, bucket_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open bucket %s, %w", bucket_uri, err)
+	}
+
+	// As with AtomicWriter cancelling the context used to create the writer is how
+	// writes are aborted; the multipart upload is never completed.
+
+	wr_ctx, cancel := context.WithCancel(ctx)
+
+	wr, err := bucket.NewWriter(wr_ctx, final_path, final_opts)
+
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("Failed to open %s, %w", final_path, err)
+	}
+
+	if o.logger != nil {
+		o.logger.Printf("Writing %s directly to %s", final_path, bucket_uri)
+	}
+
+	sw := &StreamingAtomicWriter{
+		writer:     wr,
+		final_path: final_path,
+		cancel:     cancel,
+	}
+
+	return sw, nil
+}
+
+// Streaming returns true if data is written directly to the final path rather than to an intermediate temporary file.
+func (sw *StreamingAtomicWriter) Streaming() bool {
+	return sw.aw == nil
+}
+
+// FinalPath returns the path (relative to the bucket) that data is committed to when the `Close` method is invoked.
+func (sw *StreamingAtomicWriter) FinalPath() string {
+	return sw.final_path
+}
+
+// Write writes 'b' to the underlying writer instance.
+func (sw *StreamingAtomicWriter) Write(b []byte) (int, error) {
+
+	if sw.aw != nil {
+		return sw.aw.Write(b)
+	}
+
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	switch sw.state {
+	case state_aborted:
+		return 0, ErrAborted
+	case state_closed:
+		return 0, fmt.Errorf("Atomic writer has been closed")
+	}
+
+	return sw.writer.Write(b)
+}
+
+// Close commits the data written to the final path. For backends which support multipart uploads this completes the upload.
+// Subsequent calls to Close are no-ops which return nil, unless the writer has been aborted in which case ErrAborted is returned.
+func (sw *StreamingAtomicWriter) Close() error {
+
+	if sw.aw != nil {
+		return sw.aw.Close()
+	}
+
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	switch sw.state {
+	case state_aborted:
+		return ErrAborted
+	case state_closed:
+		return nil
+	}
+
+	sw.state = state_closed
+
+	defer sw.cancel()
+
+	err := sw.writer.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close %s, %w", sw.final_path, err)
+	}
+
+	return nil
+}
+
+// Abort discards any data written without committing it to the final path. For backends which support multipart uploads this
+// aborts the upload. Abort is a no-op if the writer has already been closed or aborted so it is safe to defer calling it
+// immediately after the writer has been created.
+func (sw *StreamingAtomicWriter) Abort() error {
+
+	if sw.aw != nil {
+		return sw.aw.Abort()
+	}
+
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if sw.state != state_open {
+		return nil
+	}
+
+	sw.state = state_aborted
+
+	sw.cancel()
+
+	// The error is expected since the context has been cancelled
+	sw.writer.Close()
+
+	return nil
+}
+
+var _ io.WriteCloser = (*StreamingAtomicWriter)(nil)
+
+// requiresStaging returns true if any of the options require data to be written to an intermediate temporary file before it
+// is committed to the final path.
+func (o *options) requiresStaging() bool {
+
+	switch {
+	case o.writer_opts != nil, len(o.listeners) > 0, o.quota_uri != "", len(o.additional_uris) > 0:
+		return true
+	case o.flush_interval > 0, len(o.notifiers) > 0, o.flock, o.tee_http != nil:
+		return true
+	case o.encryption_key != nil, o.encrypt_writer != nil, o.shard_template != "":
+		return true
+	case o.if_not_exists, o.if_match != "", o.parquet_validation:
+		return true
+	default:
+		return false
+	}
+}
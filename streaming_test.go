@@ -0,0 +1,151 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"gocloud.dev/gcerrors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStreaming(t *testing.T) {
+
+	ctx := context.Background()
+
+	mem := memblob.OpenBucket(nil)
+	defer mem.Close()
+
+	// Pretend that the in-memory bucket is an S3 bucket
+
+	opener := func(ctx context.Context, bucket_uri string) (*blob.Bucket, error) {
+		return mem, nil
+	}
+
+	sw, err := NewStreaming(ctx, "s3://example/test.txt", WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to create streaming writer, %v", err)
+	}
+
+	if !sw.Streaming() {
+		t.Fatalf("Expected writer to stream data directly to the final path")
+	}
+
+	_, err = sw.Write([]byte("hello world"))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	exists, err := mem.Exists(ctx, "test.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to determine whether test.txt exists, %v", err)
+	}
+
+	if exists {
+		t.Fatalf("Expected test.txt not to exist before Close")
+	}
+
+	err = sw.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close streaming writer, %v", err)
+	}
+
+	v, err := mem.ReadAll(ctx, "test.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to read test.txt, %v", err)
+	}
+
+	if string(v) != "hello world" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+
+	sw, err = NewStreaming(ctx, "s3://example/aborted.txt", WithBucketOpener(opener))
+
+	if err != nil {
+		t.Fatalf("Failed to create streaming writer, %v", err)
+	}
+
+	_, err = sw.Write([]byte("hello world"))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	err = sw.Abort()
+
+	if err != nil {
+		t.Fatalf("Failed to abort streaming writer, %v", err)
+	}
+
+	err = sw.Close()
+
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("Expected ErrAborted, got %v", err)
+	}
+
+	_, err = mem.Attributes(ctx, "aborted.txt")
+
+	if gcerrors.Code(err) != gcerrors.NotFound {
+		t.Fatalf("Expected aborted.txt not to exist, %v", err)
+	}
+
+	sw, err = NewStreaming(ctx, "s3://example/conditional.txt", WithBucketOpener(opener), WithIfNotExists())
+
+	if err != nil {
+		t.Fatalf("Failed to create streaming writer, %v", err)
+	}
+
+	defer sw.Abort()
+
+	if sw.Streaming() {
+		t.Fatalf("Expected WithIfNotExists to require an intermediate temporary file")
+	}
+}
+
+func TestNewStreamingFallback(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	sw, err := NewStreaming(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to create streaming writer, %v", err)
+	}
+
+	if sw.Streaming() {
+		t.Fatalf("Expected writer to use an intermediate temporary file")
+	}
+
+	_, err = sw.Write([]byte("hello world"))
+
+	if err != nil {
+		t.Fatalf("Failed to write data, %v", err)
+	}
+
+	err = sw.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close streaming writer, %v", err)
+	}
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != "hello world" {
+		t.Fatalf("Unexpected body: %q", string(v))
+	}
+}
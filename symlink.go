@@ -0,0 +1,61 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+)
+
+// ErrNotSupported is returned when an operation is not supported for a given URI.
+var ErrNotSupported = errors.New("Operation not supported")
+
+// AtomicSymlink creates (or replaces) a symbolic link at 'link_uri' pointing to 'target'. The link is first created at
+// a temporary path in the same directory as 'link_uri' and then renamed to its final path, so readers will only ever see
+// the previous link or the new one. This is useful for maintaining "latest" pointers in directories of artifacts. 'link_uri'
+// must be a local filesystem path or a `file://` URI; all other URIs return ErrNotSupported. 'target' is used as-is and may
+// be a relative path.
+func AtomicSymlink(ctx context.Context, target string, link_uri string) error {
+
+	link_path, err := localPath(link_uri)
+
+	if err != nil {
+		return err
+	}
+
+	max_tries := 15
+
+	for i := 0; i < max_tries; i++ {
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// pass
+		}
+
+		atomic_path := fmt.Sprintf("%s-%d", link_path, rand.Int())
+
+		err := os.Symlink(target, atomic_path)
+
+		if os.IsExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("Failed to create symbolic link %s, %w", atomic_path, err)
+		}
+
+		err = os.Rename(atomic_path, link_path)
+
+		if err != nil {
+			os.Remove(atomic_path)
+			return fmt.Errorf("Failed to rename %s to %s, %w", atomic_path, link_path, err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("Failed to derive temporary path for %s after %d tries", link_path, max_tries)
+}
@@ -0,0 +1,57 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicSymlink(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	link_path := filepath.Join(root, "latest")
+
+	for _, target := range []string{"v1.txt", "v2.txt"} {
+
+		err := AtomicSymlink(ctx, target, link_path)
+
+		if err != nil {
+			t.Fatalf("Failed to create symbolic link to %s, %v", target, err)
+		}
+
+		v, err := os.Readlink(link_path)
+
+		if err != nil {
+			t.Fatalf("Failed to read symbolic link, %v", err)
+		}
+
+		if v != target {
+			t.Fatalf("Unexpected link target, expected %s but got %s", target, v)
+		}
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry in %s, got %d", root, len(entries))
+	}
+}
+
+func TestAtomicSymlinkNotSupported(t *testing.T) {
+
+	ctx := context.Background()
+
+	err := AtomicSymlink(ctx, "v1.txt", "mem://latest")
+
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported, got %v", err)
+	}
+}
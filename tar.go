@@ -0,0 +1,86 @@
+package atomicwrite
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+)
+
+// type TarFile defines a file to be added to a tar archive by the `WriteTar` method.
+type TarFile struct {
+	// The name of the file in the tar archive
+	Name string
+	// The permission and mode bits for the file
+	Mode int64
+	// The contents of the file
+	Data []byte
+}
+
+// WriteTar atomically writes a tar archive containing 'files' to 'uri'. All of the file data is held in memory so
+// for large archives you should use the `EncodeTar` method instead.
+func WriteTar(ctx context.Context, uri string, files []TarFile, opts ...Option) error {
+
+	fn := func(tw *tar.Writer) error {
+
+		for _, f := range files {
+
+			hdr := &tar.Header{
+				Name: f.Name,
+				Mode: f.Mode,
+				Size: int64(len(f.Data)),
+			}
+
+			err := tw.WriteHeader(hdr)
+
+			if err != nil {
+				return fmt.Errorf("Failed to write header for %s, %w", f.Name, err)
+			}
+
+			_, err = tw.Write(f.Data)
+
+			if err != nil {
+				return fmt.Errorf("Failed to write %s, %w", f.Name, err)
+			}
+		}
+
+		return nil
+	}
+
+	return EncodeTar(ctx, uri, fn, opts...)
+}
+
+// EncodeTar atomically writes a tar archive to 'uri' whose contents are written by 'fn'. The `tar.Writer` instance passed
+// to 'fn' writes directly to an intermediate temporary file so the contents of the archive are not buffered in memory. If 'fn'
+// returns an error the temporary file is discarded and nothing is written to 'uri'.
+func EncodeTar(ctx context.Context, uri string, fn func(*tar.Writer) error, opts ...Option) error {
+
+	aw, err := newAtomicWriter(ctx, uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create atomic writer, %w", err)
+	}
+
+	tw := tar.NewWriter(aw)
+
+	err = fn(tw)
+
+	if err != nil {
+		aw.Abort()
+		return err
+	}
+
+	err = tw.Close()
+
+	if err != nil {
+		aw.Abort()
+		return fmt.Errorf("Failed to close tar writer, %w", err)
+	}
+
+	err = aw.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close atomic writer, %w", err)
+	}
+
+	return nil
+}
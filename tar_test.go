@@ -0,0 +1,93 @@
+package atomicwrite
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteTar(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.tar")
+
+	files := []TarFile{
+		{Name: "a.txt", Mode: 0644, Data: []byte("a")},
+		{Name: "b.txt", Mode: 0600, Data: []byte(HELLO_WORLD)},
+	}
+
+	err := WriteTar(ctx, path, files)
+
+	if err != nil {
+		t.Fatalf("Failed to write tar archive, %v", err)
+	}
+
+	r, err := os.Open(path)
+
+	if err != nil {
+		t.Fatalf("Failed to open %s, %v", path, err)
+	}
+
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+
+	for _, f := range files {
+
+		hdr, err := tr.Next()
+
+		if err != nil {
+			t.Fatalf("Failed to read header for %s, %v", f.Name, err)
+		}
+
+		if hdr.Name != f.Name || hdr.Mode != f.Mode {
+			t.Fatalf("Unexpected header for %s: %s (%o)", f.Name, hdr.Name, hdr.Mode)
+		}
+
+		body, err := io.ReadAll(tr)
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", f.Name, err)
+		}
+
+		if string(body) != string(f.Data) {
+			t.Fatalf("Unexpected data for %s: %s", f.Name, string(body))
+		}
+	}
+
+	_, err = tr.Next()
+
+	if err != io.EOF {
+		t.Fatalf("Expected end of archive, got %v", err)
+	}
+}
+
+func TestEncodeTarError(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.tar")
+
+	fail := errors.New("fail")
+
+	fn := func(tw *tar.Writer) error {
+		return fail
+	}
+
+	err := EncodeTar(ctx, path, fn)
+
+	if !errors.Is(err, fail) {
+		t.Fatalf("Expected callback error, got %v", err)
+	}
+
+	_, err = os.Stat(path)
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected %s not to exist, %v", path, err)
+	}
+}
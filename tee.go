@@ -0,0 +1,152 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// type teeHTTPConfig defines the HTTP request that data is streamed to by the `WithTeeToHTTP` option.
+type teeHTTPConfig struct {
+	// The URL the request is sent to
+	url string
+	// The HTTP method used for the request
+	method string
+	// Zero or more headers to assign to the request
+	header http.Header
+}
+
+// type httpTee streams data written to an AtomicWriter instance to the body of an HTTP request.
+type httpTee struct {
+	// The configuration for the HTTP request
+	config *teeHTTPConfig
+	// The write side of the pipe whose read side is the body of the HTTP request, or nil if the request has not been started
+	pw *io.PipeWriter
+	// A channel which receives the result of the HTTP request
+	done chan error
+}
+
+// WithTeeToHTTP returns an Option which streams data written to an AtomicWriter instance to the body of an HTTP request, in
+// parallel with the data written to the intermediate temporary file, using an `io.Pipe`. The request is sent to 'url' using
+// 'method' (for example "POST" or "PUT") with 'header' (which may be nil) when the first byte is written. Writes block until
+// the HTTP client has read the data so a slow endpoint will slow writes down. The request is finished when the `Close` method
+// is invoked and before data is copied to the final path: if the request fails, or the response status code is not 2xx, the
+// writer is discarded and `Close` returns an error. Aborting the writer cancels the request. If no data is written no request
+// is sent.
+func WithTeeToHTTP(url string, method string, header http.Header) Option {
+
+	return func(o *options) {
+
+		o.tee_http = &teeHTTPConfig{
+			url:    url,
+			method: method,
+			header: header,
+		}
+	}
+}
+
+// newHTTPTee returns a new httpTee instance for 'config'.
+func newHTTPTee(config *teeHTTPConfig) *httpTee {
+
+	t := &httpTee{
+		config: config,
+	}
+
+	return t
+}
+
+// write writes 'b' to the body of the HTTP request, starting the request (using 'ctx') if necessary.
+func (t *httpTee) write(ctx context.Context, b []byte) error {
+
+	if len(b) == 0 {
+		return nil
+	}
+
+	if t.pw == nil {
+
+		err := t.start(ctx)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := t.pw.Write(b)
+
+	if err != nil {
+		return fmt.Errorf("Failed to write data to %s, %w", t.config.url, err)
+	}
+
+	return nil
+}
+
+// start creates the HTTP request, using 'ctx', and sends it in a separate goroutine.
+func (t *httpTee) start(ctx context.Context) error {
+
+	pr, pw := io.Pipe()
+
+	req, err := http.NewRequestWithContext(ctx, t.config.method, t.config.url, pr)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create request for %s, %w", t.config.url, err)
+	}
+
+	for k, v := range t.config.header {
+		req.Header[k] = v
+	}
+
+	t.pw = pw
+	t.done = make(chan error, 1)
+
+	go func() {
+
+		rsp, err := http.DefaultClient.Do(req)
+
+		if err != nil {
+			err = fmt.Errorf("Failed to send request to %s, %w", t.config.url, err)
+			pr.CloseWithError(err)
+			t.done <- err
+			return
+		}
+
+		defer rsp.Body.Close()
+
+		io.Copy(io.Discard, rsp.Body)
+
+		if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+			err = fmt.Errorf("Request to %s failed with status %s", t.config.url, rsp.Status)
+			pr.CloseWithError(err)
+			t.done <- err
+			return
+		}
+
+		// Unblock any writes if the server responds before reading the entire body
+		pr.Close()
+		t.done <- nil
+	}()
+
+	return nil
+}
+
+// finish closes the body of the HTTP request and waits for the response. It is a no-op if the request was never started.
+func (t *httpTee) finish() error {
+
+	if t.pw == nil {
+		return nil
+	}
+
+	t.pw.Close()
+	return <-t.done
+}
+
+// abort closes the body of the HTTP request with ErrAborted. The request itself is cancelled by cancelling the context
+// used to create it. It is a no-op if the request was never started.
+func (t *httpTee) abort() {
+
+	if t.pw == nil {
+		return
+	}
+
+	t.pw.CloseWithError(ErrAborted)
+}
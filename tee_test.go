@@ -0,0 +1,147 @@
+package atomicwrite
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithTeeToHTTP(t *testing.T) {
+
+	ctx := context.Background()
+
+	received := make(chan string, 1)
+
+	handler := func(rsp http.ResponseWriter, req *http.Request) {
+
+		body, _ := io.ReadAll(req.Body)
+
+		if req.Method != http.MethodPut || req.Header.Get("X-Example") != "example" {
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		received <- string(body)
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(handler))
+	defer s.Close()
+
+	header := http.Header{}
+	header.Set("X-Example", "example")
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	wr, err := New(ctx, path, WithTeeToHTTP(s.URL, http.MethodPut, header))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	for _, s := range []string{"Hello", " ", "world"} {
+
+		_, err = wr.Write([]byte(s))
+
+		if err != nil {
+			t.Fatalf("Failed to write bytes, %v", err)
+		}
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	if v := <-received; v != HELLO_WORLD {
+		t.Fatalf("Unexpected request body: %s", v)
+	}
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(body) != HELLO_WORLD {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+}
+
+func TestWithTeeToHTTPError(t *testing.T) {
+
+	ctx := context.Background()
+
+	handler := func(rsp http.ResponseWriter, req *http.Request) {
+		io.Copy(io.Discard, req.Body)
+		http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(handler))
+	defer s.Close()
+
+	root := t.TempDir()
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	wr, err := New(ctx, path, WithTeeToHTTP(s.URL, http.MethodPost, nil))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write([]byte(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write bytes, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err == nil {
+		t.Fatalf("Expected close to fail")
+	}
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no files, got %d", len(entries))
+	}
+}
+
+func TestWithTeeToHTTPNoData(t *testing.T) {
+
+	requests := 0
+
+	handler := func(rsp http.ResponseWriter, req *http.Request) {
+		requests += 1
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(handler))
+	defer s.Close()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	wr, err := New(context.Background(), path, WithTeeToHTTP(s.URL, http.MethodPost, nil))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	if requests != 0 {
+		t.Fatalf("Expected no requests, got %d", requests)
+	}
+}
@@ -0,0 +1,77 @@
+package atomicwrite
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidURI is returned by the `New` constructor when a URI is rejected by a function registered using the `WithURIValidator` option.
+var ErrInvalidURI = errors.New("Invalid URI")
+
+// type URIValidatorFunc is a function used to validate URIs before any buckets are opened. It returns an error if 'uri' is not allowed.
+type URIValidatorFunc func(uri string) error
+
+// WithURIValidator returns an Option which registers 'fn' to validate the URI passed to the `New` constructor, as well as any URIs
+// defined by the `WithAdditionalPaths` option, before any buckets are opened. If 'fn' returns an error the constructor returns an
+// error wrapping ErrInvalidURI. This is useful for multi-tenant services which need to restrict where data may be written, for
+// example only allowing `file://` URIs in certain environments. This option may be specified multiple times; validators are invoked
+// in the order they were registered.
+func WithURIValidator(fn URIValidatorFunc) Option {
+
+	return func(o *options) {
+		o.uri_validators = append(o.uri_validators, fn)
+	}
+}
+
+// AllowSchemes returns a URIValidatorFunc which rejects URIs whose scheme is not one of 'schemes', for example "file" or "s3".
+// Schema-less paths are treated as `file://` URIs.
+func AllowSchemes(schemes ...string) URIValidatorFunc {
+
+	allowed := make(map[string]bool)
+
+	for _, s := range schemes {
+		allowed[strings.ToLower(s)] = true
+	}
+
+	return func(uri string) error {
+
+		u, err := url.Parse(uri)
+
+		if err != nil {
+			return fmt.Errorf("Failed to parse URI, %w", err)
+		}
+
+		scheme := u.Scheme
+
+		if scheme == "" {
+			scheme = "file"
+		}
+
+		if !allowed[scheme] {
+			return fmt.Errorf("Scheme '%s' is not allowed", scheme)
+		}
+
+		return nil
+	}
+}
+
+// validateURIs returns an error wrapping ErrInvalidURI if any of 'uris' are rejected by the functions registered using the
+// `WithURIValidator` option.
+func (o *options) validateURIs(uris ...string) error {
+
+	for _, uri := range uris {
+
+		for _, fn := range o.uri_validators {
+
+			err := fn(uri)
+
+			if err != nil {
+				return fmt.Errorf("%v, %w", err, ErrInvalidURI)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithURIValidator(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	path := filepath.Join(root, "atomicwrite.txt")
+
+	file_only := WithURIValidator(AllowSchemes("file"))
+
+	err := writeBytes(ctx, path, []byte("hello world"), file_only)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	_, err = New(ctx, "mem://atomicwrite.txt", file_only)
+
+	if !errors.Is(err, ErrInvalidURI) {
+		t.Fatalf("Expected ErrInvalidURI, got %v", err)
+	}
+
+	_, err = New(ctx, path, file_only, WithAdditionalPaths("mem://other.txt"))
+
+	if !errors.Is(err, ErrInvalidURI) {
+		t.Fatalf("Expected ErrInvalidURI for additional path, got %v", err)
+	}
+
+	no_tmp := func(uri string) error {
+
+		if strings.Contains(uri, "/tmp/") {
+			return fmt.Errorf("Writing to /tmp is not allowed")
+		}
+
+		return nil
+	}
+
+	_, err = New(ctx, "file:///tmp/atomicwrite.txt", file_only, WithURIValidator(no_tmp))
+
+	if !errors.Is(err, ErrInvalidURI) {
+		t.Fatalf("Expected ErrInvalidURI, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("Expected validator error to be included, got %v", err)
+	}
+
+	_, err = NewBuffered(ctx, "mem://atomicwrite.txt", file_only)
+
+	if !errors.Is(err, ErrInvalidURI) {
+		t.Fatalf("Expected ErrInvalidURI for buffered writer, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package atomicwrite
+
+import (
+	"context"
+	"fmt"
+	"gocloud.dev/blob"
+	"io"
+	"net/url"
+)
+
+// WriteFrom atomically writes the data in 'src' to 'uri' by creating an AtomicWriter instance and passing it to the
+// `WriteTo` method of 'src'. This allows any type implementing the `io.WriterTo` interface, for example `bytes.Buffer`,
+// `strings.Reader` or `os.File`, to be written without the caller managing the writer. If writing fails the writer is
+// aborted and nothing is written to 'uri'.
+func WriteFrom(ctx context.Context, uri string, src io.WriterTo, opts ...Option) error {
+
+	wr, err := New(ctx, uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create atomic writer, %w", err)
+	}
+
+	_, err = src.WriteTo(wr)
+
+	if err != nil {
+		wr.(*AtomicWriter).Abort()
+		return fmt.Errorf("Failed to write data, %w", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close atomic writer, %w", err)
+	}
+
+	return nil
+}
+
+// writeBytes atomically writes 'body' to 'uri'.
+func writeBytes(ctx context.Context, uri string, body []byte, opts ...Option) error {
+
+	wr, err := New(ctx, uri, opts...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create atomic writer, %w", err)
+	}
+
+	_, err = wr.Write(body)
+
+	if err != nil {
+		wr.(*AtomicWriter).Abort()
+		return fmt.Errorf("Failed to write data, %w", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close atomic writer, %w", err)
+	}
+
+	return nil
+}
+
+// readBytes reads all the data stored at 'uri'. 'uri' is parsed using the same rules as the `New` constructor.
+func readBytes(ctx context.Context, uri string) ([]byte, error) {
+
+	bucket_uri, key, err := parseURI(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, err := blob.OpenBucket(ctx, bucket_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open bucket %s, %w", bucket_uri, err)
+	}
+
+	defer bucket.Close()
+
+	body, err := bucket.ReadAll(ctx, key)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s, %w", key, err)
+	}
+
+	return body, nil
+}
+
+// sidecarURI returns a URI for a "sidecar" file associated with 'uri' by appending 'ext' to its path. Unlike
+// simple string concatenation this preserves any query parameters in 'uri'.
+func sidecarURI(uri string, ext string) (string, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	switch {
+	case u.Scheme == "":
+		return uri + ext, nil
+	case u.Path == "":
+		u.Host = u.Host + ext
+	default:
+		u.Path = u.Path + ext
+	}
+
+	return u.String(), nil
+}
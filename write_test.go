@@ -0,0 +1,84 @@
+package atomicwrite
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingWriterTo is an io.WriterTo which writes some data and then fails.
+type failingWriterTo struct{}
+
+func (f failingWriterTo) WriteTo(wr io.Writer) (int64, error) {
+
+	n, _ := wr.Write([]byte(HELLO_WORLD))
+	return int64(n), errors.New("Failed")
+}
+
+func TestWriteFrom(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	err := WriteFrom(ctx, path, strings.NewReader(HELLO_WORLD))
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	body, err := readBytes(ctx, path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(body) != HELLO_WORLD {
+		t.Fatalf("Unexpected body: %s", string(body))
+	}
+}
+
+func TestWriteFromError(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.txt")
+
+	err := WriteFrom(ctx, path, failingWriterTo{})
+
+	if err == nil {
+		t.Fatalf("Expected write to fail")
+	}
+
+	_, err = readBytes(ctx, path)
+
+	if err == nil {
+		t.Fatalf("Expected %s not to exist", path)
+	}
+}
+
+func TestSidecarURI(t *testing.T) {
+
+	tests := map[string]string{
+		"/tmp/atomicwrite.txt":                    "/tmp/atomicwrite.txt.sig",
+		"file:///tmp/atomicwrite.txt":             "file:///tmp/atomicwrite.txt.sig",
+		"mem://atomicwrite.txt":                   "mem://atomicwrite.txt.sig",
+		"s3://bucket/atomicwrite.txt?region=test": "s3://bucket/atomicwrite.txt.sig?region=test",
+	}
+
+	for uri, expected := range tests {
+
+		sidecar_uri, err := sidecarURI(uri, ".sig")
+
+		if err != nil {
+			t.Fatalf("Failed to derive sidecar URI for %s, %v", uri, err)
+		}
+
+		if sidecar_uri != expected {
+			t.Fatalf("Unexpected sidecar URI for %s: %s", uri, sidecar_uri)
+		}
+	}
+}
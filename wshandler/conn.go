@@ -0,0 +1,406 @@
+package wshandler
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The GUID used to derive the Sec-WebSocket-Accept header, as defined in RFC 6455
+const websocket_guid string = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket frame opcodes, as defined in RFC 6455
+const (
+	opcode_continuation byte = 0x0
+	opcode_text         byte = 0x1
+	opcode_binary       byte = 0x2
+	opcode_close        byte = 0x8
+	opcode_ping         byte = 0x9
+	opcode_pong         byte = 0xa
+)
+
+// WebSocket close status codes, as defined in RFC 6455
+const (
+	close_normal          uint16 = 1000
+	close_protocol_error  uint16 = 1002
+	close_unsupported     uint16 = 1003
+	close_too_big         uint16 = 1009
+	close_internal_error  uint16 = 1011
+	max_control_frame_len int64  = 125
+)
+
+// The maximum time to wait for data from a client before the connection is closed. This is a variable, rather than a constant,
+// so that it can be shortened in tests.
+var idle_timeout = IDLE_TIMEOUT
+
+// errClosed is returned by readFrame when the client has sent a close frame.
+var errClosed = errors.New("Connection closed by client")
+
+// type protocolError is returned by readFrame when the client has violated the WebSocket protocol.
+type protocolError struct {
+	// The close status code to send to the client
+	code uint16
+	// A description of the error
+	reason string
+}
+
+func (e *protocolError) Error() string {
+	return e.reason
+}
+
+// type frame defines the header of a WebSocket frame and a reader for its (unmasked) payload.
+type frame struct {
+	// Whether this is the final frame of a message
+	fin bool
+	// The frame opcode
+	opcode byte
+	// The length, in bytes, of the payload
+	length int64
+	// A reader for the payload
+	payload io.Reader
+}
+
+// type conn implements the server side of a WebSocket connection. It supports the subset of RFC 6455 required by the handler:
+// no extensions or subprotocols are negotiated.
+type conn struct {
+	// The underlying network connection
+	net_conn net.Conn
+	// The buffered reader for net_conn, which may contain data read during the handshake
+	reader *bufio.Reader
+}
+
+// upgrade validates the WebSocket handshake in 'req' and hijacks the underlying connection, writing the handshake response. If
+// the handshake is invalid an HTTP error is written to 'rsp' and an error is returned.
+func upgrade(rsp http.ResponseWriter, req *http.Request) (*conn, error) {
+
+	if req.Method != http.MethodGet {
+		rsp.Header().Set("Allow", http.MethodGet)
+		http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, fmt.Errorf("Invalid method %s", req.Method)
+	}
+
+	if !headerContainsToken(req.Header, "Connection", "upgrade") || !headerContainsToken(req.Header, "Upgrade", "websocket") {
+		http.Error(rsp, "Bad request", http.StatusBadRequest)
+		return nil, fmt.Errorf("Missing upgrade headers")
+	}
+
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		rsp.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(rsp, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, fmt.Errorf("Unsupported WebSocket version")
+	}
+
+	key := req.Header.Get("Sec-WebSocket-Key")
+
+	decoded, err := base64.StdEncoding.DecodeString(key)
+
+	if err != nil || len(decoded) != 16 {
+		http.Error(rsp, "Bad request", http.StatusBadRequest)
+		return nil, fmt.Errorf("Invalid Sec-WebSocket-Key header")
+	}
+
+	if !isSameOrigin(req) {
+		http.Error(rsp, "Forbidden", http.StatusForbidden)
+		return nil, fmt.Errorf("Cross-origin request")
+	}
+
+	hj, ok := rsp.(http.Hijacker)
+
+	if !ok {
+		http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+		return nil, fmt.Errorf("Response writer does not support hijacking")
+	}
+
+	net_conn, brw, err := hj.Hijack()
+
+	if err != nil {
+		http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+		return nil, fmt.Errorf("Failed to hijack connection, %w", err)
+	}
+
+	h := sha1.New()
+	h.Write([]byte(key + websocket_guid))
+
+	accept := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	brw.WriteString("Upgrade: websocket\r\n")
+	brw.WriteString("Connection: Upgrade\r\n")
+	brw.WriteString("Sec-WebSocket-Accept: " + accept + "\r\n\r\n")
+
+	err = brw.Flush()
+
+	if err != nil {
+		net_conn.Close()
+		return nil, fmt.Errorf("Failed to write handshake, %w", err)
+	}
+
+	// The buffered reader may already contain data sent by the client after the handshake
+
+	buffered, _ := brw.Reader.Peek(brw.Reader.Buffered())
+
+	r := io.MultiReader(bytes.NewReader(buffered), &idleReader{net_conn})
+
+	c := &conn{
+		net_conn: net_conn,
+		reader:   bufio.NewReader(r),
+	}
+
+	return c, nil
+}
+
+// readFrame reads the next data frame from the client. Ping frames are answered and pong frames are ignored. If the client sends
+// a close frame it is answered and errClosed is returned. The payload of the returned frame must be read completely before the
+// next call.
+func (c *conn) readFrame() (*frame, error) {
+
+	for {
+
+		f, err := c.readFrameHeader()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if f.opcode < opcode_close {
+			return f, nil
+		}
+
+		body, err := io.ReadAll(f.payload)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch f.opcode {
+		case opcode_ping:
+
+			err = c.writeFrame(opcode_pong, body)
+
+			if err != nil {
+				return nil, err
+			}
+
+		case opcode_close:
+
+			// The client's status code is not echoed since codes like 1005 (no status) and
+			// 1006 (abnormal closure) must never be sent in a close frame
+
+			c.writeClose(close_normal, "")
+			return nil, errClosed
+		}
+	}
+}
+
+// readFrameHeader reads the header of the next frame from the client.
+func (c *conn) readFrameHeader() (*frame, error) {
+
+	var hdr [2]byte
+
+	_, err := io.ReadFull(c.reader, hdr[:])
+
+	if err != nil {
+		return nil, err
+	}
+
+	fin := hdr[0]&0x80 != 0
+	opcode := hdr[0] & 0x0f
+
+	if hdr[0]&0x70 != 0 {
+		return nil, &protocolError{close_protocol_error, "Reserved bits set"}
+	}
+
+	if hdr[1]&0x80 == 0 {
+		return nil, &protocolError{close_protocol_error, "Client frames must be masked"}
+	}
+
+	length := int64(hdr[1] & 0x7f)
+
+	switch length {
+	case 126:
+
+		var ext [2]byte
+
+		_, err = io.ReadFull(c.reader, ext[:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+
+	case 127:
+
+		var ext [8]byte
+
+		_, err = io.ReadFull(c.reader, ext[:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		v := binary.BigEndian.Uint64(ext[:])
+
+		if v>>63 != 0 {
+			return nil, &protocolError{close_protocol_error, "Invalid payload length"}
+		}
+
+		length = int64(v)
+	}
+
+	switch opcode {
+	case opcode_continuation, opcode_text, opcode_binary:
+		// pass
+	case opcode_close, opcode_ping, opcode_pong:
+
+		if !fin || length > max_control_frame_len {
+			return nil, &protocolError{close_protocol_error, "Invalid control frame"}
+		}
+
+	default:
+		return nil, &protocolError{close_protocol_error, "Unknown opcode"}
+	}
+
+	var mask [4]byte
+
+	_, err = io.ReadFull(c.reader, mask[:])
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := &frame{
+		fin:    fin,
+		opcode: opcode,
+		length: length,
+		payload: &maskedReader{
+			reader: io.LimitReader(c.reader, length),
+			mask:   mask,
+		},
+	}
+
+	return f, nil
+}
+
+// writeFrame writes an unfragmented, unmasked frame with 'opcode' and 'payload' to the client.
+func (c *conn) writeFrame(opcode byte, payload []byte) error {
+
+	hdr := []byte{0x80 | opcode}
+
+	switch {
+	case len(payload) < 126:
+		hdr = append(hdr, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		hdr = append(hdr, 126, 0, 0)
+		binary.BigEndian.PutUint16(hdr[2:], uint16(len(payload)))
+	default:
+		hdr = append(hdr, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(hdr[2:], uint64(len(payload)))
+	}
+
+	_, err := c.net_conn.Write(append(hdr, payload...))
+	return err
+}
+
+// writeClose writes a close frame with status 'code' and 'reason' to the client. Reasons are truncated to fit in a control frame.
+func (c *conn) writeClose(code uint16, reason string) error {
+
+	if len(reason) > int(max_control_frame_len)-2 {
+		reason = reason[:max_control_frame_len-2]
+	}
+
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, code)
+
+	return c.writeFrame(opcode_close, append(payload, reason...))
+}
+
+// Close closes the underlying network connection.
+func (c *conn) Close() error {
+	return c.net_conn.Close()
+}
+
+// type idleReader reads from a network connection, failing if no data is received within idle_timeout of each read starting.
+type idleReader struct {
+	// The underlying network connection
+	net_conn net.Conn
+}
+
+func (r *idleReader) Read(b []byte) (int, error) {
+
+	err := r.net_conn.SetReadDeadline(time.Now().Add(idle_timeout))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return r.net_conn.Read(b)
+}
+
+// type maskedReader unmasks the payload of a client frame as it is read.
+type maskedReader struct {
+	// The reader for the masked payload
+	reader io.Reader
+	// The masking key
+	mask [4]byte
+	// The number of bytes read so far
+	offset int
+}
+
+func (r *maskedReader) Read(b []byte) (int, error) {
+
+	n, err := r.reader.Read(b)
+
+	for i := 0; i < n; i++ {
+		b[i] ^= r.mask[(r.offset+i)%4]
+	}
+
+	r.offset = (r.offset + n) % 4
+	return n, err
+}
+
+// headerContainsToken returns true if the comma-separated header 'name' in 'h' contains 'token' (case-insensitively).
+func headerContainsToken(h http.Header, name string, token string) bool {
+
+	for _, v := range h.Values(name) {
+
+		for _, t := range strings.Split(v, ",") {
+
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isSameOrigin returns true if 'req' has no Origin header, as with non-browser clients, or its host matches the request's Host
+// header. Browsers do not apply the same-origin policy to WebSocket connections so this prevents other sites from writing data
+// using a visitor's credentials.
+func isSameOrigin(req *http.Request) bool {
+
+	origin := req.Header.Get("Origin")
+
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, req.Host)
+}
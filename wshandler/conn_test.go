@@ -0,0 +1,71 @@
+package wshandler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestMaskedReader(t *testing.T) {
+
+	mask := [4]byte{0x37, 0xfa, 0x21, 0x3d}
+	masked := []byte{0x7f, 0x9f, 0x4d, 0x51, 0x58}
+
+	r := &maskedReader{
+		reader: bytes.NewReader(masked),
+		mask:   mask,
+	}
+
+	// Read one byte at a time to check the mask offset is preserved between reads
+
+	v, err := io.ReadAll(io.LimitReader(r, 1))
+
+	if err != nil {
+		t.Fatalf("Failed to read, %v", err)
+	}
+
+	rest, err := io.ReadAll(r)
+
+	if err != nil {
+		t.Fatalf("Failed to read, %v", err)
+	}
+
+	if string(append(v, rest...)) != "Hello" {
+		t.Fatalf("Unexpected payload: %s", string(append(v, rest...)))
+	}
+}
+
+func TestIsSameOrigin(t *testing.T) {
+
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"", true},
+		{"https://example.com", true},
+		{"https://EXAMPLE.com", true},
+		{"https://example.org", false},
+		{"null", false},
+	}
+
+	for _, test := range tests {
+
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com/atomicwrite.txt", nil)
+
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+
+		if isSameOrigin(req) != test.expected {
+			t.Fatalf("Unexpected result for origin %q", test.origin)
+		}
+	}
+
+	h := http.Header{}
+	h.Add("Connection", "keep-alive, Upgrade")
+
+	if !headerContainsToken(h, "Connection", "upgrade") {
+		t.Fatalf("Expected Connection header to contain upgrade token")
+	}
+}
@@ -0,0 +1,210 @@
+// package wshandler implements an http.Handler which atomically writes the data streamed by WebSocket clients to a gocloud.dev/blob
+// bucket. It allows browser or IoT clients to write to a bucket through a server-side proxy which holds the bucket's credentials.
+package wshandler
+
+import (
+	"errors"
+	"github.com/sfomuseum/go-atomicwrite"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// COMMIT is the text message sent by clients to commit the data they have written.
+const COMMIT string = "COMMIT"
+
+// ABORT is the text message sent by clients to discard the data they have written.
+const ABORT string = "ABORT"
+
+// Close status codes sent to clients when data can not be committed. They are the corresponding HTTP status codes plus 4000.
+const (
+	CLOSE_PRECONDITION_FAILED uint16 = 4412
+	CLOSE_QUOTA_EXCEEDED      uint16 = 4413
+)
+
+// IDLE_TIMEOUT is the maximum time to wait for data from a client. If it is exceeded the connection is closed and any data written
+// is discarded.
+const IDLE_TIMEOUT time.Duration = 60 * time.Second
+
+// The maximum length, in bytes, of text messages
+const max_text_len int64 = 1024
+
+// NewWebSocketHandler returns an http.Handler which atomically writes the data streamed by a WebSocket client to the key defined by
+// the request's path (without the leading "/") in the bucket defined by 'bucket_uri'. 'opts' are applied to each write, as with the
+// `atomicwrite.New` constructor. Binary messages are written as they are received. The client finalizes the write by sending a
+// COMMIT or ABORT text message after which the server closes the connection with one of the following status codes:
+//
+//   - 1000 (normal closure) if the data was committed or aborted
+//   - 1002 (protocol error) if the client violated the WebSocket protocol
+//   - 1003 (unsupported data) if the client sent a text message other than COMMIT or ABORT
+//   - 1009 (message too big) if the client sent a text message longer than 1KB
+//   - 1011 (internal error) for all other errors
+//   - 4412 if the data was not committed because of a condition defined by 'opts' (see `atomicwrite.WithIfNotExists`)
+//   - 4413 if writing the data exceeds a quota defined by 'opts'
+//
+// Data is discarded if the connection is closed before the client sends COMMIT, or if no data is received from the client for
+// IDLE_TIMEOUT. If the client sends a close frame the server replies with 1000 (normal closure) and discards the data. Requests
+// whose key is empty or contains ".." segments are rejected with 400 Bad Request, before the connection is upgraded. Requests with
+// an Origin header which does not match the request's Host header are rejected with 403 Forbidden since browsers do not apply the
+// same-origin policy to WebSocket connections. WebSocket extensions and subprotocols are not supported.
+func NewWebSocketHandler(bucket_uri string, opts ...atomicwrite.Option) http.Handler {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		key := strings.TrimPrefix(req.URL.Path, "/")
+
+		if !atomicwrite.IsValidKey(key) {
+			http.Error(rsp, "Invalid key", http.StatusBadRequest)
+			return
+		}
+
+		ctx := req.Context()
+
+		wr, err := atomicwrite.NewDeferred(ctx, bucket_uri, opts...)
+
+		if err != nil {
+			log.Printf("Failed to create writer for %s, %v", key, err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		c, err := upgrade(rsp, req)
+
+		if err != nil {
+			wr.Abort()
+			return
+		}
+
+		defer c.Close()
+
+		err = readWrite(c, wr, key)
+
+		if err != nil {
+			wr.Abort()
+		}
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// readWrite reads frames from 'c', writing binary messages to 'wr', until the client sends a COMMIT or ABORT message. It closes
+// 'c' with the appropriate status code. If an error is returned the caller must abort 'wr'.
+func readWrite(c *conn, wr *atomicwrite.DeferredWriter, key string) error {
+
+	// The opcode of the current fragmented message, if any
+	var current byte
+
+	var text []byte
+
+	for {
+
+		f, err := c.readFrame()
+
+		if err != nil {
+
+			var pe *protocolError
+
+			if errors.As(err, &pe) {
+				c.writeClose(pe.code, pe.reason)
+			}
+
+			return err
+		}
+
+		opcode := f.opcode
+
+		switch {
+		case opcode == opcode_continuation && current == 0:
+			c.writeClose(close_protocol_error, "Unexpected continuation frame")
+			return errors.New("Unexpected continuation frame")
+		case opcode == opcode_continuation:
+			opcode = current
+		case current != 0:
+			c.writeClose(close_protocol_error, "Expected continuation frame")
+			return errors.New("Expected continuation frame")
+		}
+
+		switch opcode {
+		case opcode_binary:
+
+			_, err := io.Copy(wr, f.payload)
+
+			if err != nil {
+				writeError(c, key, err)
+				return err
+			}
+
+		case opcode_text:
+
+			if int64(len(text))+f.length > max_text_len {
+				c.writeClose(close_too_big, "Message too big")
+				return errors.New("Text message too big")
+			}
+
+			b, err := io.ReadAll(f.payload)
+
+			if err != nil {
+				return err
+			}
+
+			text = append(text, b...)
+		}
+
+		if !f.fin {
+			current = opcode
+			continue
+		}
+
+		current = 0
+
+		if opcode != opcode_text {
+			continue
+		}
+
+		switch string(text) {
+		case COMMIT:
+
+			err := wr.Close(key)
+
+			if err != nil {
+				writeError(c, key, err)
+				return err
+			}
+
+			c.writeClose(close_normal, "Committed")
+			return nil
+
+		case ABORT:
+
+			err := wr.Abort()
+
+			if err != nil {
+				writeError(c, key, err)
+				return err
+			}
+
+			c.writeClose(close_normal, "Aborted")
+			return nil
+
+		default:
+			c.writeClose(close_unsupported, "Unknown command")
+			return errors.New("Unknown command")
+		}
+	}
+}
+
+// writeError closes 'c' with the status code for 'err', encountered writing 'key'.
+func writeError(c *conn, key string, err error) {
+
+	switch {
+	case errors.Is(err, atomicwrite.ErrPreconditionFailed):
+		c.writeClose(CLOSE_PRECONDITION_FAILED, "Precondition failed")
+	case errors.Is(err, atomicwrite.ErrQuotaExceeded):
+		c.writeClose(CLOSE_QUOTA_EXCEEDED, "Quota exceeded")
+	default:
+		log.Printf("Failed to write %s, %v", key, err)
+		c.writeClose(close_internal_error, "Internal error")
+	}
+}
@@ -0,0 +1,395 @@
+package wshandler
+
+import (
+	"bufio"
+	"encoding/binary"
+	_ "gocloud.dev/blob/fileblob"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// type testClient is a minimal WebSocket client used for testing.
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dial connects to 'path' on the server 'addr' and performs the WebSocket handshake, returning the HTTP status code of the response
+// and, if the connection was upgraded, a client.
+func dial(t *testing.T, addr string, path string, header map[string]string) (*testClient, int) {
+
+	conn, err := net.Dial("tcp", addr)
+
+	if err != nil {
+		t.Fatalf("Failed to dial %s, %v", addr, err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+addr+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	err = req.Write(conn)
+
+	if err != nil {
+		t.Fatalf("Failed to write request, %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	rsp, err := http.ReadResponse(reader, req)
+
+	if err != nil {
+		t.Fatalf("Failed to read response, %v", err)
+	}
+
+	if rsp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, rsp.StatusCode
+	}
+
+	if rsp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected Sec-WebSocket-Accept header: %s", rsp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	c := &testClient{
+		conn:   conn,
+		reader: reader,
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	return c, rsp.StatusCode
+}
+
+// send writes a masked frame to the server.
+func (c *testClient) send(t *testing.T, fin bool, opcode byte, payload []byte) {
+
+	b0 := opcode
+
+	if fin {
+		b0 |= 0x80
+	}
+
+	hdr := []byte{b0}
+
+	switch {
+	case len(payload) < 126:
+		hdr = append(hdr, 0x80|byte(len(payload)))
+	default:
+		hdr = append(hdr, 0x80|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(hdr[2:], uint64(len(payload)))
+	}
+
+	mask := []byte{1, 2, 3, 4}
+	hdr = append(hdr, mask...)
+
+	masked := make([]byte, len(payload))
+
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+
+	_, err := c.conn.Write(append(hdr, masked...))
+
+	if err != nil {
+		t.Fatalf("Failed to write frame, %v", err)
+	}
+}
+
+// readClose reads frames from the server until it receives a close frame and returns its status code.
+func (c *testClient) readClose(t *testing.T) uint16 {
+
+	for {
+
+		var hdr [2]byte
+
+		_, err := io.ReadFull(c.reader, hdr[:])
+
+		if err != nil {
+			t.Fatalf("Failed to read frame, %v", err)
+		}
+
+		payload := make([]byte, hdr[1]&0x7f)
+
+		_, err = io.ReadFull(c.reader, payload)
+
+		if err != nil {
+			t.Fatalf("Failed to read payload, %v", err)
+		}
+
+		if hdr[0]&0x0f == opcode_close {
+			return binary.BigEndian.Uint16(payload)
+		}
+	}
+}
+
+func TestWebSocketHandler(t *testing.T) {
+
+	root := t.TempDir()
+
+	s := httptest.NewServer(NewWebSocketHandler("file://" + root))
+	defer s.Close()
+
+	addr := strings.TrimPrefix(s.URL, "http://")
+
+	c, _ := dial(t, addr, "/a/atomicwrite.txt", nil)
+
+	c.send(t, false, opcode_binary, []byte("Hello "))
+	c.send(t, true, opcode_ping, []byte("ping"))
+	c.send(t, true, opcode_continuation, []byte(strings.Repeat("world", 100)))
+	c.send(t, false, opcode_text, []byte("COM"))
+	c.send(t, true, opcode_continuation, []byte("MIT"))
+
+	code := c.readClose(t)
+
+	if code != close_normal {
+		t.Fatalf("Unexpected close code %d", code)
+	}
+
+	path := filepath.Join(root, "a", "atomicwrite.txt")
+
+	v, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	if string(v) != "Hello "+strings.Repeat("world", 100) {
+		t.Fatalf("Unexpected body: %s", string(v))
+	}
+}
+
+func TestWebSocketHandlerAbort(t *testing.T) {
+
+	root := t.TempDir()
+
+	// Hijacked connections are not tracked by httptest.Server so keep track of running handlers
+	// in order to wait for them to finish aborting before checking for leftover files. Each client
+	// makes exactly one request so the counter is incremented before it dials the server.
+
+	var wg sync.WaitGroup
+
+	h := NewWebSocketHandler("file://" + root)
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+		defer wg.Done()
+		h.ServeHTTP(rsp, req)
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(fn))
+	defer s.Close()
+
+	addr := strings.TrimPrefix(s.URL, "http://")
+
+	tests := []struct {
+		opcode     byte
+		text       string
+		code       uint16
+		disconnect bool
+	}{
+		{opcode_text, ABORT, close_normal, false},
+		{opcode_text, "NOPE", close_unsupported, false},
+		{opcode_text, strings.Repeat("A", 2048), close_too_big, false},
+		{opcode_continuation, "", close_protocol_error, false},
+		{opcode_binary, "", 0, true},
+	}
+
+	for _, test := range tests {
+
+		wg.Add(1)
+
+		c, _ := dial(t, addr, "/atomicwrite.txt", nil)
+
+		c.send(t, true, opcode_binary, []byte("Hello world"))
+
+		if test.disconnect {
+			c.conn.Close()
+			continue
+		}
+
+		c.send(t, true, test.opcode, []byte(test.text))
+
+		code := c.readClose(t)
+
+		if code != test.code {
+			t.Fatalf("Unexpected close code for %q, expected %d but got %d", test.text, test.code, code)
+		}
+	}
+
+	// The handler for the client which disconnected may still be running
+
+	wg.Wait()
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no files, got %d", len(entries))
+	}
+}
+
+func TestWebSocketHandlerHandshake(t *testing.T) {
+
+	root := t.TempDir()
+
+	s := httptest.NewServer(NewWebSocketHandler("file://" + root))
+	defer s.Close()
+
+	addr := strings.TrimPrefix(s.URL, "http://")
+
+	tests := []struct {
+		path   string
+		header map[string]string
+		status int
+	}{
+		{"/atomicwrite.txt", nil, http.StatusSwitchingProtocols},
+		{"/atomicwrite.txt", map[string]string{"Origin": "http://" + addr}, http.StatusSwitchingProtocols},
+		{"/atomicwrite.txt", map[string]string{"Origin": "http://example.com"}, http.StatusForbidden},
+		{"/atomicwrite.txt", map[string]string{"Upgrade": "h2c"}, http.StatusBadRequest},
+		{"/atomicwrite.txt", map[string]string{"Sec-WebSocket-Version": "8"}, http.StatusUpgradeRequired},
+		{"/", nil, http.StatusBadRequest},
+		{"/a/../../atomicwrite.txt", nil, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+
+		c, status := dial(t, addr, test.path, test.header)
+
+		if status != test.status {
+			t.Fatalf("Unexpected status for %s %v, expected %d but got %d", test.path, test.header, test.status, status)
+		}
+
+		// Abort upgraded connections, and wait for the server to close them, so that no temporary files are left behind
+
+		if c != nil {
+			c.send(t, true, opcode_text, []byte(ABORT))
+			c.readClose(t)
+		}
+	}
+}
+
+func TestWebSocketHandlerClientClose(t *testing.T) {
+
+	root := t.TempDir()
+
+	var wg sync.WaitGroup
+
+	h := NewWebSocketHandler("file://" + root)
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+		defer wg.Done()
+		h.ServeHTTP(rsp, req)
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(fn))
+	defer s.Close()
+
+	addr := strings.TrimPrefix(s.URL, "http://")
+
+	// 1005, 1006 and 1015 must never be sent in a close frame so they must not be echoed back
+
+	for _, client_code := range []uint16{close_normal, 1005, 1006, 1015} {
+
+		wg.Add(1)
+
+		c, _ := dial(t, addr, "/atomicwrite.txt", nil)
+
+		c.send(t, true, opcode_binary, []byte("Hello world"))
+
+		payload := make([]byte, 2)
+		binary.BigEndian.PutUint16(payload, client_code)
+
+		c.send(t, true, opcode_close, payload)
+
+		code := c.readClose(t)
+
+		if code != close_normal {
+			t.Fatalf("Unexpected close code for client code %d, expected %d but got %d", client_code, close_normal, code)
+		}
+	}
+
+	wg.Wait()
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no files, got %d", len(entries))
+	}
+}
+
+func TestWebSocketHandlerIdleTimeout(t *testing.T) {
+
+	default_timeout := idle_timeout
+	idle_timeout = 100 * time.Millisecond
+
+	defer func() {
+		idle_timeout = default_timeout
+	}()
+
+	root := t.TempDir()
+
+	var wg sync.WaitGroup
+
+	h := NewWebSocketHandler("file://" + root)
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+		defer wg.Done()
+		h.ServeHTTP(rsp, req)
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(fn))
+	defer s.Close()
+
+	addr := strings.TrimPrefix(s.URL, "http://")
+
+	wg.Add(1)
+
+	c, _ := dial(t, addr, "/atomicwrite.txt", nil)
+
+	c.send(t, true, opcode_binary, []byte("Hello world"))
+
+	// The server closes the connection without a close frame once the client has been idle for too long
+
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	_, err := io.ReadAll(c.reader)
+
+	if err != nil {
+		t.Fatalf("Expected server to close idle connection, %v", err)
+	}
+
+	wg.Wait()
+
+	entries, err := os.ReadDir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", root, err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no files, got %d", len(entries))
+	}
+}
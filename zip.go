@@ -0,0 +1,151 @@
+package atomicwrite
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// type zipEntryWriter implements the io.WriteCloser interface for atomically adding or replacing an entry in a ZIP archive.
+type zipEntryWriter struct {
+	io.WriteCloser
+	// The underlying AtomicWriter instance that the (new) ZIP archive is written to
+	aw *AtomicWriter
+	// The zip.Writer instance wrapping aw
+	zw *zip.Writer
+	// The io.Writer instance for the entry being added or replaced
+	entry io.Writer
+}
+
+// NewZipEntry returns a new io.WriteCloser instance for atomically adding or replacing the entry named 'entry_name' in the
+// ZIP archive defined by 'zip_uri'. Because ZIP is a sequential format the entire archive is rewritten: All the existing entries
+// in the archive (if it exists), except 'entry_name', are copied to a new archive which is written to an intermediate temporary
+// file followed by the data written to the new io.WriteCloser instance. The new archive is committed to 'zip_uri' when the `Close`
+// method is invoked. This is useful for updating files like JAR, EPUB or DOCX files which are all ZIP archives under the hood.
+// Note that the existing archive is read in to memory.
+func NewZipEntry(ctx context.Context, zip_uri string, entry_name string, opts ...Option) (io.WriteCloser, error) {
+
+	aw, err := newAtomicWriter(ctx, zip_uri, opts...)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create atomic writer, %w", err)
+	}
+
+	zw := zip.NewWriter(aw)
+
+	err = copyZipEntries(ctx, aw, zw, entry_name)
+
+	if err != nil {
+		aw.Abort()
+		return nil, err
+	}
+
+	entry, err := zw.Create(entry_name)
+
+	if err != nil {
+		aw.Abort()
+		return nil, fmt.Errorf("Failed to create %s entry, %w", entry_name, err)
+	}
+
+	z := &zipEntryWriter{
+		aw:    aw,
+		zw:    zw,
+		entry: entry,
+	}
+
+	return z, nil
+}
+
+// Write writes 'b' to the ZIP archive entry.
+func (z *zipEntryWriter) Write(b []byte) (int, error) {
+	return z.entry.Write(b)
+}
+
+// Close finalizes the ZIP archive and commits it to the final path.
+func (z *zipEntryWriter) Close() error {
+
+	err := z.zw.Close()
+
+	if err != nil {
+		z.aw.Abort()
+		return fmt.Errorf("Failed to close zip writer, %w", err)
+	}
+
+	return z.aw.Close()
+}
+
+// copyZipEntries copies all the entries, except 'skip', in the existing ZIP archive at the final path of 'aw' (if present) to 'zw'.
+func copyZipEntries(ctx context.Context, aw *AtomicWriter, zw *zip.Writer, skip string) error {
+
+	final_path := aw.FinalPath()
+
+	exists, err := aw.bucket.Exists(ctx, final_path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to determine whether %s exists, %w", final_path, err)
+	}
+
+	if !exists {
+		return nil
+	}
+
+	body, err := aw.bucket.ReadAll(ctx, final_path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to read %s, %w", final_path, err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+
+	if err != nil {
+		return fmt.Errorf("Failed to create zip reader for %s, %w", final_path, err)
+	}
+
+	for _, f := range zr.File {
+
+		if f.Name == skip {
+			continue
+		}
+
+		err := copyZipEntry(zw, f)
+
+		if err != nil {
+			return fmt.Errorf("Failed to copy %s entry, %w", f.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// copyZipEntry copies 'f' to 'zw'.
+func copyZipEntry(zw *zip.Writer, f *zip.File) error {
+
+	r, err := f.Open()
+
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	// Copy the header so that zip.Writer can (re)assign sizes and checksums
+	// without modifying the header of the archive being read from
+
+	fh := f.FileHeader
+
+	wr, err := zw.CreateHeader(&fh)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(wr, r)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
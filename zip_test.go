@@ -0,0 +1,80 @@
+package atomicwrite
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewZipEntry(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "atomicwrite.zip")
+
+	entries := [][2]string{
+		{"a.txt", "a"},
+		{"b.txt", "b"},
+		{"a.txt", HELLO_WORLD},
+	}
+
+	for _, e := range entries {
+
+		wr, err := NewZipEntry(ctx, path, e[0])
+
+		if err != nil {
+			t.Fatalf("Failed to create zip entry writer for %s, %v", e[0], err)
+		}
+
+		_, err = wr.Write([]byte(e[1]))
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", e[0], err)
+		}
+
+		err = wr.Close()
+
+		if err != nil {
+			t.Fatalf("Failed to close %s, %v", e[0], err)
+		}
+	}
+
+	zr, err := zip.OpenReader(path)
+
+	if err != nil {
+		t.Fatalf("Failed to open %s, %v", path, err)
+	}
+
+	defer zr.Close()
+
+	expected := map[string]string{
+		"b.txt": "b",
+		"a.txt": HELLO_WORLD,
+	}
+
+	if len(zr.File) != len(expected) {
+		t.Fatalf("Unexpected number of entries in %s: %d", path, len(zr.File))
+	}
+
+	for _, f := range zr.File {
+
+		r, err := f.Open()
+
+		if err != nil {
+			t.Fatalf("Failed to open %s, %v", f.Name, err)
+		}
+
+		body, err := io.ReadAll(r)
+		r.Close()
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", f.Name, err)
+		}
+
+		if string(body) != expected[f.Name] {
+			t.Fatalf("Unexpected data for %s: %s", f.Name, string(body))
+		}
+	}
+}